@@ -2,45 +2,130 @@ package main
 
 import (
 	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math/big"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 
 	"github.com/fahedafzaal/go-integration/internal/config"
+	"github.com/fahedafzaal/go-integration/pkg/auth"
 	"github.com/fahedafzaal/go-integration/pkg/blockchain"
 	"github.com/fahedafzaal/go-integration/pkg/database"
+	"github.com/fahedafzaal/go-integration/pkg/indexer"
+	"github.com/fahedafzaal/go-integration/pkg/money"
+	"github.com/fahedafzaal/go-integration/pkg/outbox"
+	"github.com/fahedafzaal/go-integration/pkg/payment"
+	"github.com/fahedafzaal/go-integration/pkg/reconciler"
+	"github.com/fahedafzaal/go-integration/pkg/webhooks"
 )
 
 type PaymentGateway struct {
-	client *blockchain.Client
+	chains *blockchain.Chains // a client per served chain; see chainFor
 	config *config.Config
 	db     *database.DB
+	auth   *auth.Authenticator // nil when authentication is disabled
+	users  *auth.UserVerifier  // nil when authentication is disabled
+}
+
+// chain is a served chain's client and the direct-mode service on it, for client-funded
+// deposits
+type chain struct {
+	client  *blockchain.Client
+	service *blockchain.PaymentGatewayService
 }
 
 // Request/Response types for your application flow
 type PostJobRequest struct {
-	JobID             uint64 `json:"job_id"`             // application.id (your escrow_job_id)
-	FreelancerAddress string `json:"freelancer_address"` // applicant wallet
-	USDAmount         string `json:"usd_amount"`         // agreed_usd_amount
-	ClientAddress     string `json:"client_address"`     // poster wallet
+	JobID             uint64    `json:"job_id"`             // application.id (your escrow_job_id)
+	FreelancerAddress string    `json:"freelancer_address"` // applicant wallet
+	USDAmount         money.USD `json:"usd_amount"`         // agreed_usd_amount
+	ClientAddress     string    `json:"client_address"`     // poster wallet
+
+	// Set when the client funded the escrow from their own wallet. The deposit is then
+	// recorded rather than sent, once the EscrowOffer signature and transaction check out.
+	ClientTxHash   string `json:"client_tx_hash,omitempty"`
+	OfferExpiry    uint64 `json:"offer_expiry,omitempty"`    // expiry of the signed EscrowOffer, unix seconds
+	OfferSignature string `json:"offer_signature,omitempty"` // EIP-712 signature from GET /escrow-offer typed data
+	QuoteID        string `json:"quote_id,omitempty"`        // POST /quotes quote the deposit's ETH amount was locked by
+
+	ChainID int64 `json:"chain_id,omitempty"` // chain to fund the escrow on; see chainFor
+}
+
+// EscrowOfferResponse is the typed data a client signs with eth_signTypedData_v4 before
+// funding the escrow from their own wallet
+type EscrowOfferResponse struct {
+	TypedData apitypes.TypedData `json:"typed_data"`
+	Expiry    uint64             `json:"expiry"`
+}
+
+// UnsignedTxResponse carries the PostJob transaction a client funds the escrow with, as
+// eth_sendTransaction parameters or, with format=rlp, as the EIP-1559 unsigned encoding
+type UnsignedTxResponse struct {
+	Transaction *blockchain.UnsignedTx `json:"transaction,omitempty"`
+	RLP         hexutil.Bytes          `json:"rlp,omitempty"`
+}
+
+// SubmitSignedTxRequest carries a postJob transaction the client signed, for the gateway
+// to broadcast on their behalf
+type SubmitSignedTxRequest struct {
+	JobID   uint64 `json:"job_id"`             // application.id
+	RawTx   string `json:"raw_tx"`             // signed transaction as returned by eth_signTransaction, 0x hex
+	QuoteID string `json:"quote_id,omitempty"` // POST /quotes quote the transaction's value was taken from
+	ChainID int64  `json:"chain_id,omitempty"` // chain the transaction is for; see chainFor
 }
 
+// QuoteRequest asks for the ETH an application's agreed amount converts to
+type QuoteRequest struct {
+	JobID   uint64 `json:"job_id"`             // application.id
+	ChainID int64  `json:"chain_id,omitempty"` // chain the deposit will be sent on; see chainFor
+}
+
+// QuoteResponse locks the ETH a client deposit must carry until ExpiresAt. A deposit
+// referencing QuoteID is checked against RequiredETH rather than the price when it lands.
+type QuoteResponse struct {
+	QuoteID      string    `json:"quote_id"`
+	JobID        uint64    `json:"job_id"`
+	ChainID      int64     `json:"chain_id"`
+	USDAmount    money.USD `json:"usd_amount"`
+	RequiredETH  string    `json:"required_eth"`  // wei
+	EthUSDPrice  string    `json:"eth_usd_price"` // 8 decimals
+	PriceSources []string  `json:"price_sources"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// escrowOfferTTL is how long a client has to sign an offer and fund the escrow
+const escrowOfferTTL = 15 * time.Minute
+
+// priceQuoteTTL is how long a quoted ETH amount holds for a deposit
+const priceQuoteTTL = 15 * time.Minute
+
 type JobStatusResponse struct {
-	JobID             uint64 `json:"job_id"`
-	ApplicationID     int32  `json:"application_id"`
-	FreelancerAddress string `json:"freelancer_address"`
-	ClientAddress     string `json:"client_address"`
-	USDAmount         string `json:"usd_amount"`
-	PaymentStatus     string `json:"payment_status"`
-	ApplicationStatus string `json:"application_status"`
-	TxHashDeposit     string `json:"tx_hash_deposit,omitempty"`
-	TxHashRelease     string `json:"tx_hash_release,omitempty"`
-	TxHashRefund      string `json:"tx_hash_refund,omitempty"`
+	JobID             uint64    `json:"job_id"`
+	ApplicationID     int32     `json:"application_id"`
+	FreelancerAddress string    `json:"freelancer_address"`
+	ClientAddress     string    `json:"client_address"`
+	USDAmount         money.USD `json:"usd_amount"`
+	PaymentStatus     string    `json:"payment_status"`
+	ApplicationStatus string    `json:"application_status"`
+	TxHashDeposit     string    `json:"tx_hash_deposit,omitempty"`
+	TxHashRelease     string    `json:"tx_hash_release,omitempty"`
+	TxHashRefund      string    `json:"tx_hash_refund,omitempty"`
+	ChainID           *int64    `json:"chain_id,omitempty"`     // chain the escrow job is on, once a deposit is queued
+	ExplorerURL       string    `json:"explorer_url,omitempty"` // block explorer of that chain
+
+	TxReplacements []database.TxReplacement `json:"tx_replacements,omitempty"`
 }
 
 type TransactionResponse struct {
@@ -51,9 +136,301 @@ type TransactionResponse struct {
 	Error       string `json:"error,omitempty"`
 }
 
+// OperationResponse tracks a queued chain transaction through
+// queued -> submitted -> mined -> confirmed, or failed
+type OperationResponse struct {
+	ID            int64     `json:"id"`
+	Kind          string    `json:"kind"` // deposit, client_deposit, release or refund
+	ApplicationID int32     `json:"application_id"`
+	ChainID       int64     `json:"chain_id"`
+	Status        string    `json:"status"`
+	TxHash        string    `json:"tx_hash,omitempty"`
+	BlockNumber   uint64    `json:"block_number,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	RevertReason  string    `json:"revert_reason,omitempty"`
+	ErrorCode     string    `json:"error_code,omitempty"` // set when the contract rejected the call
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// WalletNonceRequest asks for a Sign-In-With-Ethereum challenge binding address to a user
+type WalletNonceRequest struct {
+	UserID  int32  `json:"user_id"`
+	Address string `json:"address"`
+}
+
+// WalletNonceResponse is the challenge the wallet signs with personal_sign
+type WalletNonceResponse struct {
+	Nonce     string    `json:"nonce"`
+	Message   string    `json:"message"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// VerifyWalletRequest returns a signed challenge
+type VerifyWalletRequest struct {
+	Nonce     string `json:"nonce"`
+	Signature string `json:"signature"` // 0x-prefixed 65-byte personal_sign signature
+}
+
+// walletNonceTTL is how long a wallet challenge may be signed and returned
+const walletNonceTTL = 10 * time.Minute
+
+// ResolveDiscrepancyRequest closes a payment discrepancy with a note on how it was handled
+type ResolveDiscrepancyRequest struct {
+	Resolution string `json:"resolution"`
+}
+
+// ChainResponse describes a chain the gateway serves
+type ChainResponse struct {
+	ChainID           int64  `json:"chain_id"`
+	Name              string `json:"name"`
+	ContractAddress   string `json:"contract_address"`
+	ExplorerURL       string `json:"explorer_url,omitempty"`
+	ConfirmationDepth uint64 `json:"confirmation_depth"`
+	Default           bool   `json:"default"` // used by requests that name no chain
+}
+
+// ErrorResponse is the JSON body returned for contract rejections
+type ErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// contractErrorStatus maps each escrow contract error to the HTTP status it is reported with
+var contractErrorStatus = map[*blockchain.ContractError]int{
+	blockchain.ErrInsufficientEthSent:        http.StatusUnprocessableEntity,
+	blockchain.ErrJobAlreadyCompleted:        http.StatusConflict,
+	blockchain.ErrJobNotCancelable:           http.StatusConflict,
+	blockchain.ErrJobNotCompleted:            http.StatusConflict,
+	blockchain.ErrNotJobClient:               http.StatusForbidden,
+	blockchain.ErrOnlyClientCanMarkCompleted: http.StatusForbidden,
+	blockchain.ErrPaymentAlreadyReleased:     http.StatusConflict,
+}
+
+// writeContractError reports err as a JSON error with its machine-readable code if it is
+// an escrow contract error, and returns false otherwise
+func writeContractError(w http.ResponseWriter, err error) bool {
+	var contractErr *blockchain.ContractError
+	if !errors.As(err, &contractErr) {
+		return false
+	}
+
+	status, ok := contractErrorStatus[contractErr]
+	if !ok {
+		status = http.StatusBadRequest
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error(), Code: contractErr.Code})
+	return true
+}
+
+// priceErrorStatus reports a price feed answer the guards rejected, or too few price
+// sources answering, as 503, since quoting can resume once the sources recover, and any
+// other failure as 500
+func priceErrorStatus(err error) int {
+	var feedErr *blockchain.PriceFeedError
+	if errors.As(err, &feedErr) || errors.Is(err, blockchain.ErrPriceNonPositive) || errors.Is(err, blockchain.ErrPriceQuorum) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// quoteErrorStatus reports an unknown, expired or mismatched price quote as 400 and one
+// already used for another deposit as 409, and anything else as priceErrorStatus does
+func quoteErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, database.ErrPriceQuoteNotFound), errors.Is(err, blockchain.ErrQuoteExpired),
+		errors.Is(err, blockchain.ErrQuoteMismatch):
+		return http.StatusBadRequest
+	case errors.Is(err, database.ErrPriceQuoteRedeemed):
+		return http.StatusConflict
+	}
+	return priceErrorStatus(err)
+}
+
+// loadQuote returns the price quote id refers to, or nil when id is empty. The quote must
+// have been issued for the application on chainID.
+func (pg *PaymentGateway) loadQuote(ctx context.Context, id string, applicationID int32, chainID int64) (*blockchain.Quote, error) {
+	if id == "" {
+		return nil, nil
+	}
+	q, err := pg.db.GetPriceQuote(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if q.ApplicationID != applicationID {
+		return nil, fmt.Errorf("%w: quote %s is for application %d", blockchain.ErrQuoteMismatch, id, q.ApplicationID)
+	}
+	if q.ChainID != chainID {
+		return nil, fmt.Errorf("%w: quote %s is for chain %d", blockchain.ErrQuoteMismatch, id, q.ChainID)
+	}
+
+	quote := &blockchain.Quote{
+		ID:            q.ID,
+		JobID:         uint64(q.ApplicationID),
+		USDAmountE8:   q.USDAmountE8,
+		RequiredWei:   q.Wei,
+		EthUSDPriceE8: q.EthUSDPriceE8,
+		PriceSources:  q.PriceSources,
+		ExpiresAt:     q.ExpiresAt,
+	}
+	if q.TxHash != nil {
+		quote.RedeemedBy = *q.TxHash
+	}
+	return quote, nil
+}
+
+// chainErrorStatus reports a chain the gateway does not serve as 400 and a chain other
+// than the one an application's escrow job is on as 409, and anything else as 500
+func chainErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, blockchain.ErrUnknownChain):
+		return http.StatusBadRequest
+	case errors.Is(err, database.ErrEscrowChainMismatch):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// parseChainID parses an optional chain_id query parameter; 0 means none was given
+func parseChainID(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	chainID, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || chainID <= 0 {
+		return 0, fmt.Errorf("invalid chain_id %q", s)
+	}
+	return chainID, nil
+}
+
+// chain returns the served chain chainID, or the default chain for 0
+func (pg *PaymentGateway) chain(chainID int64) (chain, error) {
+	client, err := pg.chains.Client(chainID)
+	if err != nil {
+		return chain{}, err
+	}
+	service, err := pg.chains.Service(client.ChainID())
+	if err != nil {
+		return chain{}, err
+	}
+	return chain{client: client, service: service}, nil
+}
+
+// chainFor resolves the chain an application's escrow is handled on: the chain its escrow
+// job is on once a deposit is queued, and otherwise requested, or the default chain when
+// requested is 0. Naming a chain other than the escrow job's is an error.
+func (pg *PaymentGateway) chainFor(details *database.ApplicationPaymentDetails, requested int64) (chain, error) {
+	if details.EscrowChainID != nil {
+		if requested != 0 && requested != *details.EscrowChainID {
+			return chain{}, fmt.Errorf("%w: chain %d, not %d", database.ErrEscrowChainMismatch, *details.EscrowChainID, requested)
+		}
+		requested = *details.EscrowChainID
+	}
+	return pg.chain(requested)
+}
+
+// requestOrigin attributes payment ledger events to the API key and HTTP endpoint that
+// caused them
+func requestOrigin(r *http.Request) database.Origin {
+	actor := database.ActorAPI
+	if key, ok := auth.FromContext(r.Context()); ok {
+		actor += ":" + key.ID
+	}
+	if user, ok := auth.ActingUserFromContext(r.Context()); ok {
+		actor += fmt.Sprintf("/user:%d", user.ID)
+	}
+	return database.Origin{Actor: actor, Source: r.Method + " " + r.URL.Path}
+}
+
+// protect requires callers of h to hold scope and verifies the user they act for, unless
+// authentication is disabled
+func (pg *PaymentGateway) protect(scope string, h http.HandlerFunc) http.HandlerFunc {
+	if pg.auth == nil {
+		return h
+	}
+	return pg.auth.Require(scope, pg.users.Middleware(h))
+}
+
+// authorizeActingUser lets a release or refund through only for the application's poster,
+// or for an admin when allowAdmin is set. Refusals are written to the payment ledger and
+// answered with 401 or 403; it returns false when the request has been answered.
+func (pg *PaymentGateway) authorizeActingUser(ctx context.Context, w http.ResponseWriter, r *http.Request, applicationID int32, action string, allowAdmin bool) bool {
+	if pg.users == nil {
+		return true
+	}
+
+	details, err := pg.db.GetApplicationPaymentDetails(ctx, applicationID)
+	if errors.Is(err, database.ErrApplicationNotFound) {
+		http.Error(w, "Application not found", http.StatusNotFound)
+		return false
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get application: %v", err), http.StatusInternalServerError)
+		return false
+	}
+
+	user, ok := auth.ActingUserFromContext(r.Context())
+	var status int
+	var reason string
+	switch {
+	case !ok:
+		status, reason = http.StatusUnauthorized, fmt.Sprintf("%s without an acting user", action)
+	case user.ID == details.PosterUserID:
+		return true
+	case allowAdmin && user.Admin():
+		return true
+	case allowAdmin:
+		status, reason = http.StatusForbidden, fmt.Sprintf("%s by user %d, who is neither the poster nor an admin", action, user.ID)
+	default:
+		status, reason = http.StatusForbidden, fmt.Sprintf("%s by user %d, who is not the poster", action, user.ID)
+	}
+
+	log.Printf("Denied %s for application %d", reason, applicationID)
+	if err := pg.db.RecordAccessDenied(ctx, applicationID, requestOrigin(r), reason); err != nil {
+		log.Printf("Warning: Failed to record denial for application %d: %v", applicationID, err)
+	}
+	http.Error(w, "Forbidden: "+reason, status)
+	return false
+}
+
+func newOperationResponse(intent *database.TxIntent) OperationResponse {
+	response := OperationResponse{
+		ID:            intent.ID,
+		Kind:          intent.Kind,
+		ApplicationID: intent.ApplicationID,
+		ChainID:       intent.ChainID,
+		Status:        intent.Status,
+		CreatedAt:     intent.CreatedAt,
+		UpdatedAt:     intent.UpdatedAt,
+	}
+	if intent.TxHash != nil {
+		response.TxHash = *intent.TxHash
+	}
+	if intent.BlockNumber != nil {
+		response.BlockNumber = uint64(*intent.BlockNumber)
+	}
+	if intent.Error != nil {
+		response.Error = *intent.Error
+	}
+	if intent.RevertReason != nil {
+		response.RevertReason = *intent.RevertReason
+		if contractErr, ok := blockchain.ContractErrorByName(*intent.RevertReason); ok {
+			response.ErrorCode = contractErr.Code
+		}
+	}
+	return response
+}
+
 func NewPaymentGateway(cfg *config.Config) (*PaymentGateway, error) {
-	// Initialize blockchain client
-	client, err := blockchain.NewClient(cfg)
+	// Initialize a blockchain client per served chain, all signing as one address
+	signer, err := blockchain.NewSignerFromConfig(context.Background(), cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signer: %w", err)
+	}
+	chains, err := blockchain.DialChains(context.Background(), cfg, signer)
 	if err != nil {
 		return nil, err
 	}
@@ -61,18 +438,39 @@ func NewPaymentGateway(cfg *config.Config) (*PaymentGateway, error) {
 	// Initialize database connection
 	db, err := database.NewDB(cfg.DatabaseURL)
 	if err != nil {
-		client.Close()
+		chains.Close()
 		return nil, fmt.Errorf("failed to connect to database: %v", err)
 	}
 
+	var authenticator *auth.Authenticator
+	var users *auth.UserVerifier
+	if !cfg.AuthDisabled {
+		keys, err := auth.ParseKeys(cfg.APIKeys)
+		if err != nil {
+			chains.Close()
+			db.Close()
+			return nil, fmt.Errorf("invalid API_KEYS: %v", err)
+		}
+		authenticator = auth.NewAuthenticator(keys, 0)
+		users = auth.NewUserVerifier([]byte(cfg.ActingUserSecret))
+	}
+
+	// Keep nonce reservations in Postgres so concurrent and restarted requests never reuse a nonce
+	chains.SetNonceStore(func(chainID int64) blockchain.NonceStore { return db.Nonces(chainID) })
+	// Record fee-bumped and cancelling replacements against their application
+	chains.SetReplacementRecorder(db)
+
 	return &PaymentGateway{
-		client: client,
+		chains: chains,
 		config: cfg,
 		db:     db,
+		auth:   authenticator,
+		users:  users,
 	}, nil
 }
 
 // POST /post-job - Called when candidate accepts offer
+// Returns 202 Accepted with an operation once the deposit is queued; poll GET /operations/{id}.
 func (pg *PaymentGateway) postJobHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -85,7 +483,7 @@ func (pg *PaymentGateway) postJobHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	// Validate the application is ready for blockchain operations
@@ -105,6 +503,23 @@ func (pg *PaymentGateway) postJobHandler(w http.ResponseWriter, r *http.Request)
 	if alreadyInitiated {
 		log.Printf("Escrow deposit already initiated for application %d (tx: %s)", applicationID, existingTxHash)
 
+		// A deposit queued or broadcast by the gateway is reported through its operation
+		var intent *database.TxIntent
+		for _, kind := range []string{database.IntentDeposit, database.IntentClientDeposit} {
+			latest, err := pg.db.LatestTxIntent(ctx, applicationID, kind)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Failed to look up deposit operation: %v", err), http.StatusInternalServerError)
+				return
+			}
+			if latest != nil && (intent == nil || latest.ID > intent.ID) {
+				intent = latest
+			}
+		}
+		if intent != nil {
+			writeOperationAccepted(w, intent)
+			return
+		}
+
 		// Return success response indicating deposit was already initiated
 		response := TransactionResponse{
 			TxHash:      existingTxHash,
@@ -135,282 +550,1054 @@ func (pg *PaymentGateway) postJobHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Parse addresses and amount
-	freelancerAddr := common.HexToAddress(req.FreelancerAddress)
-	clientAddr := common.HexToAddress(req.ClientAddress)
-	usdAmountFloat, err := strconv.ParseFloat(req.USDAmount, 64)
-	if err != nil {
-		http.Error(w, "Invalid USD amount", http.StatusBadRequest)
+	// The worker funds the escrow with the agreed amount, so the request must match it
+	if details.AgreedUSDAmount == nil || *details.AgreedUSDAmount != req.USDAmount {
+		http.Error(w, "USD amount mismatch", http.StatusBadRequest)
 		return
 	}
 
-	// Post job to blockchain - let smart contract handle all validation
-	result, err := pg.client.PostJob(ctx, req.JobID, freelancerAddr, usdAmountFloat, clientAddr)
+	c, err := pg.chainFor(details, req.ChainID)
 	if err != nil {
-		// Smart contract rejected the transaction with a clear reason
-		log.Printf("Smart contract rejected job %d: %v", req.JobID, err)
-		http.Error(w, fmt.Sprintf("Smart contract rejected transaction: %v", err), http.StatusBadRequest)
+		http.Error(w, fmt.Sprintf("Cannot post job: %v", err), chainErrorStatus(err))
 		return
 	}
 
-	// Validate transaction result - don't proceed if transaction hash is empty
-	if result.TxHash == "" {
-		log.Printf("Error: PostJob returned success but with empty transaction hash for application %d", applicationID)
-		http.Error(w, "Blockchain operation failed: empty transaction hash", http.StatusInternalServerError)
+	// A client who funded the escrow themselves has already sent the deposit
+	if req.ClientTxHash != "" {
+		pg.acceptClientDeposit(ctx, w, r, c, req)
 		return
 	}
 
-	// Use atomic database update to prevent race conditions
-	if err := pg.db.AtomicStartEscrowDeposit(ctx, applicationID, result.TxHash); err != nil {
-		log.Printf("Warning: Blockchain transaction succeeded but database update failed: %v", err)
-		// Don't fail the request since blockchain transaction succeeded
+	// Move the payment status and queue the deposit atomically; the outbox worker sends it
+	intent, _, err := pg.db.EnqueueTxIntent(ctx, c.client.ChainID(), applicationID, database.IntentDeposit, requestOrigin(r))
+	if errors.Is(err, database.ErrIntentNotAllowed) {
+		http.Error(w, fmt.Sprintf("Cannot post job: %v", err), http.StatusBadRequest)
+		return
 	}
-
-	response := TransactionResponse{
-		TxHash:      result.TxHash,
-		BlockNumber: result.BlockNumber,
-		GasUsed:     result.GasUsed,
-		Success:     result.Success,
+	if errors.Is(err, database.ErrEscrowChainMismatch) {
+		http.Error(w, fmt.Sprintf("Cannot post job: %v", err), http.StatusConflict)
+		return
 	}
-
-	if result.Error != nil {
-		response.Error = result.Error.Error()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to queue escrow deposit: %v", err), http.StatusInternalServerError)
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	writeOperationAccepted(w, intent)
 }
 
-// GET /get-transaction-data?job_id=X&freelancer_address=Y&usd_amount=Z&client_address=W
-// Returns encoded transaction data for smart contract interaction
-func (pg *PaymentGateway) getTransactionDataHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+// acceptClientDeposit records a deposit the client sent from their own wallet. The client's
+// EscrowOffer signature must cover the request's terms, which postJobHandler has matched
+// against the application, and the transaction must have posted the job with those terms.
+// The signature is kept as the client's record of agreeing to them.
+func (pg *PaymentGateway) acceptClientDeposit(ctx context.Context, w http.ResponseWriter, r *http.Request, c chain, req PostJobRequest) {
+	applicationID := int32(req.JobID)
+	serviceReq := blockchain.PostJobRequest{
+		JobID:             req.JobID,
+		FreelancerAddress: req.FreelancerAddress,
+		USDAmount:         req.USDAmount,
+		ClientAddress:     req.ClientAddress,
+		ClientTxHash:      req.ClientTxHash,
+		OfferExpiry:       req.OfferExpiry,
+		OfferSignature:    req.OfferSignature,
+		QuoteID:           req.QuoteID,
 	}
-
-	// Parse query parameters
-	jobIDStr := r.URL.Query().Get("job_id")
-	freelancerAddress := r.URL.Query().Get("freelancer_address")
-	usdAmount := r.URL.Query().Get("usd_amount")
-	clientAddress := r.URL.Query().Get("client_address")
-
-	if jobIDStr == "" || freelancerAddress == "" || usdAmount == "" || clientAddress == "" {
-		http.Error(w, "Missing required parameters: job_id, freelancer_address, usd_amount, client_address", http.StatusBadRequest)
+	offer, err := serviceReq.Offer()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid escrow offer: %v", err), http.StatusBadRequest)
 		return
 	}
-
-	jobID, err := strconv.ParseUint(jobIDStr, 10, 64)
+	serviceReq.Quote, err = pg.loadQuote(ctx, req.QuoteID, applicationID, c.client.ChainID())
 	if err != nil {
-		http.Error(w, "Invalid job_id", http.StatusBadRequest)
+		http.Error(w, fmt.Sprintf("Invalid price quote: %v", err), quoteErrorStatus(err))
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	// Create a payment service with direct mode
-	service, err := blockchain.NewPaymentGatewayServiceDirect(
-		pg.config.EthereumRPCURL,
-		pg.config.ContractAddress,
-		pg.config.PrivateKey,
-	)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to create payment service: %v", err), http.StatusInternalServerError)
+	// Checks the offer signature before looking at the transaction
+	if _, err := c.service.PostJob(ctx, serviceReq); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, blockchain.ErrOfferSignerMismatch) {
+			status = http.StatusForbidden
+		}
+		http.Error(w, fmt.Sprintf("Client deposit rejected: %v", err), status)
 		return
 	}
-	defer service.Close()
 
-	// Calculate required ETH amount
-	requiredEth, err := service.CalculateRequiredETH(ctx, usdAmount)
+	chainID := big.NewInt(c.client.ChainID())
+	contract := c.client.ContractAddress()
+	hash, err := offer.Hash(chainID, contract)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to calculate required ETH: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to hash escrow offer: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	// Get encoded transaction data
-	req := blockchain.PostJobRequest{
-		JobID:             jobID,
-		FreelancerAddress: freelancerAddress,
-		USDAmount:         usdAmount,
-		ClientAddress:     clientAddress,
+	// The quote locks the amount of this deposit, and cannot lock another's, only if the
+	// offer and the deposit are recorded with it
+	var quoteID string
+	if serviceReq.Quote != nil {
+		quoteID = serviceReq.Quote.ID
 	}
-
-	transactionData, err := service.GetTransactionData(ctx, req)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get transaction data: %v", err), http.StatusInternalServerError)
+	err = pg.db.StartClientDeposit(ctx, chainID.Int64(), database.EscrowOffer{
+		ApplicationID:     applicationID,
+		ClientAddress:     offer.Client.Hex(),
+		FreelancerAddress: offer.Freelancer.Hex(),
+		USDAmountE8:       offer.USDAmount,
+		Expiry:            time.Unix(int64(offer.Expiry), 0),
+		ChainID:           chainID.Int64(),
+		ContractAddress:   contract.Hex(),
+		TypedDataHash:     hexutil.Encode(hash),
+		Signature:         req.OfferSignature,
+		ClientTxHash:      req.ClientTxHash,
+	}, quoteID, requestOrigin(r))
+	if errors.Is(err, database.ErrPriceQuoteNotFound) || errors.Is(err, database.ErrPriceQuoteRedeemed) {
+		http.Error(w, fmt.Sprintf("Price quote rejected: %v", err), quoteErrorStatus(err))
 		return
 	}
-
-	// Return transaction data for client to use
-	response := map[string]interface{}{
-		"contract_address": pg.config.ContractAddress,
-		"required_eth":     requiredEth.String(),
-		"transaction_data": "0x" + transactionData,
-		"job_id":           jobID,
-		"freelancer":       freelancerAddress,
-		"client":           clientAddress,
-		"usd_amount":       usdAmount,
-		"instructions":     "Send a transaction to contract_address with value=required_eth and data=transaction_data",
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to record escrow deposit: %v", err), http.StatusConflict)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(TransactionResponse{TxHash: req.ClientTxHash, Success: true})
 }
 
-// POST /complete-job?job_id=X - Called when poster approves work
-func (pg *PaymentGateway) completeJobHandler(w http.ResponseWriter, r *http.Request) {
+// POST /submit-signed-tx - Broadcast a postJob transaction the client signed but cannot
+// send themselves, e.g. from a hardware wallet. Returns 202 Accepted with an operation
+// once the transaction is stored; the outbox worker tracks it and verifies the deposit.
+func (pg *PaymentGateway) submitSignedTxHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	jobIDStr := r.URL.Query().Get("job_id")
-	jobID, err := strconv.ParseUint(jobIDStr, 10, 64)
+	var req SubmitSignedTxRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	raw, err := hexutil.Decode(req.RawTx)
 	if err != nil {
-		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		http.Error(w, "Invalid raw_tx, expected 0x-prefixed hex", http.StatusBadRequest)
+		return
+	}
+	tx, err := blockchain.DecodeSignedTx(raw)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	applicationID := int32(jobID) // application.id is used as escrow job_id
-
-	// Get application details to verify payment status
+	// The transaction must post the application's terms, never the caller's
+	applicationID := int32(req.JobID)
+	if err := pg.db.ValidateApplicationForBlockchain(ctx, applicationID); err != nil {
+		http.Error(w, fmt.Sprintf("Application validation failed: %v", err), http.StatusBadRequest)
+		return
+	}
 	details, err := pg.db.GetApplicationPaymentDetails(ctx, applicationID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get application details: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	if details.PaymentStatus != "deposited" {
-		http.Error(w, fmt.Sprintf("Cannot complete job: payment status is '%s', expected 'deposited'", details.PaymentStatus), http.StatusBadRequest)
+	c, err := pg.chainFor(details, req.ChainID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Cannot post job: %v", err), chainErrorStatus(err))
 		return
 	}
 
-	// Complete job on blockchain
-	result, err := pg.client.MarkJobCompleted(ctx, jobID)
+	terms := blockchain.PostJobRequest{
+		JobID:             req.JobID,
+		FreelancerAddress: *details.ApplicantWalletAddress,
+		USDAmount:         *details.AgreedUSDAmount,
+		ClientAddress:     *details.PosterWalletAddress,
+		QuoteID:           req.QuoteID,
+	}
+	terms.Quote, err = pg.loadQuote(ctx, req.QuoteID, applicationID, c.client.ChainID())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to complete job on blockchain: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Invalid price quote: %v", err), quoteErrorStatus(err))
+		return
+	}
+	if err := c.service.CheckSignedPostJob(ctx, tx, terms); err != nil {
+		status := quoteErrorStatus(err)
+		if errors.Is(err, blockchain.ErrSignedTxMismatch) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, fmt.Sprintf("Signed transaction rejected: %v", err), status)
 		return
 	}
 
-	// Update database with release transaction hash
-	if err := pg.db.UpdatePaymentStatus(ctx, applicationID, "release_initiated", &result.TxHash, "release"); err != nil {
-		log.Printf("Warning: Failed to update payment status in database: %v", err)
+	amounts := database.Amounts{Wei: tx.Value()}
+	if terms.Quote != nil {
+		// The outbox verifies the deposit against the quote once it is mined
+		if err := pg.db.RedeemPriceQuote(ctx, terms.Quote.ID, tx.Hash().Hex()); err != nil {
+			http.Error(w, fmt.Sprintf("Price quote rejected: %v", err), quoteErrorStatus(err))
+			return
+		}
+		amounts.EthUSDPriceE8 = terms.Quote.EthUSDPriceE8
+	} else if price, err := c.client.GetETHUSDPrice(ctx); err != nil {
+		log.Printf("Could not read ETH/USD price for the ledger: %v", err)
+	} else {
+		amounts.EthUSDPriceE8 = price
 	}
 
-	response := TransactionResponse{
-		TxHash:      result.TxHash,
-		BlockNumber: result.BlockNumber,
-		GasUsed:     result.GasUsed,
-		Success:     result.Success,
+	// Store the transaction before broadcasting it, as the outbox does for its own
+	intent, created, err := pg.db.EnqueueSignedTxIntent(ctx, c.client.ChainID(), applicationID, database.IntentClientDeposit, database.SignedTx{
+		From:    terms.ClientAddress,
+		Nonce:   tx.Nonce(),
+		TxHash:  tx.Hash().Hex(),
+		RawTx:   raw,
+		Amounts: amounts,
+	}, requestOrigin(r))
+	if errors.Is(err, database.ErrIntentNotAllowed) {
+		http.Error(w, fmt.Sprintf("Cannot post job: %v", err), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, database.ErrEscrowChainMismatch) {
+		http.Error(w, fmt.Sprintf("Cannot post job: %v", err), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to queue client deposit: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !created && (intent.TxHash == nil || *intent.TxHash != tx.Hash().Hex()) {
+		http.Error(w, fmt.Sprintf("Another client deposit is already in flight (operation %d)", intent.ID), http.StatusConflict)
+		return
 	}
 
-	if result.Error != nil {
-		response.Error = result.Error.Error()
+	if err := c.client.SendSignedTransaction(ctx, tx); err != nil {
+		// Left submitted; the outbox worker rebroadcasts it
+		log.Printf("Client deposit %s for application %d not broadcast yet: %v", tx.Hash().Hex(), applicationID, err)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	writeOperationAccepted(w, intent)
 }
 
-// POST /cancel-job?job_id=X - Called for refunds
-func (pg *PaymentGateway) cancelJobHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
+// GET /escrow-offer?job_id=X&chain_id=C - EIP-712 EscrowOffer for the client to sign
+// before funding the escrow from their own wallet on chain C
+func (pg *PaymentGateway) getEscrowOfferHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	jobIDStr := r.URL.Query().Get("job_id")
-	jobID, err := strconv.ParseUint(jobIDStr, 10, 64)
+	jobID, err := strconv.ParseUint(r.URL.Query().Get("job_id"), 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		http.Error(w, "Invalid job_id", http.StatusBadRequest)
+		return
+	}
+	chainID, err := parseChainID(r.URL.Query().Get("chain_id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	applicationID := int32(jobID) // application.id is used as escrow job_id
-
-	// Get application details to verify payment status
+	// The offer carries the application's terms, never the caller's
+	applicationID := int32(jobID)
+	if err := pg.db.ValidateApplicationForBlockchain(ctx, applicationID); err != nil {
+		http.Error(w, fmt.Sprintf("Application validation failed: %v", err), http.StatusBadRequest)
+		return
+	}
 	details, err := pg.db.GetApplicationPaymentDetails(ctx, applicationID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get application details: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	if details.PaymentStatus != "deposited" {
-		http.Error(w, fmt.Sprintf("Cannot cancel job: payment status is '%s', expected 'deposited'", details.PaymentStatus), http.StatusBadRequest)
+	c, err := pg.chainFor(details, chainID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Cannot build escrow offer: %v", err), chainErrorStatus(err))
 		return
 	}
 
-	// Cancel job on blockchain
-	result, err := pg.client.CancelJob(ctx, jobID)
+	expiry := uint64(time.Now().Add(escrowOfferTTL).Unix())
+	offer, err := blockchain.PostJobRequest{
+		JobID:             jobID,
+		FreelancerAddress: *details.ApplicantWalletAddress,
+		USDAmount:         *details.AgreedUSDAmount,
+		ClientAddress:     *details.PosterWalletAddress,
+		OfferExpiry:       expiry,
+	}.Offer()
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to cancel job on blockchain: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Invalid escrow terms: %v", err), http.StatusBadRequest)
 		return
 	}
 
-	// Update database with refund transaction hash
-	if err := pg.db.UpdatePaymentStatus(ctx, applicationID, "refund_initiated", &result.TxHash, "refund"); err != nil {
-		log.Printf("Warning: Failed to update payment status in database: %v", err)
-	}
-
-	response := TransactionResponse{
-		TxHash:      result.TxHash,
-		BlockNumber: result.BlockNumber,
-		GasUsed:     result.GasUsed,
-		Success:     result.Success,
-	}
-
-	if result.Error != nil {
-		response.Error = result.Error.Error()
+	typedData, err := c.client.EscrowOfferTypedData(ctx, offer)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to build escrow offer: %v", err), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(EscrowOfferResponse{TypedData: typedData, Expiry: expiry})
 }
 
-// GET /job-status?job_id=X - Get application payment status
-func (pg *PaymentGateway) getJobStatusHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+// POST /quotes - Lock the ETH an application's deposit must carry for a while, so a
+// price move before the client's transaction is mined cannot fail its verification.
+// Returns 201 Created with the quote.
+func (pg *PaymentGateway) createQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	jobIDStr := r.URL.Query().Get("job_id")
-	jobID, err := strconv.ParseUint(jobIDStr, 10, 64)
-	if err != nil {
-		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+	var req QuoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	applicationID := int32(jobID)
-
-	// Get application details from database
+	// The quote is for the application's agreed amount, never the caller's
+	applicationID := int32(req.JobID)
+	if err := pg.db.ValidateApplicationForBlockchain(ctx, applicationID); err != nil {
+		http.Error(w, fmt.Sprintf("Application validation failed: %v", err), http.StatusBadRequest)
+		return
+	}
 	details, err := pg.db.GetApplicationPaymentDetails(ctx, applicationID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get application details: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	response := JobStatusResponse{
-		JobID:             jobID,
-		ApplicationID:     details.ApplicationID,
-		FreelancerAddress: *details.ApplicantWalletAddress,
-		ClientAddress:     *details.PosterWalletAddress,
-		USDAmount:         fmt.Sprintf("%d", *details.AgreedUSDAmount),
-		PaymentStatus:     details.PaymentStatus,
-		ApplicationStatus: details.ApplicationStatus,
+	c, err := pg.chainFor(details, req.ChainID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Cannot quote ETH amount: %v", err), chainErrorStatus(err))
+		return
 	}
 
-	if details.EscrowTxHashDeposit != nil {
+	usdAmount := *details.AgreedUSDAmount
+	quote, err := c.service.NewQuote(ctx, req.JobID, usdAmount, priceQuoteTTL)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to quote ETH amount: %v", err), priceErrorStatus(err))
+		return
+	}
+
+	if err := pg.db.CreatePriceQuote(ctx, database.PriceQuote{
+		ID:            quote.ID,
+		ApplicationID: applicationID,
+		ChainID:       c.client.ChainID(),
+		USDAmountE8:   quote.USDAmountE8,
+		Wei:           quote.RequiredWei,
+		EthUSDPriceE8: quote.EthUSDPriceE8,
+		PriceSources:  quote.PriceSources,
+		ExpiresAt:     quote.ExpiresAt,
+	}); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to store quote: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(QuoteResponse{
+		QuoteID:      quote.ID,
+		JobID:        req.JobID,
+		ChainID:      c.client.ChainID(),
+		USDAmount:    usdAmount,
+		RequiredETH:  quote.RequiredWei.String(),
+		EthUSDPrice:  quote.EthUSDPriceE8.String(),
+		PriceSources: quote.PriceSources,
+		ExpiresAt:    quote.ExpiresAt,
+	})
+}
+
+// GET /get-transaction-data?job_id=X&freelancer_address=Y&usd_amount=Z&client_address=W&chain_id=C
+// Returns encoded transaction data for smart contract interaction on chain C, by default
+// the default chain
+func (pg *PaymentGateway) getTransactionDataHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Parse query parameters
+	jobIDStr := r.URL.Query().Get("job_id")
+	freelancerAddress := r.URL.Query().Get("freelancer_address")
+	usdAmountStr := r.URL.Query().Get("usd_amount")
+	clientAddress := r.URL.Query().Get("client_address")
+
+	if jobIDStr == "" || freelancerAddress == "" || usdAmountStr == "" || clientAddress == "" {
+		http.Error(w, "Missing required parameters: job_id, freelancer_address, usd_amount, client_address", http.StatusBadRequest)
+		return
+	}
+
+	jobID, err := strconv.ParseUint(jobIDStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid job_id", http.StatusBadRequest)
+		return
+	}
+
+	// Agreed amounts are in whole cents
+	usdAmount, err := money.ParseUSDCents(usdAmountStr)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid usd_amount: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	chainID, err := parseChainID(r.URL.Query().Get("chain_id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c, err := pg.chain(chainID)
+	if err != nil {
+		http.Error(w, err.Error(), chainErrorStatus(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Calculate required ETH amount
+	requiredEth, quote, err := c.service.QuoteRequiredETH(ctx, usdAmount)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to calculate required ETH: %v", err), priceErrorStatus(err))
+		return
+	}
+
+	// Get encoded transaction data
+	req := blockchain.PostJobRequest{
+		JobID:             jobID,
+		FreelancerAddress: freelancerAddress,
+		USDAmount:         usdAmount,
+		ClientAddress:     clientAddress,
+	}
+
+	transactionData, err := c.service.GetTransactionData(ctx, req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get transaction data: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return transaction data for client to use
+	response := map[string]interface{}{
+		"chain_id":         c.client.ChainID(),
+		"contract_address": c.client.ContractAddress().Hex(),
+		"required_eth":     requiredEth.String(),
+		"price_sources":    quote.Sources,
+		"transaction_data": "0x" + transactionData,
+		"job_id":           jobID,
+		"freelancer":       freelancerAddress,
+		"client":           clientAddress,
+		"usd_amount":       usdAmount,
+		"instructions":     "Sign the EscrowOffer from GET /escrow-offer, send a transaction to contract_address with value=required_eth and data=transaction_data (GET /unsigned-tx returns it complete with gas and fees), then POST /post-job with client_tx_hash, offer_expiry and offer_signature. POST /quotes first to lock the ETH amount until the deposit is mined",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GET /unsigned-tx?job_id=X&format=json|rlp&quote_id=Q&chain_id=C - Complete unsigned
+// PostJob transaction for the client to sign and send from their own wallet, priced for
+// the client's address. With quote_id its value is the quoted ETH amount.
+func (pg *PaymentGateway) getUnsignedTxHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobID, err := strconv.ParseUint(r.URL.Query().Get("job_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid job_id", http.StatusBadRequest)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "rlp" {
+		http.Error(w, "Invalid format, expected json or rlp", http.StatusBadRequest)
+		return
+	}
+	chainID, err := parseChainID(r.URL.Query().Get("chain_id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Like the escrow offer, the transaction carries the application's terms
+	applicationID := int32(jobID)
+	if err := pg.db.ValidateApplicationForBlockchain(ctx, applicationID); err != nil {
+		http.Error(w, fmt.Sprintf("Application validation failed: %v", err), http.StatusBadRequest)
+		return
+	}
+	details, err := pg.db.GetApplicationPaymentDetails(ctx, applicationID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get application details: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	c, err := pg.chainFor(details, chainID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Cannot build transaction: %v", err), chainErrorStatus(err))
+		return
+	}
+
+	terms := blockchain.PostJobRequest{
+		JobID:             jobID,
+		FreelancerAddress: *details.ApplicantWalletAddress,
+		USDAmount:         *details.AgreedUSDAmount,
+		ClientAddress:     *details.PosterWalletAddress,
+		QuoteID:           r.URL.Query().Get("quote_id"),
+	}
+	terms.Quote, err = pg.loadQuote(ctx, terms.QuoteID, applicationID, c.client.ChainID())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid price quote: %v", err), quoteErrorStatus(err))
+		return
+	}
+
+	tx, err := c.service.BuildPostJobTx(ctx, terms)
+	if writeContractError(w, err) {
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to build transaction: %v", err), quoteErrorStatus(err))
+		return
+	}
+
+	var response UnsignedTxResponse
+	if format == "rlp" {
+		response.RLP, err = tx.MarshalRLP()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to encode transaction: %v", err), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		response.Transaction = tx
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// POST /complete-job?job_id=X - Called when poster approves work
+// Returns 202 Accepted with an operation once the release is queued; poll GET /operations/{id}.
+func (pg *PaymentGateway) completeJobHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobIDStr := r.URL.Query().Get("job_id")
+	jobID, err := strconv.ParseUint(jobIDStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	applicationID := int32(jobID) // application.id is used as escrow job_id
+
+	// Only the poster approves the work and releases the payment
+	if !pg.authorizeActingUser(ctx, w, r, applicationID, "release", false) {
+		return
+	}
+
+	// The release goes to the chain the escrow job is on
+	details, c, ok := pg.escrowChain(ctx, w, applicationID)
+	if !ok {
+		return
+	}
+
+	// Reject calls the contract would revert before anything is queued
+	if err := preflight(ctx, c, details, (*blockchain.Client).SimulateMarkJobCompleted); writeContractError(w, err) {
+		return
+	}
+
+	// Move the payment status and queue the transaction atomically; the outbox worker sends it
+	intent, created, err := pg.db.EnqueueTxIntent(ctx, c.client.ChainID(), applicationID, database.IntentRelease, requestOrigin(r))
+	if errors.Is(err, database.ErrIntentNotAllowed) {
+		http.Error(w, fmt.Sprintf("Cannot complete job: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to queue payment release: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if !created {
+		log.Printf("Payment release already in flight for application %d (intent %d)", applicationID, intent.ID)
+	}
+
+	writeOperationAccepted(w, intent)
+}
+
+// POST /cancel-job?job_id=X - Called for refunds
+// Returns 202 Accepted with an operation once the refund is queued; poll GET /operations/{id}.
+func (pg *PaymentGateway) cancelJobHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobIDStr := r.URL.Query().Get("job_id")
+	jobID, err := strconv.ParseUint(jobIDStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	applicationID := int32(jobID) // application.id is used as escrow job_id
+
+	// The poster, or an admin settling a dispute, may cancel
+	if !pg.authorizeActingUser(ctx, w, r, applicationID, "refund", true) {
+		return
+	}
+
+	// The refund goes to the chain the escrow job is on
+	details, c, ok := pg.escrowChain(ctx, w, applicationID)
+	if !ok {
+		return
+	}
+
+	// Reject calls the contract would revert before anything is queued
+	if err := preflight(ctx, c, details, (*blockchain.Client).SimulateCancelJob); writeContractError(w, err) {
+		return
+	}
+
+	// Move the payment status and queue the transaction atomically; the outbox worker sends it
+	intent, created, err := pg.db.EnqueueTxIntent(ctx, c.client.ChainID(), applicationID, database.IntentRefund, requestOrigin(r))
+	if errors.Is(err, database.ErrIntentNotAllowed) {
+		http.Error(w, fmt.Sprintf("Cannot cancel job: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to queue refund: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if !created {
+		log.Printf("Refund already in flight for application %d (intent %d)", applicationID, intent.ID)
+	}
+
+	writeOperationAccepted(w, intent)
+}
+
+// escrowChain looks up an application and the chain its escrow job is on. It returns
+// false when the request has been answered.
+func (pg *PaymentGateway) escrowChain(ctx context.Context, w http.ResponseWriter, applicationID int32) (*database.ApplicationPaymentDetails, chain, bool) {
+	details, err := pg.db.GetApplicationPaymentDetails(ctx, applicationID)
+	if errors.Is(err, database.ErrApplicationNotFound) {
+		http.Error(w, "Application not found", http.StatusNotFound)
+		return nil, chain{}, false
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get application: %v", err), http.StatusInternalServerError)
+		return nil, chain{}, false
+	}
+
+	c, err := pg.chainFor(details, 0)
+	if err != nil {
+		// The job is on a chain this gateway was started without
+		http.Error(w, fmt.Sprintf("Escrow job is not reachable: %v", err), http.StatusServiceUnavailable)
+		return nil, chain{}, false
+	}
+	return details, c, true
+}
+
+// preflight simulates a release or refund for an application whose deposit has landed.
+// Other payment statuses are left to EnqueueTxIntent to reject, and simulation failures
+// that are not contract errors are only logged; the outbox worker retries those.
+func preflight(ctx context.Context, c chain, details *database.ApplicationPaymentDetails, simulate func(*blockchain.Client, context.Context, uint64) error) error {
+	if details.PaymentStatus != payment.Deposited {
+		return nil
+	}
+
+	err := simulate(c.client, ctx, uint64(details.ApplicationID))
+	if err != nil {
+		log.Printf("Preflight for application %d failed: %v", details.ApplicationID, err)
+	}
+	return err
+}
+
+// writeOperationAccepted reports a queued transaction intent as an operation with 202 Accepted
+func writeOperationAccepted(w http.ResponseWriter, intent *database.TxIntent) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/operations/%d", intent.ID))
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(newOperationResponse(intent))
+}
+
+// GET /operations/{id} - Poll an operation returned by a mutating endpoint
+func (pg *PaymentGateway) getOperationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid operation ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	intent, err := pg.db.GetTxIntent(ctx, id)
+	if errors.Is(err, database.ErrTxIntentNotFound) {
+		http.Error(w, "Operation not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get operation: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newOperationResponse(intent))
+}
+
+// GET /webhooks/deliveries?application_id=X&status=Y&limit=N - Inspect the webhook delivery log
+func (pg *PaymentGateway) listWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var filter database.WebhookDeliveryFilter
+	if s := r.URL.Query().Get("application_id"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid application ID", http.StatusBadRequest)
+			return
+		}
+		filter.ApplicationID = int32(id)
+	}
+	switch status := r.URL.Query().Get("status"); status {
+	case "", database.WebhookPending, database.WebhookDelivered, database.WebhookFailed:
+		filter.Status = status
+	default:
+		http.Error(w, "Invalid status: expected pending, delivered or failed", http.StatusBadRequest)
+		return
+	}
+	if s := r.URL.Query().Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	deliveries, err := pg.db.ListWebhookDeliveries(ctx, filter)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list webhook deliveries: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []database.WebhookDelivery{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// POST /webhooks/deliveries/{id}/replay - Send a webhook delivery again
+func (pg *PaymentGateway) replayWebhookDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid delivery ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	delivery, err := pg.db.ReplayWebhookDelivery(ctx, id)
+	if errors.Is(err, database.ErrWebhookDeliveryNotFound) {
+		http.Error(w, "Webhook delivery not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to replay webhook delivery: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(delivery)
+}
+
+// GET /applications/{id}/history[?format=csv] - Payment ledger for one application, oldest first
+func (pg *PaymentGateway) getApplicationHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid application ID", http.StatusBadRequest)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		http.Error(w, "Invalid format: expected json or csv", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	events, err := pg.db.ListPaymentEvents(ctx, database.PaymentEventFilter{ApplicationID: int32(id)})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list payment events: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if format == "csv" {
+		writePaymentEventsCSV(w, fmt.Sprintf("application-%d-history.csv", id), events)
+		return
+	}
+	if events == nil {
+		events = []database.PaymentEvent{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// GET /payment-events/export?since=2025-01-01&until=2025-02-01 - Payment ledger as CSV.
+// Bounds are dates or RFC 3339 times; since is inclusive and until exclusive.
+func (pg *PaymentGateway) exportPaymentEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var filter database.PaymentEventFilter
+	var err error
+	if filter.Since, err = parseTimeParam(r.URL.Query().Get("since")); err != nil {
+		http.Error(w, "Invalid since: expected YYYY-MM-DD or RFC 3339", http.StatusBadRequest)
+		return
+	}
+	if filter.Until, err = parseTimeParam(r.URL.Query().Get("until")); err != nil {
+		http.Error(w, "Invalid until: expected YYYY-MM-DD or RFC 3339", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	events, err := pg.db.ListPaymentEvents(ctx, filter)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list payment events: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writePaymentEventsCSV(w, "payment-events.csv", events)
+}
+
+// parseTimeParam parses an optional date or RFC 3339 query parameter
+func parseTimeParam(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// writePaymentEventsCSV sends payment ledger rows as a CSV attachment
+func writePaymentEventsCSV(w http.ResponseWriter, filename string, events []database.PaymentEvent) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	optional := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	status := func(s *payment.Status) string {
+		if s == nil {
+			return ""
+		}
+		return string(*s)
+	}
+
+	out := csv.NewWriter(w)
+	out.Write([]string{
+		"id", "created_at", "application_id", "type", "actor", "source", "from_status", "to_status",
+		"tx_hash", "block_number", "amount_usd_e8", "amount_wei", "eth_usd_price_e8", "detail",
+	})
+	for _, e := range events {
+		blockNumber := ""
+		if e.BlockNumber != nil {
+			blockNumber = strconv.FormatInt(*e.BlockNumber, 10)
+		}
+		out.Write([]string{
+			strconv.FormatInt(e.ID, 10), e.CreatedAt.UTC().Format(time.RFC3339), strconv.FormatInt(int64(e.ApplicationID), 10),
+			e.Type, e.Actor, e.Source, status(e.FromStatus), status(e.ToStatus),
+			optional(e.TxHash), blockNumber, optional(e.AmountUSDE8), optional(e.AmountWei), optional(e.EthUSDPriceE8),
+			optional(e.Detail),
+		})
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		log.Printf("Warning: Failed to write payment events CSV: %v", err)
+	}
+}
+
+// GET /admin/discrepancies?status=open&application_id=X&limit=N - Drifts the reconciler could not heal
+func (pg *PaymentGateway) listDiscrepanciesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var filter database.PaymentDiscrepancyFilter
+	if s := r.URL.Query().Get("application_id"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid application ID", http.StatusBadRequest)
+			return
+		}
+		filter.ApplicationID = int32(id)
+	}
+	switch status := r.URL.Query().Get("status"); status {
+	case "", database.DiscrepancyOpen, database.DiscrepancyResolved:
+		filter.Status = status
+	default:
+		http.Error(w, "Invalid status: expected open or resolved", http.StatusBadRequest)
+		return
+	}
+	if s := r.URL.Query().Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	discrepancies, err := pg.db.ListPaymentDiscrepancies(ctx, filter)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list payment discrepancies: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if discrepancies == nil {
+		discrepancies = []database.PaymentDiscrepancy{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(discrepancies)
+}
+
+// POST /admin/discrepancies/{id}/resolve - Close a discrepancy after dealing with it by hand
+func (pg *PaymentGateway) resolveDiscrepancyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid discrepancy ID", http.StatusBadRequest)
+		return
+	}
+
+	var req ResolveDiscrepancyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Resolution) == "" {
+		http.Error(w, "resolution is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	discrepancy, err := pg.db.ResolvePaymentDiscrepancy(ctx, id, req.Resolution)
+	if errors.Is(err, database.ErrDiscrepancyNotFound) {
+		http.Error(w, "Payment discrepancy not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to resolve payment discrepancy: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(discrepancy)
+}
+
+// GET /job-status?job_id=X - Get application payment status
+func (pg *PaymentGateway) getJobStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobIDStr := r.URL.Query().Get("job_id")
+	jobID, err := strconv.ParseUint(jobIDStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	applicationID := int32(jobID)
+
+	// Get application details from database
+	details, err := pg.db.GetApplicationPaymentDetails(ctx, applicationID)
+	if errors.Is(err, database.ErrApplicationNotFound) {
+		http.Error(w, "Application not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get application details: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Wallets and the agreed amount are left empty until the application has them
+	response := JobStatusResponse{
+		JobID:             jobID,
+		ApplicationID:     details.ApplicationID,
+		PaymentStatus:     string(details.PaymentStatus),
+		ApplicationStatus: details.ApplicationStatus,
+	}
+	if details.ApplicantWalletAddress != nil {
+		response.FreelancerAddress = *details.ApplicantWalletAddress
+	}
+	if details.PosterWalletAddress != nil {
+		response.ClientAddress = *details.PosterWalletAddress
+	}
+	if details.AgreedUSDAmount != nil {
+		response.USDAmount = *details.AgreedUSDAmount
+	}
+
+	if details.EscrowTxHashDeposit != nil {
 		response.TxHashDeposit = *details.EscrowTxHashDeposit
 	}
 	if details.EscrowTxHashRelease != nil {
@@ -419,12 +1606,26 @@ func (pg *PaymentGateway) getJobStatusHandler(w http.ResponseWriter, r *http.Req
 	if details.EscrowTxHashRefund != nil {
 		response.TxHashRefund = *details.EscrowTxHashRefund
 	}
+	if details.EscrowChainID != nil {
+		response.ChainID = details.EscrowChainID
+		if client, err := pg.chains.Client(*details.EscrowChainID); err == nil {
+			response.ExplorerURL = client.ExplorerURL()
+		}
+	}
+
+	replacements, err := pg.db.ListTxReplacements(ctx, applicationID)
+	if err != nil {
+		log.Printf("Warning: Failed to list transaction replacements: %v", err)
+	}
+	response.TxReplacements = replacements
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
 // POST /confirm-deposit?job_id=X - Called to confirm deposit (for polling/webhook)
+// The chain event indexer normally performs this transition; this is a manual override
+// for operators, so it needs the admin scope.
 func (pg *PaymentGateway) confirmDepositHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -444,7 +1645,12 @@ func (pg *PaymentGateway) confirmDepositHandler(w http.ResponseWriter, r *http.R
 	applicationID := int32(jobID)
 
 	// Update payment status to deposited
-	if err := pg.db.UpdatePaymentStatus(ctx, applicationID, "deposited", nil, ""); err != nil {
+	err = pg.db.UpdatePaymentStatus(ctx, applicationID, payment.Deposited, nil, "", requestOrigin(r))
+	if errors.Is(err, payment.ErrIllegalTransition) || errors.Is(err, payment.ErrStatusConflict) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to update payment status: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -454,6 +1660,9 @@ func (pg *PaymentGateway) confirmDepositHandler(w http.ResponseWriter, r *http.R
 }
 
 // POST /confirm-release?job_id=X - Called to confirm release (for polling/webhook)
+// The chain event indexer normally performs this transition; this is a manual override
+// for operators, so it needs the admin scope. Releasing otherwise goes through
+// /complete-job, which only the poster may call.
 func (pg *PaymentGateway) confirmReleaseHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -473,7 +1682,12 @@ func (pg *PaymentGateway) confirmReleaseHandler(w http.ResponseWriter, r *http.R
 	applicationID := int32(jobID)
 
 	// Update payment status to released
-	if err := pg.db.UpdatePaymentStatus(ctx, applicationID, "released", nil, ""); err != nil {
+	err = pg.db.UpdatePaymentStatus(ctx, applicationID, payment.Released, nil, "", requestOrigin(r))
+	if errors.Is(err, payment.ErrIllegalTransition) || errors.Is(err, payment.ErrStatusConflict) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to update payment status: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -482,65 +1696,524 @@ func (pg *PaymentGateway) confirmReleaseHandler(w http.ResponseWriter, r *http.R
 	json.NewEncoder(w).Encode(map[string]bool{"success": true})
 }
 
-// GET /eth-price - Get current ETH price
-func (pg *PaymentGateway) getEthPriceHandler(w http.ResponseWriter, r *http.Request) {
+// POST /wallets/nonce - Issue an EIP-4361 message for a user to sign with their wallet
+func (pg *PaymentGateway) walletNonceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req WalletNonceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.UserID <= 0 {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+	if !common.IsHexAddress(req.Address) {
+		http.Error(w, "Invalid wallet address", http.StatusBadRequest)
+		return
+	}
+	if !pg.actingAs(w, r, req.UserID) {
+		return
+	}
+
+	nonce, err := auth.NewNonce()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to issue nonce: %v", err), http.StatusInternalServerError)
+		return
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	message := auth.SIWEMessage{
+		Domain:    pg.config.SIWEDomain,
+		Address:   common.HexToAddress(req.Address),
+		Statement: fmt.Sprintf("Bind this wallet to user %d for escrow payments.", req.UserID),
+		URI:       pg.config.SIWEURI,
+		ChainID:   pg.config.NetworkID,
+		Nonce:     nonce,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(walletNonceTTL),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := pg.db.CreateWalletNonce(ctx, database.WalletNonce{
+		Nonce:     nonce,
+		UserID:    req.UserID,
+		Address:   message.Address.Hex(),
+		Message:   message.String(),
+		ExpiresAt: message.ExpiresAt,
+	}); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to issue nonce: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(WalletNonceResponse{Nonce: nonce, Message: message.String(), ExpiresAt: message.ExpiresAt})
+}
+
+// POST /wallets/verify - Check a signed wallet challenge and record the wallet as verified
+func (pg *PaymentGateway) verifyWalletHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req VerifyWalletRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	signature, err := hexutil.Decode(req.Signature)
+	if err != nil {
+		http.Error(w, "Invalid signature: expected 0x-prefixed hex", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	challenge, err := pg.db.GetWalletNonce(ctx, req.Nonce)
+	if errors.Is(err, database.ErrWalletNonceInvalid) {
+		http.Error(w, "Nonce is unknown, used or expired", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to look up nonce: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !pg.actingAs(w, r, challenge.UserID) {
+		return
+	}
+
+	if err := auth.VerifyPersonalSign(challenge.Message, signature, common.HexToAddress(challenge.Address)); err != nil {
+		http.Error(w, fmt.Sprintf("Wallet verification failed: %v", err), http.StatusUnauthorized)
+		return
+	}
+
+	binding, err := pg.db.BindWallet(ctx, challenge.Nonce, req.Signature)
+	if errors.Is(err, database.ErrWalletNonceInvalid) {
+		http.Error(w, "Nonce is unknown, used or expired", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to bind wallet: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Verified wallet %s for user %d", binding.Address, binding.UserID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(binding)
+}
+
+// GET /users/{id}/wallets - Wallets a user has proven control of
+func (pg *PaymentGateway) listWalletBindingsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
+	userID, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	price, err := pg.client.GetETHUSDPrice(ctx)
+	bindings, err := pg.db.ListWalletBindings(ctx, int32(userID))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list wallets: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if bindings == nil {
+		bindings = []database.WalletBinding{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bindings)
+}
+
+// actingAs lets a request through only when it is made for userID, or by an admin.
+// It returns false when the request has been answered.
+func (pg *PaymentGateway) actingAs(w http.ResponseWriter, r *http.Request, userID int32) bool {
+	if pg.users == nil {
+		return true
+	}
+	user, ok := auth.ActingUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: acting user required", http.StatusUnauthorized)
+		return false
+	}
+	if user.ID != userID && !user.Admin() {
+		http.Error(w, "Forbidden: cannot act for another user", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// POST /replace-transaction?job_id=X&tx_hash=0x...&action=speed_up|cancel - Re-send a stuck
+// gateway transaction with bumped fees, or void its nonce with a zero-value self-send. Only
+// transactions sent for the application are accepted, and they are looked up on the chain
+// its escrow job is on. A replacement of an outbox transaction is recorded on its intent,
+// so the outbox tracks it like one it sent itself. Requires the admin scope.
+func (pg *PaymentGateway) replaceTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobIDStr := r.URL.Query().Get("job_id")
+	jobID, err := strconv.ParseUint(jobIDStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	txHashStr := r.URL.Query().Get("tx_hash")
+	if len(txHashStr) != 66 || !strings.HasPrefix(txHashStr, "0x") {
+		http.Error(w, "Invalid transaction hash", http.StatusBadRequest)
+		return
+	}
+	txHash := common.HexToHash(txHashStr)
+
+	action := r.URL.Query().Get("action")
+	if action != blockchain.ReplacementSpeedUp && action != blockchain.ReplacementCancel {
+		http.Error(w, "Invalid action: expected speed_up or cancel", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, c, ok := pg.escrowChain(ctx, w, int32(jobID))
+	if !ok {
+		return
+	}
+
+	intent, err := pg.db.FindApplicationTx(ctx, int32(jobID), txHash.Hex())
+	if errors.Is(err, database.ErrApplicationTxNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to look up transaction: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if intent != nil && intent.Kind == database.IntentClientDeposit {
+		http.Error(w, "Cannot replace transaction: only the client can replace a transaction they signed", http.StatusConflict)
+		return
+	}
+
+	pending, err := c.client.PendingTransaction(ctx, txHash)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Cannot replace transaction: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var replacement *types.Transaction
+	if action == blockchain.ReplacementCancel {
+		replacement, err = c.client.CancelTransaction(ctx, jobID, txHash, pending)
+	} else {
+		replacement, err = c.client.SpeedUpTransaction(ctx, jobID, txHash, pending)
+	}
+	if errors.Is(err, blockchain.ErrFeeCeilingReached) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get ETH price: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to replace transaction: %v", err), http.StatusInternalServerError)
 		return
 	}
 
+	if intent != nil {
+		if err := recordIntentReplacement(ctx, pg.db, intent, replacement, action); err != nil {
+			http.Error(w, fmt.Sprintf("Replacement %s was sent but not recorded: %v", replacement.Hash().Hex(), err), http.StatusInternalServerError)
+			return
+		}
+	}
+
 	response := map[string]string{
-		"eth_usd_price": price.String(),
+		"original_tx_hash":    txHash.Hex(),
+		"replacement_tx_hash": replacement.Hash().Hex(),
+		"action":              action,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// recordIntentReplacement adds a replacement sent by an operator to the intent whose
+// transaction it replaces, as the outbox does for its own speed-ups and cancels
+func recordIntentReplacement(ctx context.Context, db *database.DB, intent *database.TxIntent, replacement *types.Transaction, action string) error {
+	if action == blockchain.ReplacementCancel {
+		return db.MarkTxIntentCancelled(ctx, intent.ID, replacement.Hash().Hex())
+	}
+
+	raw, err := replacement.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode replacement: %w", err)
+	}
+	return db.MarkTxIntentSubmitted(ctx, intent.ID, replacement.Nonce(), replacement.Hash().Hex(), raw, database.Amounts{Wei: replacement.Value()})
+}
+
+// GET /eth-price?chain_id=C - Get current ETH price from chain C's price sources, by
+// default the default chain's
+func (pg *PaymentGateway) getEthPriceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	chainID, err := parseChainID(r.URL.Query().Get("chain_id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c, err := pg.chain(chainID)
+	if err != nil {
+		http.Error(w, err.Error(), chainErrorStatus(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	quote, err := c.client.PriceQuote(ctx)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get ETH price: %v", err), priceErrorStatus(err))
+		return
+	}
+
+	response := map[string]interface{}{
+		"eth_usd_price": quote.Price.String(),
+		"sources":       quote.Sources,
+	}
+	if len(quote.Rejected) > 0 {
+		response["rejected"] = quote.Rejected
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// GET /chains - Chains the gateway serves, the default chain first
+func (pg *PaymentGateway) listChainsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var chains []ChainResponse
+	for i, client := range pg.chains.All() {
+		chain := ChainResponse{
+			ChainID:           client.ChainID(),
+			ContractAddress:   client.ContractAddress().Hex(),
+			ExplorerURL:       client.ExplorerURL(),
+			ConfirmationDepth: client.ConfirmationDepth(),
+			Default:           i == 0,
+		}
+		if cfg, ok := pg.config.Chain(client.ChainID()); ok {
+			chain.Name = cfg.Name
+		}
+		chains = append(chains, chain)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(chains)
+}
+
+// runMigrate implements the migrate subcommand:
+//
+//	migrate [up]      apply every pending migration
+//	migrate down [n]  revert the last n migrations (default 1)
+//	migrate status    list applied and pending migrations
+func runMigrate(cfg *config.Config, args []string) error {
+	if cfg.DatabaseURL == "" {
+		return errors.New("DATABASE_URL environment variable is required")
+	}
+	db, err := database.NewDB(cfg.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		applied, err := db.Migrate(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			log.Printf("Database schema is up to date")
+		}
+		return nil
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid step count %q", args[1])
+			}
+		}
+		reverted, err := db.MigrateDown(ctx, steps)
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			log.Printf("No migrations to revert")
+		}
+		return nil
+
+	case "status":
+		status, err := db.SchemaStatus(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Current version: %d\n", status.Current)
+		fmt.Printf("Latest version:  %d\n", status.Latest)
+		for _, m := range status.Pending {
+			fmt.Printf("Pending: %04d_%s\n", m.Version, m.Name)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown migrate command %q (want up, down or status)", command)
+	}
+}
+
 func main() {
 	// Load configuration
 	cfg := config.Load()
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg, os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
 	// Validate required configuration
 	if cfg.ContractAddress == "" {
 		log.Fatal("CONTRACT_ADDRESS environment variable is required")
 	}
-	if cfg.PrivateKey == "" {
-		log.Fatal("PRIVATE_KEY environment variable is required")
-	}
 	if cfg.EthereumRPCURL == "https://sepolia.infura.io/v3/YOUR_INFURA_KEY" {
 		log.Fatal("Please set a valid ETHEREUM_RPC_URL")
 	}
 	if cfg.DatabaseURL == "" {
 		log.Fatal("DATABASE_URL environment variable is required")
 	}
+	if len(cfg.WebhookURLs) > 0 && cfg.WebhookSecret == "" {
+		log.Fatal("WEBHOOK_SECRET environment variable is required when WEBHOOK_URLS is set")
+	}
+	if len(cfg.APIKeys) == 0 && !cfg.AuthDisabled {
+		log.Fatal("API_KEYS environment variable is required; set AUTH_DISABLED=true to serve without authentication")
+	}
+	if cfg.ActingUserSecret == "" && !cfg.AuthDisabled {
+		log.Fatal("ACTING_USER_SECRET environment variable is required; set AUTH_DISABLED=true to serve without authentication")
+	}
+	if cfg.AuthDisabled {
+		log.Printf("Warning: AUTH_DISABLED is set, anyone who can reach port %s can move escrowed funds", cfg.ServerPort)
+	}
 
 	// Initialize payment gateway
 	gateway, err := NewPaymentGateway(cfg)
 	if err != nil {
 		log.Fatalf("Failed to initialize payment gateway: %v", err)
 	}
-	defer gateway.client.Close()
+	defer gateway.chains.Close()
 	defer gateway.db.Close()
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Refuse to serve against a schema this binary's queries do not match
+	if err := gateway.db.CheckSchema(ctx); err != nil {
+		if errors.Is(err, database.ErrSchemaBehind) {
+			log.Fatalf("%v; run `%s migrate` first", err, os.Args[0])
+		}
+		log.Fatalf("Failed to check database schema: %v", err)
+	}
+
+	// Rows from before the gateway served several chains belong to the default chain
+	assigned, err := gateway.db.AssignDefaultChain(ctx, cfg.NetworkID)
+	if err != nil {
+		log.Fatalf("Failed to assign applications to chain %d: %v", cfg.NetworkID, err)
+	}
+	if assigned > 0 {
+		log.Printf("Assigned %d applications to chain %d", assigned, cfg.NetworkID)
+	}
+
+	for _, client := range gateway.chains.All() {
+		chainCfg, _ := cfg.Chain(client.ChainID())
+
+		// Follow escrow events so payment_status tracks the chain without manual confirmation
+		eventIndexer := indexer.New(client, gateway.db, indexer.Config{
+			StartBlock:   chainCfg.IndexerStartBlock,
+			BatchSize:    cfg.IndexerBatchSize,
+			PollInterval: time.Duration(cfg.IndexerPollInterval) * time.Second,
+		})
+		if client.ChainID() == cfg.NetworkID {
+			if err := eventIndexer.AdoptLegacyCursor(ctx); err != nil {
+				log.Fatalf("Failed to migrate the indexer cursor: %v", err)
+			}
+		}
+		go eventIndexer.Run(ctx)
+
+		// Send queued deposit, release and refund transactions
+		go outbox.New(client, gateway.db, outbox.Config{}).Run(ctx)
+
+		// Compare payment_status with the contract, healing safe drifts and flagging the rest
+		go reconciler.New(client, gateway.db, reconciler.Config{
+			Interval: time.Duration(cfg.ReconcileInterval) * time.Second,
+			Settle:   time.Duration(cfg.ReconcileSettle) * time.Second,
+		}).Run(ctx)
+	}
+
+	// Notify subscribers of payment status changes; with no endpoints events are just drained
+	go webhooks.New(gateway.db, webhooks.Config{
+		Endpoints:   cfg.WebhookURLs,
+		Secret:      cfg.WebhookSecret,
+		MaxAttempts: cfg.WebhookMaxAttempts,
+	}).Run(ctx)
+
 	// Setup HTTP routes for your application flow
-	http.HandleFunc("/post-job", gateway.postJobHandler)                        // Offer accepted → fund escrow
-	http.HandleFunc("/complete-job", gateway.completeJobHandler)                // Work approved → release payment
-	http.HandleFunc("/cancel-job", gateway.cancelJobHandler)                    // Cancel/refund
-	http.HandleFunc("/job-status", gateway.getJobStatusHandler)                 // Get payment status
-	http.HandleFunc("/get-transaction-data", gateway.getTransactionDataHandler) // Get encoded transaction data
-	http.HandleFunc("/confirm-deposit", gateway.confirmDepositHandler)          // Confirm deposit completion
-	http.HandleFunc("/confirm-release", gateway.confirmReleaseHandler)          // Confirm release completion
-	http.HandleFunc("/eth-price", gateway.getEthPriceHandler)                   // Current ETH price
+	// Read endpoints need the read scope, endpoints that move funds or payment status the
+	// write scope, and operator tooling the admin scope
+	http.HandleFunc("/post-job", gateway.protect(auth.ScopeWrite, gateway.postJobHandler))                                      // Offer accepted → fund escrow
+	http.HandleFunc("/submit-signed-tx", gateway.protect(auth.ScopeWrite, gateway.submitSignedTxHandler))                       // Broadcast a deposit the client signed
+	http.HandleFunc("/complete-job", gateway.protect(auth.ScopeWrite, gateway.completeJobHandler))                              // Work approved → release payment
+	http.HandleFunc("/cancel-job", gateway.protect(auth.ScopeWrite, gateway.cancelJobHandler))                                  // Cancel/refund
+	http.HandleFunc("/job-status", gateway.protect(auth.ScopeRead, gateway.getJobStatusHandler))                                // Get payment status
+	http.HandleFunc("/get-transaction-data", gateway.protect(auth.ScopeRead, gateway.getTransactionDataHandler))                // Get encoded transaction data
+	http.HandleFunc("/unsigned-tx", gateway.protect(auth.ScopeRead, gateway.getUnsignedTxHandler))                              // Unsigned PostJob transaction for the client's wallet
+	http.HandleFunc("/escrow-offer", gateway.protect(auth.ScopeRead, gateway.getEscrowOfferHandler))                            // Escrow terms for the client to sign
+	http.HandleFunc("/quotes", gateway.protect(auth.ScopeWrite, gateway.createQuoteHandler))                                    // Lock the ETH amount of a deposit
+	http.HandleFunc("/confirm-deposit", gateway.protect(auth.ScopeAdmin, gateway.confirmDepositHandler))                        // Confirm deposit completion
+	http.HandleFunc("/confirm-release", gateway.protect(auth.ScopeAdmin, gateway.confirmReleaseHandler))                        // Confirm release completion
+	http.HandleFunc("/eth-price", gateway.protect(auth.ScopeRead, gateway.getEthPriceHandler))                                  // Current ETH price
+	http.HandleFunc("/chains", gateway.protect(auth.ScopeRead, gateway.listChainsHandler))                                      // Served chains
+	http.HandleFunc("/replace-transaction", gateway.protect(auth.ScopeAdmin, gateway.replaceTransactionHandler))                // Speed up or cancel a stuck tx
+	http.HandleFunc("/operations/{id}", gateway.protect(auth.ScopeRead, gateway.getOperationHandler))                           // Poll a queued operation
+	http.HandleFunc("/webhooks/deliveries", gateway.protect(auth.ScopeAdmin, gateway.listWebhookDeliveriesHandler))             // Webhook delivery log
+	http.HandleFunc("/webhooks/deliveries/{id}/replay", gateway.protect(auth.ScopeAdmin, gateway.replayWebhookDeliveryHandler)) // Re-send a webhook
+	http.HandleFunc("/applications/{id}/history", gateway.protect(auth.ScopeRead, gateway.getApplicationHistoryHandler))        // Payment audit ledger
+	http.HandleFunc("/payment-events/export", gateway.protect(auth.ScopeAdmin, gateway.exportPaymentEventsHandler))             // Ledger CSV export
+	http.HandleFunc("/admin/discrepancies", gateway.protect(auth.ScopeAdmin, gateway.listDiscrepanciesHandler))                 // Reconciler findings
+	http.HandleFunc("/admin/discrepancies/{id}/resolve", gateway.protect(auth.ScopeAdmin, gateway.resolveDiscrepancyHandler))   // Close a finding
+	http.HandleFunc("/wallets/nonce", gateway.protect(auth.ScopeWrite, gateway.walletNonceHandler))                             // Wallet ownership challenge
+	http.HandleFunc("/wallets/verify", gateway.protect(auth.ScopeWrite, gateway.verifyWalletHandler))                           // Prove wallet ownership
+	http.HandleFunc("/users/{id}/wallets", gateway.protect(auth.ScopeRead, gateway.listWalletBindingsHandler))                  // Verified wallets
 
 	// Health check endpoint
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -549,8 +2222,11 @@ func main() {
 	})
 
 	log.Printf("Starting payment gateway server on port %s", cfg.ServerPort)
-	log.Printf("Contract address: %s", cfg.ContractAddress)
-	log.Printf("Network ID: %d", cfg.NetworkID)
+	for _, client := range gateway.chains.All() {
+		log.Printf("Chain %d: contract %s, %d confirmations", client.ChainID(), client.ContractAddress().Hex(), client.ConfirmationDepth())
+	}
+	log.Printf("Signing as %s", gateway.chains.Default().Signer().Address().Hex())
+	log.Printf("Default network ID: %d", cfg.NetworkID)
 	log.Printf("Database connected successfully")
 
 	if err := http.ListenAndServe(":"+cfg.ServerPort, nil); err != nil {
@@ -7,6 +7,7 @@ import (
 	"os"
 
 	"github.com/fahedafzaal/go-integration/pkg/blockchain"
+	"github.com/fahedafzaal/go-integration/pkg/money"
 )
 
 // Example integration code for your main application
@@ -108,7 +109,7 @@ func exampleRespondToOfferIntegration(paymentGateway *blockchain.PaymentGatewayS
 	applicationID := uint64(123)
 	freelancerWallet := "0x742e4C7aBd4C77d7084b7Bc2E8E73B0b54e8a9e1"
 	clientWallet := "0x8ba1f109551bD432803012645Hac136c82F57eBF"
-	agreedAmount := "150.00" // USD
+	agreedAmount := money.MustParseUSD("150.00")
 
 	// This would be called when candidate accepts offer
 	fmt.Printf("Processing job acceptance for application %d...\n", applicationID)
@@ -230,9 +231,12 @@ func exampleEnvironmentConfig() {
 			os.Getenv("PAYMENT_GATEWAY_URL"),
 		)
 	default: // "http"
-		paymentGateway = blockchain.NewPaymentGatewayServiceHTTP(
-			os.Getenv("PAYMENT_GATEWAY_URL"),
-		)
+		paymentGateway, err = blockchain.NewPaymentGatewayService(blockchain.ServiceConfig{
+			Mode:      blockchain.HTTPMode,
+			BaseURL:   os.Getenv("PAYMENT_GATEWAY_URL"),
+			APIKeyID:  os.Getenv("PAYMENT_GATEWAY_KEY_ID"),
+			APISecret: os.Getenv("PAYMENT_GATEWAY_SECRET"),
+		})
 	}
 
 	if err != nil {
@@ -262,10 +266,16 @@ func (as *ApplicationService) RespondToOffer(ctx context.Context, params Respond
 		applicant, _ := as.Queries.GetUserByID(ctx, app.UserID)
 		poster, _ := as.Queries.GetUserByID(ctx, job.UserID)
 
+		// agreed_usd_amount is whole dollars
+		agreedAmount, err := money.Dollars(int64(app.AgreedUsdAmount.Int32))
+		if err != nil {
+			return fmt.Errorf("invalid agreed amount: %w", err)
+		}
+
 		req := blockchain.PostJobRequest{
 			JobID:             uint64(params.ApplicationID),
 			FreelancerAddress: applicant.WalletAddress.String,
-			USDAmount:         fmt.Sprintf("%d", app.AgreedUsdAmount.Int32),
+			USDAmount:         agreedAmount,
 			ClientAddress:     poster.WalletAddress.String,
 		}
 
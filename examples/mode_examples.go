@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/fahedafzaal/go-integration/pkg/blockchain"
+	"github.com/fahedafzaal/go-integration/pkg/money"
 )
 
 func runModeExamples() {
@@ -131,7 +132,12 @@ func createPaymentServiceFromEnv() (*blockchain.PaymentGatewayService, error) {
 			os.Getenv("PAYMENT_GATEWAY_URL"),
 		)
 	default: // "http"
-		return blockchain.NewPaymentGatewayServiceHTTP(os.Getenv("PAYMENT_GATEWAY_URL")), nil
+		return blockchain.NewPaymentGatewayService(blockchain.ServiceConfig{
+			Mode:      blockchain.HTTPMode,
+			BaseURL:   os.Getenv("PAYMENT_GATEWAY_URL"),
+			APIKeyID:  os.Getenv("PAYMENT_GATEWAY_KEY_ID"),
+			APISecret: os.Getenv("PAYMENT_GATEWAY_SECRET"),
+		})
 	}
 }
 
@@ -144,7 +150,7 @@ func executeExampleTransaction(service *blockchain.PaymentGatewayService, modeNa
 	req := blockchain.PostJobRequest{
 		JobID:             12345,
 		FreelancerAddress: "0x742e4C7aBd4C77d7084b7Bc2E8E73B0b54e8a9e1",
-		USDAmount:         "100.00",
+		USDAmount:         money.MustParseUSD("100.00"),
 		ClientAddress:     "0x8ba1f109551bD432803012645Hac136c82F57eBF",
 	}
 
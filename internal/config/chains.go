@@ -0,0 +1,128 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ChainConfig is a chain the gateway serves and the escrow deployment on it
+type ChainConfig struct {
+	ChainID            int64
+	Name               string
+	RPCURLs            []string // Tried in order until one answers for ChainID
+	ContractAddress    string
+	ETHUSDPriceFeed    string
+	ExplorerURL        string
+	ConfirmationDepth  uint64
+	PriceFeedHeartbeat int    // Seconds after which a price feed answer is stale
+	IndexerStartBlock  uint64 // First block the chain's indexer scans when no cursor is stored
+}
+
+// loadChains builds the chain registry. NetworkID's chain comes from the top-level
+// settings, with ETHEREUM_RPC_FALLBACK_URLS tried after ETHEREUM_RPC_URL. Every other
+// chain in CHAIN_IDS is configured by CHAIN_<id>_ variables:
+//
+//	CHAIN_84532_RPC_URLS=https://sepolia.base.org,https://base-sepolia.example.org
+//	CHAIN_84532_CONTRACT_ADDRESS=0x...
+//	CHAIN_84532_ETH_USD_PRICE_FEED, _EXPLORER_URL, _CONFIRMATION_DEPTH,
+//	_PRICE_FEED_HEARTBEAT and _INDEXER_START_BLOCK
+//
+// which default to the chain's entry in Networks. An entry that is not a positive chain
+// id, repeats another or names NetworkID's chain is an error.
+func loadChains(cfg *Config) ([]ChainConfig, error) {
+	chains := []ChainConfig{{
+		ChainID:            cfg.NetworkID,
+		Name:               Networks[cfg.NetworkID].Name,
+		RPCURLs:            append([]string{cfg.EthereumRPCURL}, getEnvAsSlice("ETHEREUM_RPC_FALLBACK_URLS")...),
+		ContractAddress:    cfg.ContractAddress,
+		ETHUSDPriceFeed:    cfg.ETHUSDPriceFeed,
+		ExplorerURL:        cfg.ExplorerURL,
+		ConfirmationDepth:  cfg.ConfirmationDepth,
+		PriceFeedHeartbeat: cfg.PriceFeedHeartbeat,
+		IndexerStartBlock:  cfg.IndexerStartBlock,
+	}}
+
+	seen := map[int64]bool{cfg.NetworkID: true}
+	for _, value := range getEnvAsSlice("CHAIN_IDS") {
+		chainID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || chainID <= 0 {
+			return nil, fmt.Errorf("invalid chain id %q in CHAIN_IDS", value)
+		}
+		if chainID == cfg.NetworkID {
+			return nil, fmt.Errorf("chain %d in CHAIN_IDS is NETWORK_ID, which the top-level settings configure", chainID)
+		}
+		if seen[chainID] {
+			return nil, fmt.Errorf("chain %d is listed twice in CHAIN_IDS", chainID)
+		}
+		seen[chainID] = true
+
+		network := Networks[chainID]
+		prefix := fmt.Sprintf("CHAIN_%d_", chainID)
+
+		rpcURLs := getEnvAsSlice(prefix + "RPC_URLS")
+		if len(rpcURLs) == 0 && network.RPCURL != "" {
+			rpcURLs = []string{network.RPCURL}
+		}
+		name := network.Name
+		if name == "" {
+			name = fmt.Sprintf("chain-%d", chainID)
+		}
+
+		chain := ChainConfig{
+			ChainID:            chainID,
+			Name:               name,
+			RPCURLs:            rpcURLs,
+			ContractAddress:    getEnv(prefix+"CONTRACT_ADDRESS", ""),
+			ETHUSDPriceFeed:    getEnv(prefix+"ETH_USD_PRICE_FEED", network.ETHUSDPriceFeed),
+			ExplorerURL:        getEnv(prefix+"EXPLORER_URL", network.ExplorerURL),
+			ConfirmationDepth:  getEnvAsUint64(prefix+"CONFIRMATION_DEPTH", network.ConfirmationDepth),
+			PriceFeedHeartbeat: getEnvAsInt(prefix+"PRICE_FEED_HEARTBEAT", network.PriceFeedHeartbeat),
+			IndexerStartBlock:  getEnvAsUint64(prefix+"INDEXER_START_BLOCK", 0),
+		}
+		if chain.ConfirmationDepth == 0 {
+			chain.ConfirmationDepth = 1
+		}
+		if chain.PriceFeedHeartbeat <= 0 {
+			chain.PriceFeedHeartbeat = 3600
+		}
+		chains = append(chains, chain)
+	}
+	return chains, nil
+}
+
+// CheckChains returns the error CHAIN_IDS was rejected with, if any, in which case
+// Chains is empty
+func (c *Config) CheckChains() error {
+	return c.chainsErr
+}
+
+// Chain returns the registry entry for chainID
+func (c *Config) Chain(chainID int64) (ChainConfig, bool) {
+	for _, chain := range c.Chains {
+		if chain.ChainID == chainID {
+			return chain, true
+		}
+	}
+	return ChainConfig{}, false
+}
+
+// ForChain returns a copy of the configuration with the network settings replaced by
+// chain's, for the client of that chain. Signing, gas, price and application settings are
+// shared by every chain.
+func (c *Config) ForChain(chain ChainConfig) *Config {
+	cfg := *c
+	cfg.NetworkID = chain.ChainID
+	cfg.EthereumRPCURL = ""
+	if len(chain.RPCURLs) > 0 {
+		cfg.EthereumRPCURL = chain.RPCURLs[0]
+	}
+	cfg.ContractAddress = chain.ContractAddress
+	cfg.ETHUSDPriceFeed = chain.ETHUSDPriceFeed
+	cfg.ExplorerURL = chain.ExplorerURL
+	cfg.ConfirmationDepth = chain.ConfirmationDepth
+	cfg.PriceFeedHeartbeat = chain.PriceFeedHeartbeat
+	cfg.IndexerStartBlock = chain.IndexerStartBlock
+	cfg.Chains = []ChainConfig{chain}
+	cfg.chainsErr = nil
+	return &cfg
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
@@ -12,15 +13,47 @@ type Config struct {
 	NetworkID       int64
 	ContractAddress string
 	PrivateKey      string
+	ExplorerURL     string // Block explorer of NetworkID, e.g. https://sepolia.etherscan.io
+
+	// Chains the gateway serves, NetworkID's first; see loadChains. Requests that do not
+	// name a chain are served on NetworkID's.
+	Chains    []ChainConfig
+	chainsErr error // why CHAIN_IDS was rejected; see CheckChains
+
+	// Transaction signing; see blockchain.NewSignerFromConfig
+	SignerType           string // private_key (default), keystore, external or http
+	KeystorePath         string // Encrypted keystore JSON file
+	KeystorePassword     string // Keystore passphrase
+	KeystorePasswordFile string // File holding the keystore passphrase, preferred over KeystorePassword
+	SignerURL            string // External or HTTP signer endpoint
+	SignerAddress        string // Account the external or HTTP signer signs for
+	SignerAuthToken      string // Bearer token for the HTTP signer
 
 	// Chainlink price feed addresses
 	ETHUSDPriceFeed string
 
+	// Price feed guards; answers failing them are rejected rather than quoted
+	PriceFeedHeartbeat   int   // Seconds after which an answer is stale
+	PriceMaxDeviationBps int64 // Largest accepted move from the last accepted answer, in basis points
+
+	// Price sources besides the Chainlink feed (or the escrow contract without one), and how many must agree
+	PriceHTTPURL   string // JSON HTTP price API, such as CoinGecko's /simple/price
+	PriceHTTPField string // Dot-separated path to the USD price in its response
+	PriceQuorum    int    // Sources that must answer; 0 means a majority
+
 	// Application settings
 	FeePercentage int
 	GasLimit      uint64
 	GasPrice      int64 // in Gwei
 
+	// Blocks a transaction must be buried under before a status transition is final
+	ConfirmationDepth uint64
+
+	// Stuck transaction replacement
+	FeeBumpPercent int   // Fee increase per replacement, at least 10
+	MaxFeeCapGwei  int64 // Ceiling for replacement fees, in Gwei
+	TxReplaceAfter int   // Seconds a transaction may stay unmined before it is replaced
+
 	// Database settings
 	DBHost      string
 	DBPort      string
@@ -31,6 +64,29 @@ type Config struct {
 
 	// Server settings
 	ServerPort string
+
+	// HTTP API authentication; see auth.ParseKeys for the key format
+	APIKeys          []string // id:secret:scopes entries, e.g. backend:s3cret:read+write
+	ActingUserSecret string   // HS256 key of the X-Acting-User JWTs issued by the main app
+	AuthDisabled     bool     // Serve without authentication, for local development only
+
+	// Sign-In-With-Ethereum wallet verification; the domain and URI wallets show the user
+	SIWEDomain string
+	SIWEURI    string
+
+	// Chain event indexer settings
+	IndexerStartBlock   uint64 // First block to scan when no cursor is stored
+	IndexerBatchSize    uint64 // Maximum blocks per log query
+	IndexerPollInterval int    // in seconds
+
+	// Outgoing webhook settings
+	WebhookURLs        []string // Endpoints notified of payment status changes
+	WebhookSecret      string   // HMAC-SHA256 key used to sign webhook payloads
+	WebhookMaxAttempts int      // Deliveries are marked failed after this many attempts
+
+	// Payment reconciler settings
+	ReconcileInterval int // Seconds between passes over non-terminal applications
+	ReconcileSettle   int // Seconds an application's ledger must be quiet before it is reconciled
 }
 
 func Load() *Config {
@@ -41,13 +97,31 @@ func Load() *Config {
 		ContractAddress: getEnv("CONTRACT_ADDRESS", ""),
 		PrivateKey:      getEnv("PRIVATE_KEY", ""),
 
+		SignerType:           getEnv("SIGNER_TYPE", ""),
+		KeystorePath:         getEnv("KEYSTORE_PATH", ""),
+		KeystorePassword:     getEnv("KEYSTORE_PASSWORD", ""),
+		KeystorePasswordFile: getEnv("KEYSTORE_PASSWORD_FILE", ""),
+		SignerURL:            getEnv("SIGNER_URL", ""),
+		SignerAddress:        getEnv("SIGNER_ADDRESS", ""),
+		SignerAuthToken:      getEnv("SIGNER_AUTH_TOKEN", ""),
+
 		// Sepolia ETH/USD price feed
 		ETHUSDPriceFeed: getEnv("ETH_USD_PRICE_FEED", "0x694AA1769357215DE4FAC081bf1f309aDC325306"),
 
+		PriceMaxDeviationBps: getEnvAsInt64("PRICE_MAX_DEVIATION_BPS", 1000), // 10%
+
+		PriceHTTPURL:   getEnv("PRICE_HTTP_URL", ""),
+		PriceHTTPField: getEnv("PRICE_HTTP_FIELD", "ethereum.usd"),
+		PriceQuorum:    getEnvAsInt("PRICE_QUORUM", 0),
+
 		FeePercentage: getEnvAsInt("FEE_PERCENTAGE", 5),
 		GasLimit:      getEnvAsUint64("GAS_LIMIT", 300000),
 		GasPrice:      getEnvAsInt64("GAS_PRICE", 20), // 20 Gwei
 
+		FeeBumpPercent: getEnvAsInt("FEE_BUMP_PERCENT", 15),
+		MaxFeeCapGwei:  getEnvAsInt64("MAX_FEE_CAP_GWEI", 500),
+		TxReplaceAfter: getEnvAsInt("TX_REPLACE_AFTER", 60),
+
 		// Database settings
 		DBHost:     getEnv("DB_HOST", "localhost"),
 		DBPort:     getEnv("DB_PORT", "5432"),
@@ -56,8 +130,47 @@ func Load() *Config {
 		DBName:     getEnv("DB_NAME", "fyp-go"),
 
 		ServerPort: getEnv("SERVER_PORT", "8081"),
+
+		APIKeys:          getEnvAsSlice("API_KEYS"),
+		ActingUserSecret: getEnv("ACTING_USER_SECRET", ""),
+		AuthDisabled:     getEnv("AUTH_DISABLED", "") == "true",
+
+		SIWEDomain: getEnv("SIWE_DOMAIN", "localhost:8081"),
+		SIWEURI:    getEnv("SIWE_URI", "http://localhost:8081"),
+
+		IndexerStartBlock:   getEnvAsUint64("INDEXER_START_BLOCK", 0),
+		IndexerBatchSize:    getEnvAsUint64("INDEXER_BATCH_SIZE", 2000),
+		IndexerPollInterval: getEnvAsInt("INDEXER_POLL_INTERVAL", 15),
+
+		WebhookURLs:        getEnvAsSlice("WEBHOOK_URLS"),
+		WebhookSecret:      getEnv("WEBHOOK_SECRET", ""),
+		WebhookMaxAttempts: getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 8),
+
+		ReconcileInterval: getEnvAsInt("RECONCILE_INTERVAL", 300),
+		ReconcileSettle:   getEnvAsInt("RECONCILE_SETTLE", 600),
+	}
+
+	cfg.ExplorerURL = getEnv("EXPLORER_URL", Networks[cfg.NetworkID].ExplorerURL)
+
+	// Confirmation depth defaults to the network's recommended value
+	cfg.ConfirmationDepth = getEnvAsUint64("CONFIRMATION_DEPTH", Networks[cfg.NetworkID].ConfirmationDepth)
+	if cfg.ConfirmationDepth == 0 {
+		cfg.ConfirmationDepth = 1
+	}
+
+	// The staleness limit defaults to the network's feed heartbeat
+	cfg.PriceFeedHeartbeat = getEnvAsInt("PRICE_FEED_HEARTBEAT", Networks[cfg.NetworkID].PriceFeedHeartbeat)
+	if cfg.PriceFeedHeartbeat <= 0 {
+		cfg.PriceFeedHeartbeat = 3600
+	}
+
+	// Nodes reject same-nonce replacements that raise fees by less than 10%
+	if cfg.FeeBumpPercent < 10 {
+		cfg.FeeBumpPercent = 10
 	}
 
+	cfg.Chains, cfg.chainsErr = loadChains(cfg)
+
 	// Construct database URL
 	cfg.DatabaseURL = fmt.Sprintf("postgres://%s:%s@%s:%s/%s",
 		cfg.DBUser,
@@ -104,25 +217,53 @@ func getEnvAsUint64(key string, defaultValue uint64) uint64 {
 	return defaultValue
 }
 
+// getEnvAsSlice splits a comma-separated variable, dropping empty entries
+func getEnvAsSlice(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 // Network configurations
 var Networks = map[int64]NetworkConfig{
 	1: { // Mainnet
-		Name:            "ethereum",
-		ChainID:         1,
-		ETHUSDPriceFeed: "0x5f4eC3Df9cbd43714FE2740f5E3616155c5b8419",
-		ExplorerURL:     "https://etherscan.io",
+		Name:               "ethereum",
+		ChainID:            1,
+		ETHUSDPriceFeed:    "0x5f4eC3Df9cbd43714FE2740f5E3616155c5b8419",
+		ExplorerURL:        "https://etherscan.io",
+		ConfirmationDepth:  12,
+		PriceFeedHeartbeat: 3600,
 	},
 	11155111: { // Sepolia
-		Name:            "sepolia",
-		ChainID:         11155111,
-		ETHUSDPriceFeed: "0x694AA1769357215DE4FAC081bf1f309aDC325306",
-		ExplorerURL:     "https://sepolia.etherscan.io",
+		Name:               "sepolia",
+		ChainID:            11155111,
+		ETHUSDPriceFeed:    "0x694AA1769357215DE4FAC081bf1f309aDC325306",
+		ExplorerURL:        "https://sepolia.etherscan.io",
+		ConfirmationDepth:  3,
+		PriceFeedHeartbeat: 3600,
+	},
+	84532: { // Base Sepolia
+		Name:               "base-sepolia",
+		ChainID:            84532,
+		RPCURL:             "https://sepolia.base.org",
+		ETHUSDPriceFeed:    "0x4aDC67696bA383F43DD60A9e78F2C97Fbbfc7cb1",
+		ExplorerURL:        "https://sepolia.basescan.org",
+		ConfirmationDepth:  10,
+		PriceFeedHeartbeat: 1200,
 	},
 }
 
 type NetworkConfig struct {
-	Name            string
-	ChainID         int64
-	ETHUSDPriceFeed string
-	ExplorerURL     string
+	Name              string
+	ChainID           int64
+	RPCURL            string // Public endpoint, used when none is configured
+	ETHUSDPriceFeed   string
+	ExplorerURL       string
+	ConfirmationDepth uint64 // Blocks required before a transaction is treated as final
+
+	PriceFeedHeartbeat int // Seconds between ETH/USD feed updates when the price is flat
 }
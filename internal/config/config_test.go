@@ -65,3 +65,73 @@ func TestNetworkConfigs(t *testing.T) {
 		t.Errorf("Expected sepolia ChainID to be 11155111, got %d", sepolia.ChainID)
 	}
 }
+
+func TestConfirmationDepth(t *testing.T) {
+	// Defaults to the network's recommended depth
+	cfg := Load()
+	if cfg.ConfirmationDepth != Networks[11155111].ConfirmationDepth {
+		t.Errorf("Expected default ConfirmationDepth to be %d, got %d", Networks[11155111].ConfirmationDepth, cfg.ConfirmationDepth)
+	}
+
+	// Explicit override wins
+	os.Setenv("CONFIRMATION_DEPTH", "20")
+	defer os.Unsetenv("CONFIRMATION_DEPTH")
+
+	cfg = Load()
+	if cfg.ConfirmationDepth != 20 {
+		t.Errorf("Expected ConfirmationDepth to be 20, got %d", cfg.ConfirmationDepth)
+	}
+}
+
+func TestChains(t *testing.T) {
+	t.Setenv("CONTRACT_ADDRESS", "0x1111111111111111111111111111111111111111")
+	t.Setenv("CHAIN_IDS", "84532")
+	t.Setenv("CHAIN_84532_CONTRACT_ADDRESS", "0x2222222222222222222222222222222222222222")
+
+	cfg := Load()
+	if err := cfg.CheckChains(); err != nil {
+		t.Fatalf("CheckChains: %v", err)
+	}
+	if len(cfg.Chains) != 2 {
+		t.Fatalf("Expected the default chain and Base Sepolia, got %+v", cfg.Chains)
+	}
+	if cfg.Chains[0].ChainID != cfg.NetworkID || cfg.Chains[0].ContractAddress != cfg.ContractAddress {
+		t.Errorf("Expected NetworkID's chain first, got %+v", cfg.Chains[0])
+	}
+
+	// Base Sepolia works out of the box apart from the contract address
+	base, ok := cfg.Chain(84532)
+	if !ok {
+		t.Fatal("Expected chain 84532 to be configured")
+	}
+	if len(base.RPCURLs) != 1 || base.RPCURLs[0] != Networks[84532].RPCURL {
+		t.Errorf("Expected the public Base Sepolia RPC URL, got %v", base.RPCURLs)
+	}
+	if base.ETHUSDPriceFeed != Networks[84532].ETHUSDPriceFeed || base.ConfirmationDepth != Networks[84532].ConfirmationDepth {
+		t.Errorf("Expected Base Sepolia network defaults, got %+v", base)
+	}
+
+	chainCfg := cfg.ForChain(base)
+	if chainCfg.NetworkID != 84532 || chainCfg.ContractAddress != base.ContractAddress || chainCfg.EthereumRPCURL != base.RPCURLs[0] {
+		t.Errorf("ForChain did not switch networks: %+v", chainCfg)
+	}
+	if chainCfg.FeePercentage != cfg.FeePercentage || cfg.NetworkID == 84532 {
+		t.Error("ForChain should copy shared settings and leave the original alone")
+	}
+}
+
+func TestChainsRejectsBadIDs(t *testing.T) {
+	t.Setenv("NETWORK_ID", "11155111")
+	for _, ids := range []string{"84532,bogus", "-5", "84532, 84532", "84532,11155111"} {
+		t.Setenv("CHAIN_IDS", ids)
+		cfg := Load()
+		if err := cfg.CheckChains(); err == nil {
+			t.Errorf("CHAIN_IDS=%q: expected an error", ids)
+		} else if len(cfg.Chains) != 0 {
+			t.Errorf("CHAIN_IDS=%q: expected no chains, got %+v", ids, cfg.Chains)
+		}
+	}
+	if err := Load().ForChain(ChainConfig{ChainID: 1}).CheckChains(); err != nil {
+		t.Errorf("ForChain copied the CHAIN_IDS error: %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package escrowsim
+
+import (
+	"maps"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/program"
+)
+
+// AggregatorABI is the subset of Chainlink's MockV3Aggregator implemented by the stand-in
+const AggregatorABI = `[
+	{"type":"constructor","inputs":[{"name":"_decimals","type":"uint8"},{"name":"_initialAnswer","type":"int256"}],"stateMutability":"nonpayable"},
+	{"type":"function","name":"decimals","inputs":[],"outputs":[{"name":"","type":"uint8"}],"stateMutability":"view"},
+	{"type":"function","name":"latestRoundData","inputs":[],"outputs":[{"name":"roundId","type":"uint80"},{"name":"answer","type":"int256"},{"name":"startedAt","type":"uint256"},{"name":"updatedAt","type":"uint256"},{"name":"answeredInRound","type":"uint80"}],"stateMutability":"view"},
+	{"type":"function","name":"updateAnswer","inputs":[{"name":"_answer","type":"int256"}],"outputs":[],"stateMutability":"nonpayable"},
+	{"type":"function","name":"updateRoundData","inputs":[{"name":"_roundId","type":"uint80"},{"name":"_answer","type":"int256"},{"name":"_timestamp","type":"uint256"},{"name":"_startedAt","type":"uint256"}],"outputs":[],"stateMutability":"nonpayable"}
+]`
+
+// Aggregator storage layout
+const (
+	slotRoundID   = 0
+	slotAnswer    = 1
+	slotStartedAt = 2
+	slotUpdatedAt = 3
+	slotDecimals  = 4
+)
+
+// aggregatorMethods are dispatched in this order
+var aggregatorMethods = []string{"decimals", "latestRoundData", "updateAnswer", "updateRoundData"}
+
+// AggregatorBytecode returns deployable bytecode for a MockV3Aggregator-style price feed.
+// updateAnswer starts a new round stamped with the block time; updateRoundData sets every
+// field directly, which lets tests simulate stale or incomplete rounds.
+func AggregatorBytecode() ([]byte, error) {
+	parsed, err := abi.JSON(strings.NewReader(AggregatorABI))
+	if err != nil {
+		return nil, err
+	}
+
+	runtime := resolveJumps(func(targets map[string]uint64) ([]byte, map[string]uint64) {
+		return aggregatorRuntime(parsed, targets)
+	})
+	return aggregatorConstructor(runtime), nil
+}
+
+// resolveJumps returns the code build produces once it jumps to where its labels land.
+// Bodies follow a dispatcher, so their offsets are only known once it is built; build is
+// rerun with the offsets it reported until they no longer move.
+func resolveJumps[K comparable](build func(targets map[K]uint64) ([]byte, map[K]uint64)) []byte {
+	targets := make(map[K]uint64)
+	for {
+		code, placed := build(targets)
+		if maps.Equal(placed, targets) {
+			return code
+		}
+		targets = placed
+	}
+}
+
+// aggregatorRuntime builds the feed's runtime code jumping to targets, and returns it with
+// the offsets its method bodies ended up at
+func aggregatorRuntime(parsed abi.ABI, targets map[string]uint64) ([]byte, map[string]uint64) {
+	p := program.New()
+	p.Push(0).Op(vm.CALLDATALOAD).Push(0xe0).Op(vm.SHR)
+	for _, name := range aggregatorMethods {
+		p.Op(vm.DUP1).Push(parsed.Methods[name].ID).Op(vm.EQ).Push(targets[name]).Op(vm.JUMPI)
+	}
+	p.Push(0).Op(vm.DUP1).Op(vm.REVERT)
+
+	placed := make(map[string]uint64, len(aggregatorMethods))
+	body := func(name string) {
+		_, placed[name] = p.Jumpdest()
+	}
+
+	// decimals()
+	body("decimals")
+	p.Push(slotDecimals).Op(vm.SLOAD).Push(0).Op(vm.MSTORE).Return(0, 32)
+
+	// latestRoundData() returns the round id again as answeredInRound
+	body("latestRoundData")
+	for i, slot := range []int{slotRoundID, slotAnswer, slotStartedAt, slotUpdatedAt, slotRoundID} {
+		p.Push(slot).Op(vm.SLOAD).Push(i * 32).Op(vm.MSTORE)
+	}
+	p.Return(0, 0xa0)
+
+	// updateAnswer(int256 _answer)
+	body("updateAnswer")
+	p.Push(slotRoundID).Op(vm.SLOAD).Push(1).Op(vm.ADD).Push(slotRoundID).Op(vm.SSTORE)
+	p.Push(4).Op(vm.CALLDATALOAD).Push(slotAnswer).Op(vm.SSTORE)
+	p.Op(vm.TIMESTAMP).Push(slotStartedAt).Op(vm.SSTORE)
+	p.Op(vm.TIMESTAMP).Push(slotUpdatedAt).Op(vm.SSTORE)
+	p.Op(vm.STOP)
+
+	// updateRoundData(uint80 _roundId, int256 _answer, uint256 _timestamp, uint256 _startedAt)
+	body("updateRoundData")
+	p.Push(4).Op(vm.CALLDATALOAD).Push(slotRoundID).Op(vm.SSTORE)
+	p.Push(0x24).Op(vm.CALLDATALOAD).Push(slotAnswer).Op(vm.SSTORE)
+	p.Push(0x44).Op(vm.CALLDATALOAD).Push(slotUpdatedAt).Op(vm.SSTORE)
+	p.Push(0x64).Op(vm.CALLDATALOAD).Push(slotStartedAt).Op(vm.SSTORE)
+	p.Op(vm.STOP)
+
+	return p.Bytes(), placed
+}
+
+// aggregatorConstructor returns init code for constructor(uint8 _decimals, int256
+// _initialAnswer), which behaves like updateAnswer, deploying runtime
+func aggregatorConstructor(runtime []byte) []byte {
+	p := program.New()
+	// The constructor arguments are the last two words of the code
+	p.Push(64).Push(64).Op(vm.CODESIZE).Op(vm.SUB).Push(0).Op(vm.CODECOPY)
+	p.Push(0).Op(vm.MLOAD).Push(slotDecimals).Op(vm.SSTORE)
+	p.Push(0x20).Op(vm.MLOAD).Push(slotAnswer).Op(vm.SSTORE)
+	p.Sstore(slotRoundID, 1)
+	p.Op(vm.TIMESTAMP).Push(slotStartedAt).Op(vm.SSTORE)
+	p.Op(vm.TIMESTAMP).Push(slotUpdatedAt).Op(vm.SSTORE)
+	return p.ReturnViaCodeCopy(runtime).Bytes()
+}
+
+// Aggregator is a deployed stand-in price feed
+type Aggregator struct {
+	Address  common.Address
+	contract *bind.BoundContract
+}
+
+// DeployAggregator deploys a price feed reporting answer with the given decimals
+func DeployAggregator(auth *bind.TransactOpts, backend bind.ContractBackend, decimals uint8, answer *big.Int) (*Aggregator, *types.Transaction, error) {
+	parsed, err := abi.JSON(strings.NewReader(AggregatorABI))
+	if err != nil {
+		return nil, nil, err
+	}
+	code, err := AggregatorBytecode()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	address, tx, contract, err := bind.DeployContract(auth, parsed, code, backend, decimals, answer)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Aggregator{Address: address, contract: contract}, tx, nil
+}
+
+// UpdateAnswer starts a new round reporting answer
+func (a *Aggregator) UpdateAnswer(auth *bind.TransactOpts, answer *big.Int) (*types.Transaction, error) {
+	return a.contract.Transact(auth, "updateAnswer", answer)
+}
+
+// UpdateRoundData overwrites the latest round
+func (a *Aggregator) UpdateRoundData(auth *bind.TransactOpts, roundID, answer, updatedAt, startedAt *big.Int) (*types.Transaction, error) {
+	return a.contract.Transact(auth, "updateRoundData", roundID, answer, updatedAt, startedAt)
+}
@@ -0,0 +1,91 @@
+package escrowsim
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/fahedafzaal/go-integration/contracts"
+)
+
+func TestAggregator(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	backend := NewBackend(crypto.PubkeyToAddress(key.PublicKey))
+	defer backend.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	go Mine(ctx, backend, 20*time.Millisecond)
+
+	client := backend.Client()
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		t.Fatalf("ChainID: %v", err)
+	}
+	auth, err := bind.NewKeyedTransactorWithChainID(key, chainID)
+	if err != nil {
+		t.Fatalf("NewKeyedTransactorWithChainID: %v", err)
+	}
+
+	feed, tx, err := DeployAggregator(auth, client, 8, big.NewInt(2000_00000000))
+	if err != nil {
+		t.Fatalf("DeployAggregator: %v", err)
+	}
+	if _, err := bind.WaitDeployed(ctx, client, tx); err != nil {
+		t.Fatalf("WaitDeployed: %v", err)
+	}
+
+	// Read through the AggregatorV3Interface bindings the gateway uses
+	reader, err := contracts.NewAggregatorV3Interface(feed.Address, client)
+	if err != nil {
+		t.Fatalf("NewAggregatorV3Interface: %v", err)
+	}
+	decimals, err := reader.Decimals(nil)
+	if err != nil || decimals != 8 {
+		t.Errorf("decimals = %d, %v; want 8", decimals, err)
+	}
+	round, err := reader.LatestRoundData(nil)
+	if err != nil {
+		t.Fatalf("LatestRoundData: %v", err)
+	}
+	if round.RoundId.Int64() != 1 || round.Answer.Int64() != 2000_00000000 || round.UpdatedAt.Sign() == 0 {
+		t.Errorf("initial round = %+v", round)
+	}
+
+	tx, err = feed.UpdateAnswer(auth, big.NewInt(2100_00000000))
+	if err != nil {
+		t.Fatalf("UpdateAnswer: %v", err)
+	}
+	if _, err := bind.WaitMined(ctx, client, tx); err != nil {
+		t.Fatalf("WaitMined: %v", err)
+	}
+	round, err = reader.LatestRoundData(nil)
+	if err != nil {
+		t.Fatalf("LatestRoundData: %v", err)
+	}
+	if round.RoundId.Int64() != 2 || round.Answer.Int64() != 2100_00000000 || round.AnsweredInRound.Int64() != 2 {
+		t.Errorf("updated round = %+v", round)
+	}
+
+	stale := big.NewInt(1000)
+	tx, err = feed.UpdateRoundData(auth, big.NewInt(9), big.NewInt(1900_00000000), stale, stale)
+	if err != nil {
+		t.Fatalf("UpdateRoundData: %v", err)
+	}
+	if _, err := bind.WaitMined(ctx, client, tx); err != nil {
+		t.Fatalf("WaitMined: %v", err)
+	}
+	round, err = reader.LatestRoundData(nil)
+	if err != nil {
+		t.Fatalf("LatestRoundData: %v", err)
+	}
+	if round.RoundId.Int64() != 9 || round.UpdatedAt.Cmp(stale) != 0 || round.StartedAt.Cmp(stale) != 0 {
+		t.Errorf("overwritten round = %+v", round)
+	}
+}
@@ -0,0 +1,97 @@
+package escrowsim
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/program"
+)
+
+// EmitterBytecode returns deployable bytecode for a contract that emits whatever log it is
+// called with. Calldata is the topic count (1 to 4) as a word, the topics, then the log
+// data. Deployed in place of EthJobEscrow, it lets tests produce escrow events without the
+// contract's compiled bytecode.
+func EmitterBytecode() []byte {
+	runtime := resolveJumps(func(targets map[int]uint64) ([]byte, map[int]uint64) {
+		p := program.New()
+		p.Push(0).Op(vm.CALLDATALOAD)
+		for n := 1; n <= 4; n++ {
+			p.Op(vm.DUP1).Push(n).Op(vm.EQ).Push(targets[n]).Op(vm.JUMPI)
+		}
+		p.Push(0).Op(vm.DUP1).Op(vm.REVERT)
+
+		placed := make(map[int]uint64, 4)
+		for n := 1; n <= 4; n++ {
+			_, placed[n] = p.Jumpdest()
+			// Topics are pushed last first, so the first is on top for LOGn
+			for i := n; i >= 1; i-- {
+				p.Push(i * 32).Op(vm.CALLDATALOAD)
+			}
+			data := (n + 1) * 32
+			p.Push(data).Op(vm.CALLDATASIZE).Op(vm.SUB)
+			p.Op(vm.DUP1).Push(data).Push(0).Op(vm.CALLDATACOPY)
+			p.Push(0).Op(vm.LOG0 + vm.OpCode(n))
+			p.Op(vm.STOP)
+		}
+		return p.Bytes(), placed
+	})
+	return program.New().ReturnViaCodeCopy(runtime).Bytes()
+}
+
+// Emitter is a deployed log emitter
+type Emitter struct {
+	Address  common.Address
+	contract *bind.BoundContract
+}
+
+// DeployEmitter deploys a log emitter
+func DeployEmitter(auth *bind.TransactOpts, backend bind.ContractBackend) (*Emitter, *types.Transaction, error) {
+	address, tx, contract, err := bind.DeployContract(auth, abi.ABI{}, EmitterBytecode(), backend)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Emitter{Address: address, contract: contract}, tx, nil
+}
+
+// Emit sends a transaction that logs data under topics
+func (e *Emitter) Emit(auth *bind.TransactOpts, topics []common.Hash, data []byte) (*types.Transaction, error) {
+	calldata := common.LeftPadBytes([]byte{byte(len(topics))}, 32)
+	for _, topic := range topics {
+		calldata = append(calldata, topic.Bytes()...)
+	}
+	return e.contract.RawTransact(auth, append(calldata, data...))
+}
+
+// EmitEvent sends a transaction that logs event of contractABI as the contract would, with
+// args in the event's input order
+func (e *Emitter) EmitEvent(auth *bind.TransactOpts, contractABI *abi.ABI, event string, args ...interface{}) (*types.Transaction, error) {
+	ev, ok := contractABI.Events[event]
+	if !ok {
+		return nil, fmt.Errorf("no event %s", event)
+	}
+	if len(args) != len(ev.Inputs) {
+		return nil, fmt.Errorf("event %s takes %d arguments, got %d", event, len(ev.Inputs), len(args))
+	}
+	topics := []common.Hash{ev.ID}
+	var data []interface{}
+	for i, input := range ev.Inputs {
+		if !input.Indexed {
+			data = append(data, args[i])
+			continue
+		}
+		indexed, err := abi.MakeTopics([]interface{}{args[i]})
+		if err != nil {
+			return nil, err
+		}
+		topics = append(topics, indexed[0][0])
+	}
+	packed, err := ev.Inputs.NonIndexed().Pack(data...)
+	if err != nil {
+		return nil, err
+	}
+	return e.Emit(auth, topics, packed)
+}
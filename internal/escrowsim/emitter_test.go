@@ -0,0 +1,88 @@
+package escrowsim
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/fahedafzaal/go-integration/contracts"
+)
+
+func TestEmitter(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	backend := NewBackend(crypto.PubkeyToAddress(key.PublicKey))
+	defer backend.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	go Mine(ctx, backend, 20*time.Millisecond)
+
+	client := backend.Client()
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		t.Fatalf("ChainID: %v", err)
+	}
+	auth, err := bind.NewKeyedTransactorWithChainID(key, chainID)
+	if err != nil {
+		t.Fatalf("NewKeyedTransactorWithChainID: %v", err)
+	}
+
+	emitter, tx, err := DeployEmitter(auth, client)
+	if err != nil {
+		t.Fatalf("DeployEmitter: %v", err)
+	}
+	if _, err := bind.WaitDeployed(ctx, client, tx); err != nil {
+		t.Fatalf("WaitDeployed: %v", err)
+	}
+
+	escrowABI, err := contracts.EthJobEscrowMetaData.GetAbi()
+	if err != nil {
+		t.Fatalf("GetAbi: %v", err)
+	}
+	escrow, err := contracts.NewEthJobEscrowFilterer(emitter.Address, client)
+	if err != nil {
+		t.Fatalf("NewEthJobEscrowFilterer: %v", err)
+	}
+
+	// Read back through the EthJobEscrow bindings, as the gateway does
+	poster, freelancer := common.HexToAddress("0x01"), common.HexToAddress("0x02")
+	tx, err = emitter.EmitEvent(auth, escrowABI, "JobPosted", big.NewInt(7), poster, freelancer, big.NewInt(100_00000000), big.NewInt(5e16))
+	if err != nil {
+		t.Fatalf("EmitEvent JobPosted: %v", err)
+	}
+	receipt, err := bind.WaitMined(ctx, client, tx)
+	if err != nil {
+		t.Fatalf("WaitMined: %v", err)
+	}
+	if len(receipt.Logs) != 1 {
+		t.Fatalf("JobPosted emitted %d logs, want 1", len(receipt.Logs))
+	}
+	posted, err := escrow.ParseJobPosted(*receipt.Logs[0])
+	if err != nil {
+		t.Fatalf("ParseJobPosted: %v", err)
+	}
+	if posted.JobId.Int64() != 7 || posted.Client != poster || posted.Freelancer != freelancer ||
+		posted.UsdAmount.Int64() != 100_00000000 || posted.EthAmount.Int64() != 5e16 {
+		t.Errorf("JobPosted = %+v", posted)
+	}
+
+	tx, err = emitter.EmitEvent(auth, escrowABI, "JobCompleted", big.NewInt(7))
+	if err != nil {
+		t.Fatalf("EmitEvent JobCompleted: %v", err)
+	}
+	receipt, err = bind.WaitMined(ctx, client, tx)
+	if err != nil {
+		t.Fatalf("WaitMined: %v", err)
+	}
+	completed, err := escrow.ParseJobCompleted(*receipt.Logs[0])
+	if err != nil || completed.JobId.Int64() != 7 {
+		t.Errorf("JobCompleted = %+v, %v", completed, err)
+	}
+}
@@ -0,0 +1,100 @@
+// Package escrowsim deploys the escrow contract and a mock Chainlink price feed on
+// go-ethereum's simulated backend, so the gateway can be tested end to end offline.
+package escrowsim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+
+	"github.com/fahedafzaal/go-integration/contracts"
+)
+
+// FundedBalance is the genesis balance of every account passed to NewBackend
+var FundedBalance = new(big.Int).Mul(big.NewInt(1000), big.NewInt(1e18))
+
+// NewBackend starts a simulated chain with each of funded holding FundedBalance
+func NewBackend(funded ...common.Address) *simulated.Backend {
+	alloc := make(types.GenesisAlloc, len(funded))
+	for _, address := range funded {
+		alloc[address] = types.Account{Balance: new(big.Int).Set(FundedBalance)}
+	}
+	backend := simulated.NewBackend(alloc)
+
+	// The genesis block has a zero timestamp, which makes gas estimates against it
+	// undercount contracts that store the block time
+	Commit(backend)
+	return backend
+}
+
+// Commit mines a block and waits for the node to index it. Until then, looking up a
+// transaction the node does not know fails with "transaction indexing is in progress"
+// instead of reporting it not found.
+func Commit(backend *simulated.Backend) common.Hash {
+	hash := backend.Commit()
+
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		_, _, err := backend.Client().TransactionByHash(context.Background(), common.Hash{})
+		if errors.Is(err, ethereum.NotFound) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return hash
+}
+
+// Mine commits a block every interval until ctx is cancelled, standing in for the
+// block production a client waiting on receipts expects from a real network
+func Mine(ctx context.Context, backend *simulated.Backend, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			backend.Commit()
+		}
+	}
+}
+
+// ErrNoEscrowBytecode is returned by DeployEscrow when the bindings were generated without
+// the contract's compiled bytecode; see make bindings
+var ErrNoEscrowBytecode = errors.New("contracts.EthJobEscrowMetaData has no compiled bytecode")
+
+// DeployEscrow deploys EthJobEscrow, from the compiled bytecode in its bindings, reading
+// prices from priceFeed
+func DeployEscrow(auth *bind.TransactOpts, backend bind.ContractBackend, priceFeed, owner common.Address) (common.Address, *types.Transaction, *contracts.EthJobEscrow, error) {
+	if contracts.EthJobEscrowMetaData.Bin == "" {
+		return common.Address{}, nil, nil, ErrNoEscrowBytecode
+	}
+	parsed, err := contracts.EthJobEscrowMetaData.GetAbi()
+	if err != nil {
+		return common.Address{}, nil, nil, fmt.Errorf("failed to parse escrow ABI: %w", err)
+	}
+	code, err := hexutil.Decode(contracts.EthJobEscrowMetaData.Bin)
+	if err != nil {
+		return common.Address{}, nil, nil, fmt.Errorf("failed to decode escrow bytecode: %w", err)
+	}
+
+	address, tx, _, err := bind.DeployContract(auth, *parsed, code, backend, priceFeed, owner)
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	escrow, err := contracts.NewEthJobEscrow(address, backend)
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	return address, tx, escrow, nil
+}
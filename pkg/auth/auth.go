@@ -0,0 +1,285 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scopes granted to API keys
+const (
+	ScopeRead  = "read"  // job status, prices, operations and ledger reads
+	ScopeWrite = "write" // endpoints that move escrowed funds or payment status
+	ScopeAdmin = "admin" // webhook delivery log, ledger export, reconciler findings and manual status overrides
+)
+
+// Request headers. A caller either sends its secret as an API key, or signs the request
+// with it and sends the key id, timestamp, nonce and signature.
+const (
+	HeaderAPIKey    = "X-API-Key"
+	HeaderKeyID     = "X-Gateway-Key-Id"
+	HeaderTimestamp = "X-Gateway-Timestamp" // unix seconds the request was signed at
+	HeaderNonce     = "X-Gateway-Nonce"     // unique per request; each is accepted once
+	HeaderSignature = "X-Gateway-Signature" // sha256=<hex HMAC of the canonical request>
+)
+
+const signaturePrefix = "sha256="
+
+// maxSignedBody caps the request body read into memory to check a signature
+const maxSignedBody = 1 << 20
+
+// maxNonceLength bounds the nonces kept in memory to detect replays
+const maxNonceLength = 128
+
+var (
+	ErrUnauthenticated   = errors.New("missing or invalid credentials")
+	ErrInsufficientScope = errors.New("API key lacks the required scope")
+	ErrBodyTooLarge      = errors.New("signed request body exceeds 1 MiB")
+	ErrReplayed          = errors.New("signed request nonce was already used")
+)
+
+// Key is a caller's credential. The secret is both the plain API key and the HMAC key for
+// signed requests.
+type Key struct {
+	ID     string
+	Secret []byte
+	Scopes []string
+}
+
+// Allows reports whether the key grants scope. Admin keys may do everything.
+func (k *Key) Allows(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseKeys reads keys written as id:secret:scope+scope, for example
+// "backend:s3cret:read+write". Secrets may not contain ':' or ','.
+func ParseKeys(entries []string) ([]Key, error) {
+	keys := make([]Key, 0, len(entries))
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("invalid API key entry %q: expected id:secret:scopes", redact(entry))
+		}
+		if seen[parts[0]] {
+			return nil, fmt.Errorf("duplicate API key id %q", parts[0])
+		}
+		seen[parts[0]] = true
+
+		scopes := strings.Split(parts[2], "+")
+		for _, scope := range scopes {
+			switch scope {
+			case ScopeRead, ScopeWrite, ScopeAdmin:
+			default:
+				return nil, fmt.Errorf("API key %q has unknown scope %q", parts[0], scope)
+			}
+		}
+		keys = append(keys, Key{ID: parts[0], Secret: []byte(parts[1]), Scopes: scopes})
+	}
+	return keys, nil
+}
+
+// redact keeps a malformed key entry's id for error messages and hides the rest
+func redact(entry string) string {
+	if i := strings.Index(entry, ":"); i >= 0 {
+		return entry[:i] + ":***"
+	}
+	return "***"
+}
+
+// Sign returns the X-Gateway-Signature value for a request. The signed message binds the
+// timestamp, nonce, method, path with query and body, so a captured request can be
+// neither replayed nor redirected at another endpoint.
+func Sign(secret []byte, timestamp time.Time, nonce, method, requestURI string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%d\n%s\n%s\n%s\n%s", timestamp.Unix(), nonce, method, requestURI, hex.EncodeToString(bodyHash[:]))
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignRequest sets the signing headers on req, whose body must be body, with a fresh
+// random nonce
+func SignRequest(req *http.Request, keyID string, secret []byte, body []byte) {
+	now := time.Now()
+	nonce := rand.Text()
+	req.Header.Set(HeaderKeyID, keyID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderNonce, nonce)
+	req.Header.Set(HeaderSignature, Sign(secret, now, nonce, req.Method, req.URL.RequestURI(), body))
+}
+
+type contextKey struct{}
+
+// FromContext returns the key a request was authenticated with
+func FromContext(ctx context.Context) (*Key, bool) {
+	key, ok := ctx.Value(contextKey{}).(*Key)
+	return key, ok
+}
+
+// Authenticator checks API keys and request signatures against a fixed set of keys
+type Authenticator struct {
+	keys      map[string]*Key
+	tolerance time.Duration
+	nonces    nonceCache
+}
+
+// NewAuthenticator creates an authenticator accepting signatures made within tolerance
+// of now; a zero tolerance defaults to 5 minutes
+func NewAuthenticator(keys []Key, tolerance time.Duration) *Authenticator {
+	if tolerance <= 0 {
+		tolerance = 5 * time.Minute
+	}
+	a := &Authenticator{
+		keys:      make(map[string]*Key, len(keys)),
+		tolerance: tolerance,
+		nonces:    nonceCache{seen: make(map[string]time.Time)},
+	}
+	for i := range keys {
+		a.keys[keys[i].ID] = &keys[i]
+	}
+	return a
+}
+
+// Authenticate identifies the caller of r. Signed requests have their body read and put
+// back so the handler still sees it, and each nonce is accepted once per key while its
+// timestamp is within tolerance.
+func (a *Authenticator) Authenticate(r *http.Request) (*Key, error) {
+	if apiKey := r.Header.Get(HeaderAPIKey); apiKey != "" {
+		return a.byAPIKey(apiKey)
+	}
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return a.byAPIKey(bearer)
+	}
+
+	keyID := r.Header.Get(HeaderKeyID)
+	if keyID == "" {
+		return nil, ErrUnauthenticated
+	}
+	key, ok := a.keys[keyID]
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	unix, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	timestamp := time.Unix(unix, 0)
+	if age := time.Since(timestamp); age > a.tolerance || age < -a.tolerance {
+		return nil, ErrUnauthenticated
+	}
+
+	nonce := r.Header.Get(HeaderNonce)
+	if nonce == "" || len(nonce) > maxNonceLength {
+		return nil, ErrUnauthenticated
+	}
+
+	var body []byte
+	if r.Body != nil {
+		body, err = io.ReadAll(http.MaxBytesReader(nil, r.Body, maxSignedBody))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return nil, ErrBodyTooLarge
+			}
+			return nil, ErrUnauthenticated
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	expected := Sign(key.Secret, timestamp, nonce, r.Method, r.URL.RequestURI(), body)
+	if !hmac.Equal([]byte(r.Header.Get(HeaderSignature)), []byte(expected)) {
+		return nil, ErrUnauthenticated
+	}
+	if !a.nonces.use(key.ID+"\n"+nonce, timestamp.Add(a.tolerance)) {
+		return nil, ErrReplayed
+	}
+	return key, nil
+}
+
+// nonceCache remembers the nonces of accepted signed requests until their timestamps
+// fall outside the tolerance, after which the timestamp check rejects them anyway
+type nonceCache struct {
+	mu     sync.Mutex
+	seen   map[string]time.Time
+	pruned time.Time
+}
+
+// use records nonce as used until expires, reporting false if it was already used
+func (c *nonceCache) use(nonce string, expires time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.pruned) > time.Minute {
+		for n, exp := range c.seen {
+			if now.After(exp) {
+				delete(c.seen, n)
+			}
+		}
+		c.pruned = now
+	}
+	if exp, ok := c.seen[nonce]; ok && !now.After(exp) {
+		return false
+	}
+	c.seen[nonce] = expires
+	return true
+}
+
+// byAPIKey finds the key whose secret is apiKey, comparing every key in constant time
+func (a *Authenticator) byAPIKey(apiKey string) (*Key, error) {
+	var found *Key
+	for _, key := range a.keys {
+		if subtle.ConstantTimeCompare(key.Secret, []byte(apiKey)) == 1 {
+			found = key
+		}
+	}
+	if found == nil {
+		return nil, ErrUnauthenticated
+	}
+	return found, nil
+}
+
+// Require wraps next so it only runs for callers holding scope, answering 401 to
+// unauthenticated callers, 413 to signed bodies over the limit and 403 to keys without
+// the scope
+func (a *Authenticator) Require(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := a.Authenticate(r)
+		if errors.Is(err, ErrBodyTooLarge) {
+			log.Printf("Auth: rejected %s %s from %s: %v", r.Method, r.URL.Path, r.RemoteAddr, err)
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			log.Printf("Auth: rejected %s %s from %s: %v", r.Method, r.URL.Path, r.RemoteAddr, err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="payment-gateway"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !key.Allows(scope) {
+			log.Printf("Auth: key %s denied %s %s: needs %s scope", key.ID, r.Method, r.URL.Path, scope)
+			http.Error(w, "Forbidden: "+scope+" scope required", http.StatusForbidden)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, key)))
+	}
+}
@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testAuthenticator(t *testing.T) *Authenticator {
+	t.Helper()
+	keys, err := ParseKeys([]string{"backend:backend-secret:read+write", "dashboard:dashboard-secret:read"})
+	if err != nil {
+		t.Fatalf("ParseKeys: %v", err)
+	}
+	return NewAuthenticator(keys, 5*time.Minute)
+}
+
+func serve(a *Authenticator, scope string, r *http.Request) (*httptest.ResponseRecorder, string) {
+	var seen string
+	handler := a.Require(scope, func(w http.ResponseWriter, r *http.Request) {
+		key, _ := FromContext(r.Context())
+		body, _ := io.ReadAll(r.Body)
+		seen = key.ID + " " + string(body)
+	})
+	rec := httptest.NewRecorder()
+	handler(rec, r)
+	return rec, seen
+}
+
+func TestAPIKey(t *testing.T) {
+	a := testAuthenticator(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/job-status?job_id=1", nil)
+	r.Header.Set(HeaderAPIKey, "dashboard-secret")
+	if rec, seen := serve(a, ScopeRead, r); rec.Code != http.StatusOK || seen != "dashboard " {
+		t.Errorf("read with a read key: status %d, handler saw %q", rec.Code, seen)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/cancel-job?job_id=1", nil)
+	r.Header.Set("Authorization", "Bearer dashboard-secret")
+	if rec, _ := serve(a, ScopeWrite, r); rec.Code != http.StatusForbidden {
+		t.Errorf("write with a read key: status %d, want 403", rec.Code)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/cancel-job?job_id=1", nil)
+	r.Header.Set(HeaderAPIKey, "wrong")
+	if rec, _ := serve(a, ScopeWrite, r); rec.Code != http.StatusUnauthorized {
+		t.Errorf("unknown key: status %d, want 401", rec.Code)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/eth-price", nil)
+	if rec, _ := serve(a, ScopeRead, r); rec.Code != http.StatusUnauthorized {
+		t.Errorf("no credentials: status %d, want 401", rec.Code)
+	}
+}
+
+func TestSignedRequest(t *testing.T) {
+	a := testAuthenticator(t)
+	body := `{"job_id":7}`
+
+	r := httptest.NewRequest(http.MethodPost, "/post-job", strings.NewReader(body))
+	SignRequest(r, "backend", []byte("backend-secret"), []byte(body))
+	if rec, seen := serve(a, ScopeWrite, r); rec.Code != http.StatusOK || seen != "backend "+body {
+		t.Errorf("signed request: status %d, handler saw %q", rec.Code, seen)
+	}
+
+	// A signature does not carry over to a different body, path or secret
+	r = httptest.NewRequest(http.MethodPost, "/post-job", strings.NewReader(`{"job_id":8}`))
+	SignRequest(r, "backend", []byte("backend-secret"), []byte(body))
+	if rec, _ := serve(a, ScopeWrite, r); rec.Code != http.StatusUnauthorized {
+		t.Errorf("tampered body: status %d, want 401", rec.Code)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/cancel-job?job_id=7", nil)
+	r.Header.Set(HeaderKeyID, "backend")
+	now := time.Now()
+	r.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	r.Header.Set(HeaderNonce, "n1")
+	r.Header.Set(HeaderSignature, Sign([]byte("backend-secret"), now, "n1", http.MethodPost, "/complete-job?job_id=7", nil))
+	if rec, _ := serve(a, ScopeWrite, r); rec.Code != http.StatusUnauthorized {
+		t.Errorf("signature for another endpoint: status %d, want 401", rec.Code)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/eth-price", nil)
+	SignRequest(r, "backend", []byte("dashboard-secret"), nil)
+	if rec, _ := serve(a, ScopeRead, r); rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong secret: status %d, want 401", rec.Code)
+	}
+
+	old := time.Now().Add(-10 * time.Minute)
+	r = httptest.NewRequest(http.MethodGet, "/eth-price", nil)
+	r.Header.Set(HeaderKeyID, "backend")
+	r.Header.Set(HeaderTimestamp, strconv.FormatInt(old.Unix(), 10))
+	r.Header.Set(HeaderNonce, "n2")
+	r.Header.Set(HeaderSignature, Sign([]byte("backend-secret"), old, "n2", http.MethodGet, "/eth-price", nil))
+	if rec, _ := serve(a, ScopeRead, r); rec.Code != http.StatusUnauthorized {
+		t.Errorf("stale timestamp: status %d, want 401", rec.Code)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/eth-price", nil)
+	SignRequest(r, "backend", []byte("backend-secret"), nil)
+	r.Header.Del(HeaderNonce)
+	if rec, _ := serve(a, ScopeRead, r); rec.Code != http.StatusUnauthorized {
+		t.Errorf("missing nonce: status %d, want 401", rec.Code)
+	}
+}
+
+func TestSignedRequestReplay(t *testing.T) {
+	a := testAuthenticator(t)
+	body := `{"job_id":7}`
+
+	signed := httptest.NewRequest(http.MethodPost, "/release-payment", strings.NewReader(body))
+	SignRequest(signed, "backend", []byte("backend-secret"), []byte(body))
+	replay := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/release-payment", strings.NewReader(body))
+		r.Header = signed.Header.Clone()
+		return r
+	}
+
+	if rec, _ := serve(a, ScopeWrite, replay()); rec.Code != http.StatusOK {
+		t.Fatalf("first request: status %d, want 200", rec.Code)
+	}
+	if rec, seen := serve(a, ScopeWrite, replay()); rec.Code != http.StatusUnauthorized || seen != "" {
+		t.Errorf("replayed request: status %d, handler saw %q; want 401", rec.Code, seen)
+	}
+
+	// The same nonce under another key is a different request
+	other := httptest.NewRequest(http.MethodGet, "/eth-price", nil)
+	SignRequest(other, "dashboard", []byte("dashboard-secret"), nil)
+	other.Header.Set(HeaderNonce, signed.Header.Get(HeaderNonce))
+	now := time.Now()
+	other.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	other.Header.Set(HeaderSignature, Sign([]byte("dashboard-secret"), now, signed.Header.Get(HeaderNonce), http.MethodGet, "/eth-price", nil))
+	if rec, _ := serve(a, ScopeRead, other); rec.Code != http.StatusOK {
+		t.Errorf("same nonce, other key: status %d, want 200", rec.Code)
+	}
+}
+
+func TestSignedBodyTooLarge(t *testing.T) {
+	a := testAuthenticator(t)
+	body := strings.Repeat("x", maxSignedBody+1)
+
+	r := httptest.NewRequest(http.MethodPost, "/post-job", strings.NewReader(body))
+	SignRequest(r, "backend", []byte("backend-secret"), []byte(body))
+	if rec, seen := serve(a, ScopeWrite, r); rec.Code != http.StatusRequestEntityTooLarge || seen != "" {
+		t.Errorf("oversized body: status %d, handler saw %d bytes; want 413", rec.Code, len(seen))
+	}
+
+	// A body exactly at the limit is still accepted
+	body = body[:maxSignedBody]
+	r = httptest.NewRequest(http.MethodPost, "/post-job", strings.NewReader(body))
+	SignRequest(r, "backend", []byte("backend-secret"), []byte(body))
+	if rec, _ := serve(a, ScopeWrite, r); rec.Code != http.StatusOK {
+		t.Errorf("body at the limit: status %d, want 200", rec.Code)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys([]string{"ops:ops-secret:admin"})
+	if err != nil {
+		t.Fatalf("ParseKeys: %v", err)
+	}
+	if !keys[0].Allows(ScopeWrite) || !keys[0].Allows(ScopeRead) {
+		t.Error("admin keys should be allowed every scope")
+	}
+
+	for _, bad := range [][]string{
+		{"no-scopes:secret"},
+		{"k:secret:read+delete"},
+		{"k:a:read", "k:b:write"},
+	} {
+		if _, err := ParseKeys(bad); err == nil {
+			t.Errorf("ParseKeys(%q) succeeded, want an error", bad)
+		}
+	}
+}
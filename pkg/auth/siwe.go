@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var ErrSignatureMismatch = errors.New("signature does not match wallet address")
+
+// SIWEMessage is an EIP-4361 Sign-In-With-Ethereum message proving control of Address
+type SIWEMessage struct {
+	Domain    string // host the gateway is reached at, e.g. pay.example.com
+	Address   common.Address
+	Statement string
+	URI       string
+	ChainID   int64
+	Nonce     string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// String renders the message in the EIP-4361 layout a wallet displays and signs
+func (m SIWEMessage) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s wants you to sign in with your Ethereum account:\n%s\n\n", m.Domain, m.Address.Hex())
+	if m.Statement != "" {
+		fmt.Fprintf(&b, "%s\n\n", m.Statement)
+	}
+	fmt.Fprintf(&b, "URI: %s\nVersion: 1\nChain ID: %d\nNonce: %s\nIssued At: %s",
+		m.URI, m.ChainID, m.Nonce, m.IssuedAt.UTC().Format(time.RFC3339))
+	if !m.ExpiresAt.IsZero() {
+		fmt.Fprintf(&b, "\nExpiration Time: %s", m.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+	return b.String()
+}
+
+// NewNonce returns a random alphanumeric SIWE nonce
+func NewNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// VerifyPersonalSign checks an EIP-191 personal_sign signature of message against address.
+// Signatures with a recovery id of 27/28, as wallets produce them, and 0/1 are accepted.
+func VerifyPersonalSign(message string, signature []byte, address common.Address) error {
+	if len(signature) != crypto.SignatureLength {
+		return fmt.Errorf("invalid signature length %d", len(signature))
+	}
+	sig := make([]byte, len(signature))
+	copy(sig, signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	pub, err := crypto.SigToPub(accounts.TextHash([]byte(message)), sig)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	if signer := crypto.PubkeyToAddress(*pub); signer != address {
+		return fmt.Errorf("%w: signed by %s", ErrSignatureMismatch, signer.Hex())
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestVerifyPersonalSign(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey)
+
+	issued := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	message := SIWEMessage{
+		Domain:    "pay.example.com",
+		Address:   address,
+		Statement: "Bind this wallet to your account.",
+		URI:       "https://pay.example.com",
+		ChainID:   11155111,
+		Nonce:     "a1b2c3d4e5f60718",
+		IssuedAt:  issued,
+		ExpiresAt: issued.Add(10 * time.Minute),
+	}.String()
+
+	if !strings.HasPrefix(message, "pay.example.com wants you to sign in with your Ethereum account:\n"+address.Hex()+"\n\n") ||
+		!strings.Contains(message, "\nNonce: a1b2c3d4e5f60718\n") ||
+		!strings.HasSuffix(message, "\nExpiration Time: 2025-06-01T12:10:00Z") {
+		t.Fatalf("unexpected EIP-4361 message:\n%s", message)
+	}
+
+	// Wallets return personal_sign signatures with v = 27 or 28
+	sig, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+
+	if err := VerifyPersonalSign(message, sig, address); err != nil {
+		t.Errorf("valid signature rejected: %v", err)
+	}
+
+	other, _ := crypto.GenerateKey()
+	if err := VerifyPersonalSign(message, sig, crypto.PubkeyToAddress(other.PublicKey)); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("signature for another address: err = %v, want ErrSignatureMismatch", err)
+	}
+	if err := VerifyPersonalSign(message+" ", sig, address); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("signature over another message: err = %v, want ErrSignatureMismatch", err)
+	}
+	if err := VerifyPersonalSign(message, sig[:64], address); err == nil {
+		t.Error("short signature accepted")
+	}
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestActingUserToken(t *testing.T) {
+	secret := []byte("shared-with-main-app")
+	verifier := NewUserVerifier(secret)
+
+	token, err := SignActingUser(secret, ActingUser{ID: 42, Role: RoleAdmin}, time.Minute)
+	if err != nil {
+		t.Fatalf("SignActingUser: %v", err)
+	}
+	user, err := verifier.Verify(token)
+	if err != nil || user.ID != 42 || !user.Admin() {
+		t.Fatalf("Verify = %+v, %v; want admin user 42", user, err)
+	}
+
+	expired, _ := SignActingUser(secret, ActingUser{ID: 42}, -time.Minute)
+	if _, err := verifier.Verify(expired); !errors.Is(err, ErrInvalidActingUser) {
+		t.Errorf("expired token: err = %v, want ErrInvalidActingUser", err)
+	}
+
+	forged, _ := SignActingUser([]byte("other-secret"), ActingUser{ID: 42}, time.Minute)
+	if _, err := verifier.Verify(forged); !errors.Is(err, ErrInvalidActingUser) {
+		t.Errorf("token with the wrong secret: err = %v, want ErrInvalidActingUser", err)
+	}
+
+	// Tokens must expire and name a numeric user
+	noExpiry, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: "42"}).SignedString(secret)
+	if _, err := verifier.Verify(noExpiry); !errors.Is(err, ErrInvalidActingUser) {
+		t.Errorf("token without exp: err = %v, want ErrInvalidActingUser", err)
+	}
+	badSubject, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   "alice",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}).SignedString(secret)
+	if _, err := verifier.Verify(badSubject); !errors.Is(err, ErrInvalidActingUser) {
+		t.Errorf("non-numeric subject: err = %v, want ErrInvalidActingUser", err)
+	}
+}
+
+func TestActingUserMiddleware(t *testing.T) {
+	secret := []byte("shared-with-main-app")
+	var seen *ActingUser
+	handler := NewUserVerifier(secret).Middleware(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = ActingUserFromContext(r.Context())
+	})
+
+	token, _ := SignActingUser(secret, ActingUser{ID: 7}, time.Minute)
+	r := httptest.NewRequest(http.MethodPost, "/complete-job?job_id=1", nil)
+	r.Header.Set(HeaderActingUser, token)
+	rec := httptest.NewRecorder()
+	handler(rec, r)
+	if rec.Code != http.StatusOK || seen == nil || seen.ID != 7 {
+		t.Errorf("valid token: status %d, user %+v", rec.Code, seen)
+	}
+
+	seen = nil
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/job-status?job_id=1", nil))
+	if seen != nil {
+		t.Errorf("no header: got user %+v, want none", seen)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/complete-job?job_id=1", nil)
+	r.Header.Set(HeaderActingUser, "not-a-jwt")
+	rec = httptest.NewRecorder()
+	handler(rec, r)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("bad token: status %d, want 401", rec.Code)
+	}
+}
@@ -0,0 +1,176 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"github.com/fahedafzaal/go-integration/internal/config"
+)
+
+// ErrUnknownChain is returned for a chain the gateway is not configured to serve
+var ErrUnknownChain = errors.New("chain is not served by this gateway")
+
+// chainDialTimeout bounds connecting to one RPC endpoint and reading its chain id
+const chainDialTimeout = 30 * time.Second
+
+// Chains holds a client for every chain the gateway serves. Chain id 0 stands for the
+// default chain, the first one.
+type Chains struct {
+	clients  []*Client
+	services map[int64]*PaymentGatewayService
+}
+
+// NewChains groups clients for different chains, the default chain's first
+func NewChains(clients ...*Client) (*Chains, error) {
+	if len(clients) == 0 {
+		return nil, errors.New("at least one chain is required")
+	}
+
+	chains := &Chains{services: make(map[int64]*PaymentGatewayService, len(clients))}
+	for _, client := range clients {
+		if _, dup := chains.services[client.ChainID()]; dup {
+			return nil, fmt.Errorf("chain %d is configured twice", client.ChainID())
+		}
+		chains.clients = append(chains.clients, client)
+		chains.services[client.ChainID()] = NewPaymentGatewayServiceWithClient(client)
+	}
+	return chains, nil
+}
+
+// DialChains connects a client to every chain in cfg.Chains, all signing with signer
+func DialChains(ctx context.Context, cfg *config.Config, signer Signer) (*Chains, error) {
+	if err := cfg.CheckChains(); err != nil {
+		return nil, err
+	}
+
+	var clients []*Client
+	closeAll := func() {
+		for _, client := range clients {
+			client.Close()
+		}
+	}
+
+	for _, chain := range cfg.Chains {
+		if chain.ContractAddress == "" {
+			closeAll()
+			return nil, fmt.Errorf("chain %d (%s) has no contract address", chain.ChainID, chain.Name)
+		}
+
+		ethClient, err := DialChain(ctx, chain.RPCURLs, chain.ChainID)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("chain %d (%s): %w", chain.ChainID, chain.Name, err)
+		}
+
+		client, err := NewClientWithBackend(cfg.ForChain(chain), ethClient, signer)
+		if err != nil {
+			ethClient.Close()
+			closeAll()
+			return nil, fmt.Errorf("chain %d (%s): %w", chain.ChainID, chain.Name, err)
+		}
+		clients = append(clients, client)
+	}
+
+	chains, err := NewChains(clients...)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	return chains, nil
+}
+
+// DialChain connects to the first of urls whose node is on chainID. Endpoints that fail or
+// serve another chain are skipped, so a misconfigured URL can never send transactions to
+// the wrong network.
+func DialChain(ctx context.Context, urls []string, chainID int64) (*ethclient.Client, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("no RPC URL configured")
+	}
+
+	var errs []error
+	for _, url := range urls {
+		client, err := dialChain(ctx, url, chainID)
+		if err == nil {
+			return client, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+func dialChain(ctx context.Context, url string, chainID int64) (*ethclient.Client, error) {
+	ctx, cancel := context.WithTimeout(ctx, chainDialTimeout)
+	defer cancel()
+
+	client, err := ethclient.DialContext(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", url, err)
+	}
+	got, err := client.ChainID(ctx)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to read chain id from %s: %w", url, err)
+	}
+	if got.Cmp(new(big.Int).SetInt64(chainID)) != 0 {
+		client.Close()
+		return nil, fmt.Errorf("%s serves chain %s, want %d", url, got, chainID)
+	}
+	return client, nil
+}
+
+// Default returns the default chain's client
+func (c *Chains) Default() *Client {
+	return c.clients[0]
+}
+
+// All returns every chain's client, the default chain's first
+func (c *Chains) All() []*Client {
+	return c.clients
+}
+
+// Client returns the client for chainID, or the default chain's for 0
+func (c *Chains) Client(chainID int64) (*Client, error) {
+	service, err := c.Service(chainID)
+	if err != nil {
+		return nil, err
+	}
+	return service.client, nil
+}
+
+// Service returns a direct-mode service on chainID's client, or the default chain's for 0
+func (c *Chains) Service(chainID int64) (*PaymentGatewayService, error) {
+	if chainID == 0 {
+		chainID = c.Default().ChainID()
+	}
+	service, ok := c.services[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownChain, chainID)
+	}
+	return service, nil
+}
+
+// SetNonceStore gives every chain's client the nonce store store returns for its chain
+func (c *Chains) SetNonceStore(store func(chainID int64) NonceStore) {
+	for _, client := range c.clients {
+		client.SetNonceStore(store(client.ChainID()))
+	}
+}
+
+// SetReplacementRecorder records replacements sent on any chain with recorder
+func (c *Chains) SetReplacementRecorder(recorder ReplacementRecorder) {
+	for _, client := range c.clients {
+		client.SetReplacementRecorder(recorder)
+	}
+}
+
+// Close closes every chain's connection
+func (c *Chains) Close() {
+	for _, client := range c.clients {
+		client.Close()
+	}
+}
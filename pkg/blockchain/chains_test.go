@@ -0,0 +1,61 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fahedafzaal/go-integration/internal/config"
+	"github.com/fahedafzaal/go-integration/internal/escrowsim"
+)
+
+func TestChains(t *testing.T) {
+	backend := escrowsim.NewBackend()
+	t.Cleanup(func() { backend.Close() })
+	eth, ok := backend.Client().(EthBackend)
+	if !ok {
+		t.Fatal("simulated client does not implement EthBackend")
+	}
+
+	newClient := func(chainID int64) *Client {
+		t.Helper()
+		client, err := NewClientWithBackend(&config.Config{
+			NetworkID:         chainID,
+			ContractAddress:   "0x1111111111111111111111111111111111111111",
+			GasLimit:          300000,
+			ConfirmationDepth: 1,
+		}, eth, NewFakeSigner())
+		if err != nil {
+			t.Fatalf("NewClientWithBackend: %v", err)
+		}
+		return client
+	}
+	sepolia, base := newClient(11155111), newClient(84532)
+
+	if _, err := NewChains(); err == nil {
+		t.Error("NewChains() succeeded, want an error")
+	}
+	if _, err := NewChains(sepolia, newClient(11155111)); err == nil {
+		t.Error("NewChains with a chain twice succeeded, want an error")
+	}
+
+	chains, err := NewChains(sepolia, base)
+	if err != nil {
+		t.Fatalf("NewChains: %v", err)
+	}
+	if chains.Default() != sepolia {
+		t.Error("Default is not the first chain")
+	}
+	for chainID, want := range map[int64]*Client{0: sepolia, 11155111: sepolia, 84532: base} {
+		got, err := chains.Client(chainID)
+		if err != nil || got != want {
+			t.Errorf("Client(%d) = chain %v, %v", chainID, got, err)
+		}
+		service, err := chains.Service(chainID)
+		if err != nil || service.client != want {
+			t.Errorf("Service(%d) is not on the chain's client: %v", chainID, err)
+		}
+	}
+	if _, err := chains.Client(1); !errors.Is(err, ErrUnknownChain) {
+		t.Errorf("Client(1) error = %v, want ErrUnknownChain", err)
+	}
+}
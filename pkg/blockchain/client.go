@@ -2,33 +2,46 @@ package blockchain
 
 import (
 	"context"
-	"crypto/ecdsa"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
 	"math/big"
-	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 
 	"github.com/fahedafzaal/go-integration/contracts"
 	"github.com/fahedafzaal/go-integration/internal/config"
+	"github.com/fahedafzaal/go-integration/pkg/money"
 )
 
+// EthBackend is the node API the client uses. It is satisfied by *ethclient.Client and by
+// go-ethereum's simulated backend client.
+type EthBackend interface {
+	bind.ContractBackend
+	ethereum.BlockNumberReader
+	ethereum.ChainIDReader
+	ethereum.TransactionReader
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	TransactionSender(ctx context.Context, tx *types.Transaction, block common.Hash, index uint) (common.Address, error)
+	Close()
+}
+
 type Client struct {
-	ethClient       *ethclient.Client
+	ethClient       EthBackend
 	contract        *contracts.EthJobEscrow
 	contractAddress common.Address
-	privateKey      *ecdsa.PrivateKey
+	signer          Signer
 	publicAddress   common.Address
 	config          *config.Config
+	nonces          *NonceManager
+	replacements    ReplacementRecorder
+	prices          *MedianOracle
 }
 
 type JobDetails struct {
@@ -41,36 +54,41 @@ type JobDetails struct {
 }
 
 type TransactionResult struct {
-	TxHash      string
-	BlockNumber uint64
-	GasUsed     uint64
-	Success     bool
-	Error       error
+	TxHash        string
+	BlockNumber   uint64
+	BlockHash     string
+	GasUsed       uint64
+	Confirmations uint64
+	Success       bool
+	Error         error
 }
 
-// NewClient creates a new blockchain client instance
+// ErrTransactionReorged is returned when a mined transaction's block is no longer canonical
+var ErrTransactionReorged = errors.New("transaction block was reorganized out of the canonical chain")
+
+// NewClient creates a new blockchain client instance signing with the signer selected by
+// cfg.SignerType
 func NewClient(cfg *config.Config) (*Client, error) {
-	// Connect to Ethereum client
-	ethClient, err := ethclient.Dial(cfg.EthereumRPCURL)
+	signer, err := NewSignerFromConfig(context.Background(), cfg)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to create signer: %w", err)
 	}
+	return NewClientWithSigner(cfg, signer)
+}
 
-	// Parse private key (handle "0x" prefix)
-	pk := strings.TrimPrefix(cfg.PrivateKey, "0x")
-	privateKey, err := crypto.HexToECDSA(pk)
+// NewClientWithSigner creates a new blockchain client instance that signs with signer
+func NewClientWithSigner(cfg *config.Config, signer Signer) (*Client, error) {
+	// Connect to Ethereum client
+	ethClient, err := ethclient.Dial(cfg.EthereumRPCURL)
 	if err != nil {
 		return nil, err
 	}
+	return NewClientWithBackend(cfg, ethClient, signer)
+}
 
-	// Get public address from private key
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return nil, errors.New("cannot assert type: publicKey is not of type *ecdsa.PublicKey")
-	}
-	publicAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
-
+// NewClientWithBackend creates a blockchain client on an existing node connection, such as
+// a simulated backend in tests
+func NewClientWithBackend(cfg *config.Config, ethClient EthBackend, signer Signer) (*Client, error) {
 	// Connect to smart contract
 	contractAddress := common.HexToAddress(cfg.ContractAddress)
 	contract, err := contracts.NewEthJobEscrow(contractAddress, ethClient)
@@ -78,36 +96,82 @@ func NewClient(cfg *config.Config) (*Client, error) {
 		return nil, err
 	}
 
+	// The contract converts at the Chainlink feed's answer, so with the feed configured the
+	// guarded feed read stands in for the contract's: one aggregator is one quorum vote
+	var sources []PriceOracle
+	if cfg.ETHUSDPriceFeed == "" {
+		sources = append(sources, NewContractOracle(contract))
+	} else {
+		feed, err := NewPriceFeed(common.HexToAddress(cfg.ETHUSDPriceFeed), ethClient, PriceFeedConfig{
+			Heartbeat:       time.Duration(cfg.PriceFeedHeartbeat) * time.Second,
+			MaxDeviationBps: cfg.PriceMaxDeviationBps,
+		})
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, feed)
+	}
+	if cfg.PriceHTTPURL != "" {
+		source, err := NewHTTPPriceOracle(cfg.PriceHTTPURL, cfg.PriceHTTPField)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+
 	return &Client{
 		ethClient:       ethClient,
 		contract:        contract,
 		contractAddress: contractAddress,
-		privateKey:      privateKey,
-		publicAddress:   publicAddress,
+		signer:          signer,
+		publicAddress:   signer.Address(),
 		config:          cfg,
+		nonces:          NewNonceManager(ethClient, NewMemoryNonceStore()),
+		prices:          NewMedianOracle(cfg.PriceQuorum, sources...),
 	}, nil
 }
 
-// GetAuth creates a new transactor for sending transactions with enhanced configuration
+// SetNonceStore persists nonce reservations in store instead of process memory
+func (c *Client) SetNonceStore(store NonceStore) {
+	c.nonces = NewNonceManager(c.ethClient, store)
+}
+
+// GetAuth creates a new transactor for sending transactions with enhanced configuration.
+// The returned nonce is reserved; callers must mark it sent or release it.
 func (c *Client) GetAuth(ctx context.Context) (*bind.TransactOpts, error) {
-	nonce, err := c.ethClient.PendingNonceAt(ctx, c.publicAddress)
+	nonce, err := c.nonces.Next(ctx, c.publicAddress)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get nonce: %w", err)
 	}
 
-	chainID, err := c.ethClient.NetworkID(ctx)
+	auth, err := c.newTransactor(ctx, nonce)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get chain ID: %w", err)
+		c.nonces.Release(ctx, c.publicAddress, nonce)
+		return nil, err
 	}
 
-	// Create transactor with proper EIP-1559 support
-	auth, err := bind.NewKeyedTransactorWithChainID(c.privateKey, chainID)
+	return auth, nil
+}
+
+// newTransactor builds transaction options for the given nonce with current fee pricing
+func (c *Client) newTransactor(ctx context.Context, nonce uint64) (*bind.TransactOpts, error) {
+	chainID, err := c.ethClient.ChainID(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create transactor: %w", err)
+		return nil, fmt.Errorf("failed to get chain ID: %w", err)
 	}
 
-	// Set context for proper cancellation handling
-	auth.Context = ctx
+	// Create transactor with proper EIP-1559 support; signing goes through the configured signer
+	auth := &bind.TransactOpts{
+		From: c.publicAddress,
+		Signer: func(address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != c.publicAddress {
+				return nil, bind.ErrNotAuthorized
+			}
+			return c.signer.SignTx(ctx, tx, chainID)
+		},
+		// Set context for proper cancellation handling
+		Context: ctx,
+	}
 	auth.Nonce = big.NewInt(int64(nonce))
 	auth.Value = big.NewInt(0)
 
@@ -120,18 +184,11 @@ func (c *Client) GetAuth(ctx context.Context) (*bind.TransactOpts, error) {
 	// Use EIP-1559 pricing if supported, otherwise fall back to legacy
 	if block.BaseFee != nil {
 		// EIP-1559 transaction with dynamic fees
-		tipCap, err := c.ethClient.SuggestGasTipCap(ctx)
+		tipCap, maxFeePerGas, err := c.dynamicFees(ctx, block.BaseFee)
 		if err != nil {
-			return nil, fmt.Errorf("failed to suggest gas tip cap: %w", err)
+			return nil, err
 		}
 
-		// Calculate max fee per gas (base fee + tip)
-		// Use 2x base fee + tip as max fee to handle base fee fluctuations
-		maxFeePerGas := new(big.Int).Add(
-			new(big.Int).Mul(block.BaseFee, big.NewInt(2)),
-			tipCap,
-		)
-
 		auth.GasTipCap = tipCap
 		auth.GasFeeCap = maxFeePerGas
 
@@ -166,6 +223,22 @@ func (c *Client) GetAuth(ctx context.Context) (*bind.TransactOpts, error) {
 	return auth, nil
 }
 
+// dynamicFees suggests EIP-1559 fee caps on top of baseFee
+func (c *Client) dynamicFees(ctx context.Context, baseFee *big.Int) (tipCap, feeCap *big.Int, err error) {
+	tipCap, err = c.ethClient.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to suggest gas tip cap: %w", err)
+	}
+
+	// Calculate max fee per gas (base fee + tip)
+	// Use 2x base fee + tip as max fee to handle base fee fluctuations
+	feeCap = new(big.Int).Add(
+		new(big.Int).Mul(baseFee, big.NewInt(2)),
+		tipCap,
+	)
+	return tipCap, feeCap, nil
+}
+
 // calculateTotalGasCost estimates the total gas cost for a transaction
 func (c *Client) calculateTotalGasCost(ctx context.Context, gasLimit uint64) (*big.Int, error) {
 	// Check if network supports EIP-1559
@@ -206,23 +279,14 @@ func (c *Client) calculateTotalGasCost(ctx context.Context, gasLimit uint64) (*b
 	return totalGasCost, nil
 }
 
-// toUsdE8 converts USD float to 8-decimal "micro-dollars" format expected by the contract
-func toUsdE8(usdFloat float64) (*big.Int, error) {
-	val := new(big.Float).Mul(big.NewFloat(usdFloat), big.NewFloat(1e8))
-	intVal, _ := val.Int(nil) // Uses banker's rounding (round to even)
-	return intVal, nil
-}
+// PreparePostJob signs, but does not send, a PostJob transaction funded from the gateway
+// wallet with slippage protection. Its nonce stays reserved until BroadcastTransaction or
+// DiscardTransaction.
+func (c *Client) PreparePostJob(ctx context.Context, jobID uint64, freelancer common.Address, usdAmount money.USD, client common.Address) (*types.Transaction, error) {
+	log.Printf("DEBUG PostJob: Preparing PostJob for JobID=%d, USDAmount=%s", jobID, usdAmount)
 
-// PostJob creates a new job on the blockchain with enhanced error handling and slippage protection
-func (c *Client) PostJob(ctx context.Context, jobID uint64, freelancer common.Address, usdAmountFloat float64, client common.Address) (*TransactionResult, error) {
-	log.Printf("DEBUG PostJob: Starting PostJob for JobID=%d, USDAmount=%.2f", jobID, usdAmountFloat)
-
-	// Convert USD to 8-decimal format expected by contract
-	usdE8, err := toUsdE8(usdAmountFloat)
-	if err != nil {
-		log.Printf("ERROR PostJob: Failed to convert USD to E8 format: %v", err)
-		return nil, fmt.Errorf("failed to convert USD to E8 format: %w", err)
-	}
+	// 8-decimal format expected by contract
+	usdE8 := usdAmount.E8()
 
 	// Get current ETH price and calculate required ETH
 	ethAmount, err := c.contract.ConvertUsdToEth(&bind.CallOpts{Context: ctx}, usdE8)
@@ -265,6 +329,7 @@ func (c *Client) PostJob(ctx context.Context, jobID uint64, freelancer common.Ad
 		// Check if we have sufficient balance for ETH amount + gas costs
 		totalRequired := new(big.Int).Add(ethAmountWithSlippage, totalGasCost)
 		if balance.Cmp(totalRequired) < 0 {
+			c.nonces.Release(ctx, c.publicAddress, auth.Nonce.Uint64())
 			return nil, fmt.Errorf("insufficient balance: need %s wei (ETH: %s + Gas: %s) but only have %s wei",
 				totalRequired.String(), ethAmountWithSlippage.String(), totalGasCost.String(), balance.String())
 		}
@@ -273,11 +338,29 @@ func (c *Client) PostJob(ctx context.Context, jobID uint64, freelancer common.Ad
 	// Set the value to send (ETH amount with slippage buffer)
 	auth.Value = ethAmountWithSlippage
 
-	log.Printf("DEBUG PostJob: Executing transaction with value: %s wei", auth.Value.String())
+	log.Printf("DEBUG PostJob: Signing transaction with value: %s wei", auth.Value.String())
 
-	// Execute transaction with usdE8
+	// Sign transaction with usdE8
+	auth.NoSend = true
 	tx, err := c.contract.PostJob(auth, big.NewInt(int64(jobID)), freelancer, usdE8, client)
 	if err != nil {
+		c.nonces.Release(ctx, c.publicAddress, auth.Nonce.Uint64())
+		log.Printf("ERROR PostJob: Failed to build transaction: %v", err)
+		return nil, fmt.Errorf("failed to build transaction: %w", err)
+	}
+
+	return tx, nil
+}
+
+// PostJob creates a new job on the blockchain and waits for it to be confirmed
+func (c *Client) PostJob(ctx context.Context, jobID uint64, freelancer common.Address, usdAmount money.USD, client common.Address) (*TransactionResult, error) {
+	tx, err := c.PreparePostJob(ctx, jobID, freelancer, usdAmount, client)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.BroadcastTransaction(ctx, tx); err != nil {
+		c.DiscardTransaction(ctx, tx)
 		log.Printf("ERROR PostJob: Transaction failed: %v", err)
 		return &TransactionResult{
 			Success: false,
@@ -288,7 +371,7 @@ func (c *Client) PostJob(ctx context.Context, jobID uint64, freelancer common.Ad
 	log.Printf("DEBUG PostJob: Transaction submitted, hash: %s", tx.Hash().Hex())
 
 	// Wait for transaction confirmation with enhanced retry logic
-	result, err := c.waitForTransactionWithRetry(ctx, tx, 3)
+	result, err := c.waitForTransactionWithRetry(ctx, jobID, tx)
 	if err != nil {
 		log.Printf("ERROR PostJob: Transaction confirmation failed: %v", err)
 	} else {
@@ -300,38 +383,35 @@ func (c *Client) PostJob(ctx context.Context, jobID uint64, freelancer common.Ad
 
 // MarkJobCompleted marks a job as completed and releases payment
 func (c *Client) MarkJobCompleted(ctx context.Context, jobID uint64) (*TransactionResult, error) {
-	auth, err := c.GetAuth(ctx)
+	tx, err := c.PrepareMarkJobCompleted(ctx, jobID)
 	if err != nil {
 		return nil, err
 	}
 
-	tx, err := c.contract.MarkJobCompleted(auth, big.NewInt(int64(jobID)))
-	if err != nil {
-		return &TransactionResult{
-			Success: false,
-			Error:   err,
-		}, err
-	}
-
-	return c.waitForTransactionWithRetry(ctx, tx, 3)
+	return c.sendAndWait(ctx, jobID, tx)
 }
 
 // CancelJob cancels a job and refunds the client
 func (c *Client) CancelJob(ctx context.Context, jobID uint64) (*TransactionResult, error) {
-	auth, err := c.GetAuth(ctx)
+	tx, err := c.PrepareCancelJob(ctx, jobID)
 	if err != nil {
 		return nil, err
 	}
 
-	tx, err := c.contract.CancelJob(auth, big.NewInt(int64(jobID)))
-	if err != nil {
+	return c.sendAndWait(ctx, jobID, tx)
+}
+
+// sendAndWait broadcasts a prepared transaction and waits for it to be confirmed
+func (c *Client) sendAndWait(ctx context.Context, jobID uint64, tx *types.Transaction) (*TransactionResult, error) {
+	if err := c.BroadcastTransaction(ctx, tx); err != nil {
+		c.DiscardTransaction(ctx, tx)
 		return &TransactionResult{
 			Success: false,
 			Error:   err,
 		}, err
 	}
 
-	return c.waitForTransactionWithRetry(ctx, tx, 3)
+	return c.waitForTransactionWithRetry(ctx, jobID, tx)
 }
 
 // GetJobDetails retrieves job information from the blockchain
@@ -354,9 +434,45 @@ func (c *Client) GetJobDetails(ctx context.Context, jobID uint64) (*JobDetails,
 	}, nil
 }
 
-// GetETHUSDPrice gets the current ETH/USD price from Chainlink
+// JobDetailsAt reads a job as of the given block. It returns nil when the contract has no
+// such job at that block, whether the lookup reverts or yields an empty job.
+func (c *Client) JobDetailsAt(ctx context.Context, jobID uint64, block uint64) (*JobDetails, error) {
+	result, err := c.contract.GetJobDetails(
+		&bind.CallOpts{Context: ctx, BlockNumber: new(big.Int).SetUint64(block)},
+		big.NewInt(int64(jobID)),
+	)
+	if err != nil {
+		if _, ok := revertData(err); ok {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get job %d details at block %d: %w", jobID, block, err)
+	}
+	if result.Client == (common.Address{}) {
+		return nil, nil
+	}
+
+	return &JobDetails{
+		Client:      result.Client,
+		Freelancer:  result.Freelancer,
+		USDAmount:   result.UsdAmount,
+		ETHAmount:   result.EthAmount,
+		IsCompleted: result.IsCompleted,
+		IsPaid:      result.IsPaid,
+	}, nil
+}
+
+// GetETHUSDPrice reads the current ETH/USD price, 8 decimals, as the median of the
+// configured price sources. See PriceQuote.
 func (c *Client) GetETHUSDPrice(ctx context.Context) (*big.Int, error) {
-	return c.contract.GetLatestEthUsd(&bind.CallOpts{Context: ctx})
+	return c.prices.LatestPrice(ctx)
+}
+
+// PriceQuote reads the ETH/USD price from the escrow contract, the Chainlink feed and the
+// HTTP price source, whichever are configured, and reports the median and the sources
+// that answered. Too few answers is a *QuorumError, which also matches each source's
+// error, such as a *PriceFeedError for an answer the feed guards reject.
+func (c *Client) PriceQuote(ctx context.Context) (*PriceQuote, error) {
+	return c.prices.Quote(ctx)
 }
 
 // ConvertUSDToETH converts USD amount to ETH using current price
@@ -406,13 +522,13 @@ func (c *Client) JobExists(ctx context.Context, jobID uint64) (bool, error) {
 	// Try to get job details - if it exists, this will succeed
 	details, err := c.contract.GetJobDetails(&bind.CallOpts{Context: ctx}, big.NewInt(int64(jobID)))
 	if err != nil {
-		// If error contains "job does not exist" or similar, return false
-		// Otherwise, it's a real error
-		errStr := err.Error()
-		if strings.Contains(errStr, "job does not exist") || strings.Contains(errStr, "Job not found") || strings.Contains(errStr, "execution reverted") {
+		// A revert means the contract refused the lookup, so there is no job to read.
+		// Anything else is an RPC failure and must not be mistaken for a missing job.
+		if data, ok := revertData(err); ok {
+			log.Printf("DEBUG JobExists: Job %d lookup reverted (%v) - treating as non-existing", jobID, DecodeRevert(data))
 			return false, nil
 		}
-		return false, err
+		return false, fmt.Errorf("failed to get job details: %w", err)
 	}
 
 	// NEW: Check if job is corrupted (ghost job with ALL fields being zero/null)
@@ -431,60 +547,74 @@ func (c *Client) JobExists(ctx context.Context, jobID uint64) (bool, error) {
 	return true, nil
 }
 
-// waitForTransactionWithRetry waits for transaction confirmation with exponential backoff
-func (c *Client) waitForTransactionWithRetry(ctx context.Context, tx *types.Transaction, maxRetries int) (*TransactionResult, error) {
+// waitForTransactionWithRetry waits for transaction confirmation. A transaction still unmined
+// after the replace-after interval is re-sent under the same nonce with bumped fees until one
+// of the versions is mined, the fee ceiling is reached, or the context ends.
+func (c *Client) waitForTransactionWithRetry(ctx context.Context, jobID uint64, tx *types.Transaction) (*TransactionResult, error) {
 	log.Printf("Transaction sent: %s", tx.Hash().Hex())
 
-	var lastErr error
-	baseDelay := time.Second
+	candidates := []*types.Transaction{tx}
+	latest := tx
+	canReplace := true
+
+	failed := func(err error) (*TransactionResult, error) {
+		return &TransactionResult{
+			TxHash:  latest.Hash().Hex(),
+			Success: false,
+			Error:   err,
+		}, err
+	}
 
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		log.Printf("Attempt %d/%d: Waiting for transaction confirmation", attempt, maxRetries)
+	for round := 1; ; round++ {
+		log.Printf("Round %d: Waiting for transaction confirmation (%d candidate(s))", round, len(candidates))
 
-		// Create a context with timeout for this attempt
-		attemptCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+		// Create a context with timeout for this round
+		roundCtx, cancel := context.WithTimeout(ctx, c.ReplaceAfter())
 
-		// Wait for transaction to be mined
-		receipt, err := bind.WaitMined(attemptCtx, c.ethClient, tx)
+		// Wait for any version of the transaction to be mined
+		receipt, mined, err := c.waitMinedAny(roundCtx, candidates)
 		cancel() // Call immediately to prevent context leaks
 
 		if err != nil {
-			lastErr = err
-			if attempt < maxRetries {
-				// Exponential backoff: wait longer between retries
-				delay := time.Duration(1<<uint(attempt-1)) * baseDelay
-				log.Printf("Attempt %d failed: %v. Retrying in %v...", attempt, err, delay)
-
-				select {
-				case <-time.After(delay):
-					continue
-				case <-ctx.Done():
-					return &TransactionResult{
-						TxHash:  tx.Hash().Hex(),
-						Success: false,
-						Error:   ctx.Err(),
-					}, ctx.Err()
+			if ctx.Err() != nil {
+				log.Printf("Stopped waiting for transaction %s: %v", latest.Hash().Hex(), ctx.Err())
+				return failed(ctx.Err())
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				log.Printf("Transaction %s failed: %v", latest.Hash().Hex(), err)
+				return failed(err)
+			}
+
+			// Still pending - speed it up so a base fee spike cannot strand it
+			if canReplace {
+				replacement, err := c.SpeedUpTransaction(ctx, jobID, tx.Hash(), latest)
+				switch {
+				case errors.Is(err, ErrFeeCeilingReached):
+					log.Printf("Transaction %s is at the fee ceiling, waiting without further replacement", latest.Hash().Hex())
+					canReplace = false
+				case err != nil:
+					log.Printf("Failed to replace transaction %s: %v", latest.Hash().Hex(), err)
+				default:
+					candidates = append(candidates, replacement)
+					latest = replacement
 				}
 			}
+			continue
+		}
 
-			log.Printf("All %d attempts failed, last error: %v", maxRetries, err)
-			return &TransactionResult{
-				TxHash:  tx.Hash().Hex(),
-				Success: false,
-				Error:   err,
-			}, err
+		if mined.Hash() != tx.Hash() {
+			log.Printf("Replacement %s was mined for original transaction %s", mined.Hash().Hex(), tx.Hash().Hex())
 		}
 
 		// Check if transaction succeeded
 		if receipt.Status == types.ReceiptStatusFailed {
-			// Get detailed revert reason
-			revertReason := c.getRevertReason(ctx, tx.Hash())
-			revertErr := fmt.Errorf("transaction reverted: %s", revertReason)
+			// Get detailed revert reason; contract errors match their sentinels with errors.Is
+			revertErr := fmt.Errorf("transaction reverted: %w", c.getRevertError(ctx, mined.Hash()))
 
-			log.Printf("Transaction failed with revert reason: %s", revertReason)
+			log.Printf("Transaction failed with revert reason: %v", revertErr)
 
 			return &TransactionResult{
-				TxHash:      tx.Hash().Hex(),
+				TxHash:      mined.Hash().Hex(),
 				BlockNumber: receipt.BlockNumber.Uint64(),
 				GasUsed:     receipt.GasUsed,
 				Success:     false,
@@ -492,69 +622,150 @@ func (c *Client) waitForTransactionWithRetry(ctx context.Context, tx *types.Tran
 			}, revertErr
 		}
 
+		// Transaction mined - wait until it is buried deep enough to survive a shallow reorg
+		confirmations, err := c.waitForConfirmations(ctx, receipt)
+		if errors.Is(err, ErrTransactionReorged) {
+			log.Printf("Transaction %s was reorged out of block %d, waiting for it to be mined again",
+				mined.Hash().Hex(), receipt.BlockNumber.Uint64())
+			continue
+		}
+		if err != nil {
+			return &TransactionResult{
+				TxHash:      mined.Hash().Hex(),
+				BlockNumber: receipt.BlockNumber.Uint64(),
+				BlockHash:   receipt.BlockHash.Hex(),
+				GasUsed:     receipt.GasUsed,
+				Success:     false,
+				Error:       err,
+			}, err
+		}
+
 		// Transaction succeeded
-		log.Printf("Transaction confirmed successfully in block %d, gas used: %d",
-			receipt.BlockNumber.Uint64(), receipt.GasUsed)
+		log.Printf("Transaction confirmed successfully in block %d with %d confirmations, gas used: %d",
+			receipt.BlockNumber.Uint64(), confirmations, receipt.GasUsed)
 
 		return &TransactionResult{
-			TxHash:      tx.Hash().Hex(),
-			BlockNumber: receipt.BlockNumber.Uint64(),
-			GasUsed:     receipt.GasUsed,
-			Success:     true,
-			Error:       nil,
+			TxHash:        mined.Hash().Hex(),
+			BlockNumber:   receipt.BlockNumber.Uint64(),
+			BlockHash:     receipt.BlockHash.Hex(),
+			GasUsed:       receipt.GasUsed,
+			Confirmations: confirmations,
+			Success:       true,
+			Error:         nil,
 		}, nil
 	}
+}
 
-	// This should never be reached, but included for completeness
-	return &TransactionResult{
-		TxHash:  tx.Hash().Hex(),
-		Success: false,
-		Error:   lastErr,
-	}, lastErr
+// ConfirmationDepth returns the number of blocks required before a transaction is final
+func (c *Client) ConfirmationDepth() uint64 {
+	if c.config.ConfirmationDepth == 0 {
+		return 1
+	}
+	return c.config.ConfirmationDepth
+}
+
+// BlockHashAt returns the hash of the canonical block at the given height
+func (c *Client) BlockHashAt(ctx context.Context, number uint64) (common.Hash, error) {
+	header, err := c.ethClient.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to get header for block %d: %w", number, err)
+	}
+	return header.Hash(), nil
+}
+
+// Confirmations returns how many blocks deep a receipt is, or ErrTransactionReorged
+// if its block is no longer part of the canonical chain
+func (c *Client) Confirmations(ctx context.Context, receipt *types.Receipt) (uint64, error) {
+	canonical, err := c.BlockHashAt(ctx, receipt.BlockNumber.Uint64())
+	if err != nil {
+		return 0, err
+	}
+	if canonical != receipt.BlockHash {
+		return 0, ErrTransactionReorged
+	}
+
+	head, err := c.ethClient.BlockNumber(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest block: %w", err)
+	}
+	if head < receipt.BlockNumber.Uint64() {
+		return 0, nil
+	}
+
+	return head - receipt.BlockNumber.Uint64() + 1, nil
 }
 
-// getRevertReason attempts to get the detailed revert reason for a failed transaction
+// waitForConfirmations blocks until the receipt has reached the configured confirmation
+// depth, checking on every poll that its block is still canonical
+func (c *Client) waitForConfirmations(ctx context.Context, receipt *types.Receipt) (uint64, error) {
+	depth := c.ConfirmationDepth()
+
+	ticker := time.NewTicker(4 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		confirmations, err := c.Confirmations(ctx, receipt)
+		if err != nil {
+			return 0, err
+		}
+		if confirmations >= depth {
+			return confirmations, nil
+		}
+
+		log.Printf("Transaction %s has %d/%d confirmations", receipt.TxHash.Hex(), confirmations, depth)
+
+		select {
+		case <-ctx.Done():
+			return confirmations, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// getRevertReason attempts to get the detailed revert reason for a failed transaction.
+// Contract custom errors are reported by their Solidity name, e.g. JobNotCompleted.
 func (c *Client) getRevertReason(ctx context.Context, txHash common.Hash) string {
+	err := c.getRevertError(ctx, txHash)
+
+	var contractErr *ContractError
+	if errors.As(err, &contractErr) {
+		return contractErr.Name
+	}
+	var revertErr *RevertError
+	if errors.As(err, &revertErr) && revertErr.Reason != "" {
+		return revertErr.Reason
+	}
+	return err.Error()
+}
+
+// getRevertError replays a failed transaction at its block and decodes the revert data
+// into a *ContractError sentinel or a *RevertError
+func (c *Client) getRevertError(ctx context.Context, txHash common.Hash) error {
 	// Try to get the transaction receipt first
 	receipt, err := c.ethClient.TransactionReceipt(ctx, txHash)
 	if err != nil {
 		log.Printf("DEBUG getRevertReason: Failed to get receipt: %v", err)
-		return "execution reverted (unable to fetch receipt)"
+		return errors.New("execution reverted (unable to fetch receipt)")
 	}
 
 	// If the transaction succeeded, there's no revert reason
 	if receipt.Status == types.ReceiptStatusSuccessful {
-		return "transaction succeeded (no revert)"
+		return errors.New("transaction succeeded (no revert)")
 	}
 
 	// Try to get the transaction details
 	tx, _, err := c.ethClient.TransactionByHash(ctx, txHash)
 	if err != nil {
 		log.Printf("DEBUG getRevertReason: Failed to get transaction: %v", err)
-		return "execution reverted (unable to fetch transaction)"
+		return errors.New("execution reverted (unable to fetch transaction)")
 	}
 
 	// Get the sender address from the transaction
 	// We need to derive it since receipt doesn't contain the From field
-	var from common.Address
-	if tx.ChainId() != nil {
-		// For EIP-155 transactions
-		signer := types.NewEIP155Signer(tx.ChainId())
-		sender, err := types.Sender(signer, tx)
-		if err != nil {
-			log.Printf("DEBUG getRevertReason: Failed to get sender: %v", err)
-			return "execution reverted (unable to get sender)"
-		}
-		from = sender
-	} else {
-		// For pre-EIP155 transactions (fallback)
-		signer := types.HomesteadSigner{}
-		sender, err := types.Sender(signer, tx)
-		if err != nil {
-			log.Printf("DEBUG getRevertReason: Failed to get sender with fallback: %v", err)
-			return "execution reverted (unable to get sender)"
-		}
-		from = sender
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		log.Printf("DEBUG getRevertReason: Failed to get sender: %v", err)
+		return errors.New("execution reverted (unable to get sender)")
 	}
 
 	// Try to simulate the transaction to get the revert reason
@@ -569,7 +780,8 @@ func (c *Client) getRevertReason(ctx context.Context, txHash common.Hash) string
 	}
 
 	// Handle EIP-1559 transactions properly (type 2)
-	if tx.Type() == 2 {
+	if tx.Type() == types.DynamicFeeTxType {
+		callMsg.GasPrice = nil
 		callMsg.GasTipCap = tx.GasTipCap()
 		callMsg.GasFeeCap = tx.GasFeeCap()
 	}
@@ -579,59 +791,41 @@ func (c *Client) getRevertReason(ctx context.Context, txHash common.Hash) string
 
 	result, err := c.ethClient.CallContract(ctx, callMsg, blockNumber)
 	if err != nil {
-		// Extract revert reason from error message if possible
-		errStr := err.Error()
-
-		// Common patterns in revert error messages
-		if strings.Contains(errStr, "execution reverted: ") {
-			// Extract the custom revert message
-			parts := strings.Split(errStr, "execution reverted: ")
-			if len(parts) > 1 {
-				return strings.Trim(parts[1], `"`)
-			}
-		}
-
-		if strings.Contains(errStr, "revert ") {
-			// Extract revert reason from error
-			parts := strings.Split(errStr, "revert ")
-			if len(parts) > 1 {
-				return strings.Trim(parts[1], `"`)
-			}
-		}
-
-		// Check for common revert patterns
-		if strings.Contains(errStr, "insufficient funds") {
-			return "InsufficientEthSent"
-		}
-		if strings.Contains(errStr, "Job already exists") {
-			return "JobAlreadyExists"
+		// Nodes return the revert data alongside the error
+		if data, ok := revertData(err); ok {
+			return DecodeRevert(data)
 		}
 
 		log.Printf("DEBUG getRevertReason: Call contract failed: %v", err)
-		return fmt.Sprintf("execution reverted (%v)", err)
+		return fmt.Errorf("execution reverted (%v)", err)
 	}
 
-	// If we get here, the call succeeded, which shouldn't happen for a reverted transaction
-	// Try to decode the result as a revert reason
+	// Some nodes return the revert data as the call result instead
 	if len(result) >= 4 {
-		// Check if it's an Error(string) revert (0x08c379a0)
-		errorSelector := result[:4]
-		if hex.EncodeToString(errorSelector) == "08c379a0" && len(result) >= 68 {
-			// Decode the string parameter
-			// Skip the selector (4 bytes) and offset (32 bytes)
-			if len(result) >= 68 {
-				lengthBytes := result[36:68]
-				length := new(big.Int).SetBytes(lengthBytes).Uint64()
-
-				if len(result) >= int(68+length) {
-					messageBytes := result[68 : 68+length]
-					return string(messageBytes)
-				}
-			}
-		}
+		return DecodeRevert(result)
 	}
 
-	return "execution reverted (unknown reason)"
+	return errors.New("execution reverted (unknown reason)")
+}
+
+// Signer returns the signer the client sends transactions with
+func (c *Client) Signer() Signer {
+	return c.signer
+}
+
+// ContractAddress returns the address of the escrow contract
+func (c *Client) ContractAddress() common.Address {
+	return c.contractAddress
+}
+
+// ChainID returns the id of the chain the client's escrow contract is deployed on
+func (c *Client) ChainID() int64 {
+	return c.config.NetworkID
+}
+
+// ExplorerURL returns the block explorer of the client's chain, or "" if it has none
+func (c *Client) ExplorerURL() string {
+	return c.config.ExplorerURL
 }
 
 // GetContract returns the contract instance
@@ -0,0 +1,159 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/fahedafzaal/go-integration/contracts"
+)
+
+// ContractError is a custom error declared by the EthJobEscrow contract. The declared
+// errors are exported as sentinels, so a decoded revert can be matched with errors.Is.
+type ContractError struct {
+	Name string // Solidity error name, e.g. JobNotCompleted
+	Code string // machine-readable code, e.g. job_not_completed
+}
+
+func (e *ContractError) Error() string {
+	return "contract error: " + e.Name
+}
+
+// Custom errors declared by the EthJobEscrow contract
+var (
+	ErrInsufficientEthSent        = &ContractError{Name: "InsufficientEthSent", Code: "insufficient_eth_sent"}
+	ErrJobAlreadyCompleted        = &ContractError{Name: "JobAlreadyCompleted", Code: "job_already_completed"}
+	ErrJobNotCancelable           = &ContractError{Name: "JobNotCancelable", Code: "job_not_cancelable"}
+	ErrJobNotCompleted            = &ContractError{Name: "JobNotCompleted", Code: "job_not_completed"}
+	ErrNotJobClient               = &ContractError{Name: "NotJobClient", Code: "not_job_client"}
+	ErrOnlyClientCanMarkCompleted = &ContractError{Name: "OnlyClientCanMarkCompleted", Code: "only_client_can_mark_completed"}
+	ErrPaymentAlreadyReleased     = &ContractError{Name: "PaymentAlreadyReleased", Code: "payment_already_released"}
+)
+
+var contractErrors = []*ContractError{
+	ErrInsufficientEthSent,
+	ErrJobAlreadyCompleted,
+	ErrJobNotCancelable,
+	ErrJobNotCompleted,
+	ErrNotJobClient,
+	ErrOnlyClientCanMarkCompleted,
+	ErrPaymentAlreadyReleased,
+}
+
+// RevertError is a revert that is not one of the contract's custom errors, such as a
+// require message or a revert without data
+type RevertError struct {
+	Reason string // decoded Error(string) message, empty when unknown
+	Data   []byte // raw revert data
+}
+
+func (e *RevertError) Error() string {
+	if e.Reason != "" {
+		return "execution reverted: " + e.Reason
+	}
+	if len(e.Data) > 0 {
+		return "execution reverted: " + hexutil.Encode(e.Data)
+	}
+	return "execution reverted"
+}
+
+// contractErrorSelectors maps the 4-byte selector of each declared error to its sentinel,
+// built from the contract ABI so it cannot drift from the deployed bindings
+var contractErrorSelectors = func() map[[4]byte]*ContractError {
+	selectors := make(map[[4]byte]*ContractError)
+
+	parsed, err := contracts.EthJobEscrowMetaData.GetAbi()
+	if err != nil {
+		log.Printf("Warning: Failed to parse escrow ABI, custom errors will not be decoded: %v", err)
+		return selectors
+	}
+
+	for _, sentinel := range contractErrors {
+		abiErr, ok := parsed.Errors[sentinel.Name]
+		if !ok {
+			log.Printf("Warning: Escrow ABI does not declare error %s", sentinel.Name)
+			continue
+		}
+		var selector [4]byte
+		copy(selector[:], abiErr.ID[:4])
+		selectors[selector] = sentinel
+	}
+	return selectors
+}()
+
+// errorStringSelector is the selector of the built-in Error(string) revert
+var errorStringSelector = []byte{0x08, 0xc3, 0x79, 0xa0}
+
+// DecodeRevert decodes revert data into one of the contract's error sentinels, or a
+// *RevertError when the data is not a declared custom error
+func DecodeRevert(data []byte) error {
+	if len(data) >= 4 {
+		var selector [4]byte
+		copy(selector[:], data[:4])
+		if sentinel, ok := contractErrorSelectors[selector]; ok {
+			return sentinel
+		}
+	}
+
+	if bytes.HasPrefix(data, errorStringSelector) {
+		if reason, err := abi.UnpackRevert(data); err == nil {
+			return &RevertError{Reason: reason, Data: data}
+		}
+	}
+
+	return &RevertError{Data: data}
+}
+
+// ContractErrorByCode returns the sentinel with the given machine-readable code
+func ContractErrorByCode(code string) (*ContractError, bool) {
+	for _, sentinel := range contractErrors {
+		if sentinel.Code == code {
+			return sentinel, true
+		}
+	}
+	return nil, false
+}
+
+// ContractErrorByName returns the sentinel with the given Solidity error name
+func ContractErrorByName(name string) (*ContractError, bool) {
+	for _, sentinel := range contractErrors {
+		if sentinel.Name == name {
+			return sentinel, true
+		}
+	}
+	return nil, false
+}
+
+// revertData extracts the revert data a node attached to a failed call or gas estimate.
+// Errors without revert data, such as transport failures, report false.
+func revertData(err error) ([]byte, bool) {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return nil, false
+	}
+
+	hexData, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return nil, false
+	}
+	data, decodeErr := hexutil.Decode(hexData)
+	if decodeErr != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// parseCallError wraps err with its decoded revert when it carries revert data, so the
+// result matches both the contract sentinel and the original RPC error
+func parseCallError(err error) error {
+	data, ok := revertData(err)
+	if !ok {
+		return err
+	}
+	return fmt.Errorf("%w: %w", DecodeRevert(data), err)
+}
@@ -0,0 +1,57 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+type testDataError struct {
+	data string
+}
+
+func (e testDataError) Error() string          { return "execution reverted" }
+func (e testDataError) ErrorData() interface{} { return e.data }
+
+func TestDecodeRevert(t *testing.T) {
+	for _, sentinel := range contractErrors {
+		selector := crypto.Keccak256([]byte(sentinel.Name + "()"))[:4]
+		if err := DecodeRevert(selector); !errors.Is(err, sentinel) {
+			t.Errorf("DecodeRevert(%s selector) = %v, want %v", sentinel.Name, err, sentinel)
+		}
+	}
+
+	// Error("Job not found")
+	data := hexutil.MustDecode("0x08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"000000000000000000000000000000000000000000000000000000000000000d" +
+		"4a6f62206e6f7420666f756e6400000000000000000000000000000000000000")
+	var revertErr *RevertError
+	if err := DecodeRevert(data); !errors.As(err, &revertErr) || revertErr.Reason != "Job not found" {
+		t.Errorf("DecodeRevert(Error(string)) = %v, want reason %q", err, "Job not found")
+	}
+
+	if err := DecodeRevert([]byte{0xde, 0xad, 0xbe, 0xef}); !errors.As(err, &revertErr) {
+		t.Errorf("DecodeRevert(unknown selector) = %v, want *RevertError", err)
+	}
+}
+
+func TestParseCallError(t *testing.T) {
+	selector := crypto.Keccak256([]byte("JobNotCompleted()"))[:4]
+	rpcErr := testDataError{data: hexutil.Encode(selector)}
+
+	err := parseCallError(rpcErr)
+	if !errors.Is(err, ErrJobNotCompleted) {
+		t.Errorf("parseCallError() = %v, want ErrJobNotCompleted", err)
+	}
+	if !errors.Is(err, rpcErr) {
+		t.Errorf("parseCallError() = %v, want the RPC error to stay wrapped", err)
+	}
+
+	transportErr := errors.New("connection refused")
+	if err := parseCallError(transportErr); err != transportErr {
+		t.Errorf("parseCallError(transport error) = %v, want it unchanged", err)
+	}
+}
@@ -0,0 +1,148 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/fahedafzaal/go-integration/contracts"
+)
+
+// Escrow contract event names
+const (
+	EventJobPosted       = "JobPosted"
+	EventJobCompleted    = "JobCompleted"
+	EventPaymentReleased = "PaymentReleased"
+	EventJobCancelled    = "JobCancelled"
+)
+
+// EscrowEvent is a decoded EthJobEscrow log entry
+type EscrowEvent struct {
+	Name        string
+	JobID       uint64
+	Client      common.Address // JobPosted, JobCancelled
+	Freelancer  common.Address // JobPosted, PaymentReleased
+	USDAmount   *big.Int       // JobPosted
+	ETHAmount   *big.Int       // JobPosted, PaymentReleased, JobCancelled
+	TxHash      common.Hash
+	BlockNumber uint64
+	BlockHash   common.Hash
+	LogIndex    uint
+}
+
+// LatestBlockNumber returns the current head block number
+func (c *Client) LatestBlockNumber(ctx context.Context) (uint64, error) {
+	return c.ethClient.BlockNumber(ctx)
+}
+
+// FilterEscrowEvents fetches all escrow events emitted in the inclusive block range
+// [fromBlock, toBlock], ordered by block number and log index
+func (c *Client) FilterEscrowEvents(ctx context.Context, fromBlock, toBlock uint64) ([]EscrowEvent, error) {
+	contractABI, err := contracts.EthJobEscrowMetaData.GetAbi()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contract ABI: %w", err)
+	}
+
+	topics := []common.Hash{
+		contractABI.Events[EventJobPosted].ID,
+		contractABI.Events[EventJobCompleted].ID,
+		contractABI.Events[EventPaymentReleased].ID,
+		contractABI.Events[EventJobCancelled].ID,
+	}
+
+	query := ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(fromBlock),
+		ToBlock:   new(big.Int).SetUint64(toBlock),
+		Addresses: []common.Address{c.contractAddress},
+		Topics:    [][]common.Hash{topics},
+	}
+
+	logs, err := c.ethClient.FilterLogs(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter logs for blocks %d-%d: %w", fromBlock, toBlock, err)
+	}
+
+	events := make([]EscrowEvent, 0, len(logs))
+	for _, vLog := range logs {
+		if vLog.Removed {
+			continue
+		}
+
+		event, err := c.parseEscrowEvent(vLog, topics)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse log %s/%d: %w", vLog.TxHash.Hex(), vLog.Index, err)
+		}
+		events = append(events, *event)
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].BlockNumber != events[j].BlockNumber {
+			return events[i].BlockNumber < events[j].BlockNumber
+		}
+		return events[i].LogIndex < events[j].LogIndex
+	})
+
+	return events, nil
+}
+
+// parseEscrowEvent decodes a raw log using the generated contract filterers
+func (c *Client) parseEscrowEvent(vLog types.Log, topics []common.Hash) (*EscrowEvent, error) {
+	if len(vLog.Topics) == 0 {
+		return nil, fmt.Errorf("log has no topics")
+	}
+
+	event := &EscrowEvent{
+		TxHash:      vLog.TxHash,
+		BlockNumber: vLog.BlockNumber,
+		BlockHash:   vLog.BlockHash,
+		LogIndex:    vLog.Index,
+	}
+
+	switch vLog.Topics[0] {
+	case topics[0]:
+		posted, err := c.contract.ParseJobPosted(vLog)
+		if err != nil {
+			return nil, err
+		}
+		event.Name = EventJobPosted
+		event.JobID = posted.JobId.Uint64()
+		event.Client = posted.Client
+		event.Freelancer = posted.Freelancer
+		event.USDAmount = posted.UsdAmount
+		event.ETHAmount = posted.EthAmount
+	case topics[1]:
+		completed, err := c.contract.ParseJobCompleted(vLog)
+		if err != nil {
+			return nil, err
+		}
+		event.Name = EventJobCompleted
+		event.JobID = completed.JobId.Uint64()
+	case topics[2]:
+		released, err := c.contract.ParsePaymentReleased(vLog)
+		if err != nil {
+			return nil, err
+		}
+		event.Name = EventPaymentReleased
+		event.JobID = released.JobId.Uint64()
+		event.Freelancer = released.Freelancer
+		event.ETHAmount = released.EthAmount
+	case topics[3]:
+		cancelled, err := c.contract.ParseJobCancelled(vLog)
+		if err != nil {
+			return nil, err
+		}
+		event.Name = EventJobCancelled
+		event.JobID = cancelled.JobId.Uint64()
+		event.Client = cancelled.Client
+		event.ETHAmount = cancelled.EthAmount
+	default:
+		return nil, fmt.Errorf("unknown event topic %s", vLog.Topics[0].Hex())
+	}
+
+	return event, nil
+}
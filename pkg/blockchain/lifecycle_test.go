@@ -0,0 +1,312 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/fahedafzaal/go-integration/internal/config"
+	"github.com/fahedafzaal/go-integration/internal/escrowsim"
+	"github.com/fahedafzaal/go-integration/pkg/money"
+)
+
+// ethUSDPrice is the initial price feed answer, $2000 with 8 decimals
+var ethUSDPrice = big.NewInt(2000_00000000)
+
+// escrowEnv is an escrow deployment on a simulated chain. The gateway signer owns the
+// contract and funds deposits, as it does in production.
+type escrowEnv struct {
+	t          *testing.T
+	ctx        context.Context
+	eth        EthBackend
+	deployer   *bind.TransactOpts
+	feed       *escrowsim.Aggregator
+	escrow     common.Address
+	gateway    *Client
+	stranger   *Client // funded, but neither the owner nor a job's client
+	poster     common.Address
+	freelancer common.Address
+}
+
+func newEscrowEnv(t *testing.T) *escrowEnv {
+	t.Helper()
+
+	deployerKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	gatewaySigner := NewFakeSigner()
+	strangerSigner := NewFakeSigner()
+
+	backend := escrowsim.NewBackend(crypto.PubkeyToAddress(deployerKey.PublicKey), gatewaySigner.Address(), strangerSigner.Address())
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(func() {
+		cancel()
+		backend.Close()
+	})
+	go escrowsim.Mine(ctx, backend, 20*time.Millisecond)
+
+	eth, ok := backend.Client().(EthBackend)
+	if !ok {
+		t.Fatal("simulated client does not implement EthBackend")
+	}
+	chainID, err := eth.ChainID(ctx)
+	if err != nil {
+		t.Fatalf("ChainID: %v", err)
+	}
+	deployer, err := bind.NewKeyedTransactorWithChainID(deployerKey, chainID)
+	if err != nil {
+		t.Fatalf("NewKeyedTransactorWithChainID: %v", err)
+	}
+
+	env := &escrowEnv{
+		t:          t,
+		ctx:        ctx,
+		eth:        eth,
+		deployer:   deployer,
+		poster:     NewFakeSigner().Address(),
+		freelancer: NewFakeSigner().Address(),
+	}
+
+	feed, tx, err := escrowsim.DeployAggregator(deployer, eth, 8, ethUSDPrice)
+	if err != nil {
+		t.Fatalf("DeployAggregator: %v", err)
+	}
+	env.waitMined(tx)
+	env.feed = feed
+
+	escrow, tx, _, err := escrowsim.DeployEscrow(deployer, eth, feed.Address, gatewaySigner.Address())
+	if errors.Is(err, escrowsim.ErrNoEscrowBytecode) {
+		t.Skip("EthJobEscrow bindings lack the compiled bytecode; regenerate them with make bindings")
+	}
+	if err != nil {
+		t.Fatalf("DeployEscrow: %v", err)
+	}
+	env.waitMined(tx)
+	env.escrow = escrow
+
+	env.gateway = env.newClient(gatewaySigner)
+	env.stranger = env.newClient(strangerSigner)
+	return env
+}
+
+// newClient connects a client signing with signer to the escrow
+func (e *escrowEnv) newClient(signer Signer) *Client {
+	e.t.Helper()
+	cfg := &config.Config{
+		ContractAddress:   e.escrow.Hex(),
+		ETHUSDPriceFeed:   e.feed.Address.Hex(),
+		GasLimit:          300000,
+		ConfirmationDepth: 1,
+	}
+	client, err := NewClientWithBackend(cfg, e.eth, signer)
+	if err != nil {
+		e.t.Fatalf("NewClientWithBackend: %v", err)
+	}
+	return client
+}
+
+// service returns a direct-mode payment gateway service on the gateway client
+func (e *escrowEnv) service() *PaymentGatewayService {
+	return &PaymentGatewayService{mode: DirectMode, client: e.gateway, config: e.gateway.config}
+}
+
+// waitMined blocks until a transaction sent outside the client is mined successfully
+func (e *escrowEnv) waitMined(tx *types.Transaction) {
+	e.t.Helper()
+	receipt, err := bind.WaitMined(e.ctx, e.eth, tx)
+	if err != nil {
+		e.t.Fatalf("WaitMined(%s): %v", tx.Hash().Hex(), err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		e.t.Fatalf("transaction %s reverted", tx.Hash().Hex())
+	}
+}
+
+func (e *escrowEnv) balance(address common.Address) *big.Int {
+	e.t.Helper()
+	balance, err := e.eth.BalanceAt(e.ctx, address, nil)
+	if err != nil {
+		e.t.Fatalf("BalanceAt(%s): %v", address.Hex(), err)
+	}
+	return balance
+}
+
+func (e *escrowEnv) postJob(jobID uint64, usdAmount string) *JobDetails {
+	e.t.Helper()
+	result, err := e.gateway.PostJob(e.ctx, jobID, e.freelancer, money.MustParseUSD(usdAmount), e.poster)
+	if err != nil {
+		e.t.Fatalf("PostJob(%d): %v", jobID, err)
+	}
+	if !result.Success || result.Confirmations < 1 {
+		e.t.Fatalf("PostJob(%d) = %+v, want a confirmed success", jobID, result)
+	}
+
+	details, err := e.gateway.GetJobDetails(e.ctx, jobID)
+	if err != nil {
+		e.t.Fatalf("GetJobDetails(%d): %v", jobID, err)
+	}
+	return details
+}
+
+func (e *escrowEnv) requireStatus(jobID uint64, want string) {
+	e.t.Helper()
+	state, err := e.service().CheckAndReconcileJobState(e.ctx, jobID)
+	if err != nil {
+		e.t.Fatalf("CheckAndReconcileJobState(%d): %v", jobID, err)
+	}
+	if state.PaymentStatus != want {
+		e.t.Fatalf("CheckAndReconcileJobState(%d) status = %q, want %q", jobID, state.PaymentStatus, want)
+	}
+}
+
+func TestEscrowLifecycleRelease(t *testing.T) {
+	t.Parallel()
+	env := newEscrowEnv(t)
+	const jobID = 101
+
+	if exists, err := env.gateway.JobExists(env.ctx, jobID); err != nil || exists {
+		t.Fatalf("JobExists before posting = %v, %v; want false", exists, err)
+	}
+	env.requireStatus(jobID, "not_found")
+
+	// $150 at $2000/ETH is 0.075 ETH, sent with the client's 2% slippage buffer
+	details := env.postJob(jobID, "150")
+	wantDeposit := new(big.Int).Mul(big.NewInt(75e15), big.NewInt(102))
+	wantDeposit.Div(wantDeposit, big.NewInt(100))
+	if details.Client != env.poster || details.Freelancer != env.freelancer {
+		t.Errorf("job parties = %s, %s; want %s, %s", details.Client.Hex(), details.Freelancer.Hex(), env.poster.Hex(), env.freelancer.Hex())
+	}
+	if details.USDAmount.Cmp(big.NewInt(150_00000000)) != 0 {
+		t.Errorf("USDAmount = %s, want 15000000000", details.USDAmount)
+	}
+	if details.ETHAmount.Cmp(wantDeposit) != 0 {
+		t.Errorf("ETHAmount = %s, want %s", details.ETHAmount, wantDeposit)
+	}
+	if exists, err := env.gateway.JobExists(env.ctx, jobID); err != nil || !exists {
+		t.Fatalf("JobExists after posting = %v, %v; want true", exists, err)
+	}
+	env.requireStatus(jobID, "deposited")
+
+	// Posting the same job twice reverts with a require message
+	_, err := env.gateway.PostJob(env.ctx, jobID, env.freelancer, money.MustParseUSD("150"), env.poster)
+	var revertErr *RevertError
+	if !errors.As(err, &revertErr) || revertErr.Reason != "Job already exists" {
+		t.Fatalf("second PostJob error = %v, want the Job already exists revert", err)
+	}
+
+	// Only the job's client or the owner may release
+	if _, err := env.stranger.MarkJobCompleted(env.ctx, jobID); !errors.Is(err, ErrOnlyClientCanMarkCompleted) {
+		t.Fatalf("MarkJobCompleted by stranger error = %v, want ErrOnlyClientCanMarkCompleted", err)
+	}
+
+	result, err := env.gateway.MarkJobCompleted(env.ctx, jobID)
+	if err != nil || !result.Success {
+		t.Fatalf("MarkJobCompleted = %+v, %v", result, err)
+	}
+	// The contract may keep a platform fee, so the freelancer gets at most the deposit
+	if got := env.balance(env.freelancer); got.Sign() <= 0 || got.Cmp(wantDeposit) > 0 {
+		t.Errorf("freelancer balance = %s, want a payout of at most %s", got, wantDeposit)
+	}
+	env.requireStatus(jobID, "released")
+
+	// A released job can be neither released again nor cancelled; the preflight
+	// simulation and an on-chain revert decode to the same sentinel
+	if err := env.gateway.SimulateCancelJob(env.ctx, jobID); !errors.Is(err, ErrJobNotCancelable) {
+		t.Errorf("SimulateCancelJob error = %v, want ErrJobNotCancelable", err)
+	}
+	if _, err := env.gateway.CancelJob(env.ctx, jobID); !errors.Is(err, ErrJobNotCancelable) {
+		t.Errorf("CancelJob error = %v, want ErrJobNotCancelable", err)
+	}
+	if _, err := env.gateway.MarkJobCompleted(env.ctx, jobID); !errors.Is(err, ErrJobAlreadyCompleted) {
+		t.Errorf("second MarkJobCompleted error = %v, want ErrJobAlreadyCompleted", err)
+	}
+}
+
+func TestEscrowLifecycleCancel(t *testing.T) {
+	t.Parallel()
+	env := newEscrowEnv(t)
+	const jobID = 202
+
+	details := env.postJob(jobID, "40")
+	env.requireStatus(jobID, "deposited")
+
+	if err := env.stranger.SimulateCancelJob(env.ctx, jobID); !errors.Is(err, ErrNotJobClient) {
+		t.Fatalf("SimulateCancelJob by stranger error = %v, want ErrNotJobClient", err)
+	}
+	if _, err := env.stranger.CancelJob(env.ctx, jobID); !errors.Is(err, ErrNotJobClient) {
+		t.Fatalf("CancelJob by stranger error = %v, want ErrNotJobClient", err)
+	}
+
+	result, err := env.gateway.CancelJob(env.ctx, jobID)
+	if err != nil || !result.Success {
+		t.Fatalf("CancelJob = %+v, %v", result, err)
+	}
+	if got := env.balance(env.poster); got.Cmp(details.ETHAmount) != 0 {
+		t.Errorf("poster balance after refund = %s, want %s", got, details.ETHAmount)
+	}
+
+	// The cancelled job is deleted, so it reads as missing and cannot be released
+	if exists, err := env.gateway.JobExists(env.ctx, jobID); err != nil || exists {
+		t.Fatalf("JobExists after cancel = %v, %v; want false", exists, err)
+	}
+	env.requireStatus(jobID, "not_found")
+
+	// Historical reads still see the escrow the refund emptied
+	if before, err := env.gateway.JobDetailsAt(env.ctx, jobID, result.BlockNumber-1); err != nil || before == nil || before.Client != env.poster {
+		t.Errorf("JobDetailsAt before cancel = %+v, %v; want the posted job", before, err)
+	}
+	if after, err := env.gateway.JobDetailsAt(env.ctx, jobID, result.BlockNumber); err != nil || after != nil {
+		t.Errorf("JobDetailsAt after cancel = %+v, %v; want nil", after, err)
+	}
+
+	_, err = env.gateway.MarkJobCompleted(env.ctx, jobID)
+	var revertErr *RevertError
+	if !errors.As(err, &revertErr) || revertErr.Reason != "Job does not exist" {
+		t.Fatalf("MarkJobCompleted after cancel error = %v, want the Job does not exist revert", err)
+	}
+}
+
+func TestEscrowPostJobRevertsAfterPriceDrop(t *testing.T) {
+	t.Parallel()
+	env := newEscrowEnv(t)
+	const jobID = 303
+
+	// The deposit is priced when the transaction is prepared. A price drop before it is
+	// mined raises the ETH the contract requires beyond the slippage buffer.
+	tx, err := env.gateway.PreparePostJob(env.ctx, jobID, env.freelancer, money.MustParseUSD("500"), env.poster)
+	if err != nil {
+		t.Fatalf("PreparePostJob: %v", err)
+	}
+
+	update, err := env.feed.UpdateAnswer(env.deployer, new(big.Int).Div(ethUSDPrice, big.NewInt(2)))
+	if err != nil {
+		t.Fatalf("UpdateAnswer: %v", err)
+	}
+	env.waitMined(update)
+
+	result, err := env.gateway.sendAndWait(env.ctx, jobID, tx)
+	if !errors.Is(err, ErrInsufficientEthSent) {
+		t.Fatalf("PostJob after price drop error = %v, want ErrInsufficientEthSent", err)
+	}
+	if result == nil || result.Success || result.TxHash != tx.Hash().Hex() {
+		t.Errorf("result = %+v, want a failed result for %s", result, tx.Hash().Hex())
+	}
+
+	if exists, err := env.gateway.JobExists(env.ctx, jobID); err != nil || exists {
+		t.Fatalf("JobExists after revert = %v, %v; want false", exists, err)
+	}
+
+	// Repricing at the new answer succeeds
+	details := env.postJob(jobID, "500")
+	if want := new(big.Int).Mul(big.NewInt(5e17), big.NewInt(102)); details.ETHAmount.Cmp(want.Div(want, big.NewInt(100))) != 0 {
+		t.Errorf("ETHAmount = %s, want %s", details.ETHAmount, want)
+	}
+}
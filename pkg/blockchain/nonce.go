@@ -0,0 +1,237 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Nonce reservation states shared by all NonceStore implementations
+const (
+	NonceReserved = "reserved" // handed out, transaction not broadcast yet
+	NonceSent     = "sent"     // transaction broadcast, not mined yet
+	NonceReleased = "released" // never broadcast or dropped; handed out again before new nonces
+)
+
+// DefaultNonceStaleAfter is how long a reserved nonce may go unseen by the node before
+// it is treated as abandoned and reused to close the gap
+const DefaultNonceStaleAfter = 5 * time.Minute
+
+// NonceStore persists nonce reservations per signer address so in-flight nonces
+// survive restarts. Addresses are lower-case hex.
+type NonceStore interface {
+	// ReserveNonce atomically hands out the next nonce for address. chainPending and
+	// chainMined are the node's pending and mined transaction counts. Reservations below
+	// chainMined are forgotten, and a reservation at chainPending that was never sent and
+	// is still unseen after staleAfter is handed out again. A sent one is only reused once
+	// released, since its transaction may yet be mined.
+	ReserveNonce(ctx context.Context, address string, chainPending, chainMined uint64, staleAfter time.Duration) (uint64, error)
+	// MarkNonceSent records the transaction broadcast with a reserved nonce
+	MarkNonceSent(ctx context.Context, address string, nonce uint64, txHash string) error
+	// ReleaseNonce returns a nonce whose transaction never reached the network, or was
+	// given up on by the outbox
+	ReleaseNonce(ctx context.Context, address string, nonce uint64) error
+}
+
+// nonceBackend is the subset of the Ethereum client used to resync with the node
+type nonceBackend interface {
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+}
+
+// NonceManager hands out nonces atomically per signer address so concurrent
+// transactions from the gateway never collide
+type NonceManager struct {
+	backend    nonceBackend
+	store      NonceStore
+	staleAfter time.Duration
+
+	mu    sync.Mutex
+	locks map[common.Address]*sync.Mutex
+}
+
+// NewNonceManager creates a nonce manager backed by store
+func NewNonceManager(backend nonceBackend, store NonceStore) *NonceManager {
+	return &NonceManager{
+		backend:    backend,
+		store:      store,
+		staleAfter: DefaultNonceStaleAfter,
+		locks:      make(map[common.Address]*sync.Mutex),
+	}
+}
+
+func (m *NonceManager) lockFor(address common.Address) *sync.Mutex {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lock, ok := m.locks[address]
+	if !ok {
+		lock = &sync.Mutex{}
+		m.locks[address] = lock
+	}
+	return lock
+}
+
+// Next reserves the next nonce for address
+func (m *NonceManager) Next(ctx context.Context, address common.Address) (uint64, error) {
+	// The store locks across processes; this lock keeps one process from
+	// hammering the store with concurrent reservations for the same signer
+	lock := m.lockFor(address)
+	lock.Lock()
+	defer lock.Unlock()
+
+	pending, err := m.backend.PendingNonceAt(ctx, address)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending nonce: %w", err)
+	}
+
+	mined, err := m.backend.NonceAt(ctx, address, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get mined nonce: %w", err)
+	}
+
+	nonce, err := m.store.ReserveNonce(ctx, nonceKey(address), pending, mined, m.staleAfter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reserve nonce: %w", err)
+	}
+
+	return nonce, nil
+}
+
+// MarkSent records that the transaction using nonce was broadcast
+func (m *NonceManager) MarkSent(ctx context.Context, address common.Address, nonce uint64, txHash common.Hash) {
+	if err := m.store.MarkNonceSent(ctx, nonceKey(address), nonce, txHash.Hex()); err != nil {
+		log.Printf("Warning: Failed to record sent nonce %d for %s: %v", nonce, address.Hex(), err)
+	}
+}
+
+// Release returns a nonce whose transaction was never broadcast
+func (m *NonceManager) Release(ctx context.Context, address common.Address, nonce uint64) {
+	if err := m.store.ReleaseNonce(ctx, nonceKey(address), nonce); err != nil {
+		log.Printf("Warning: Failed to release nonce %d for %s: %v", nonce, address.Hex(), err)
+	}
+}
+
+func nonceKey(address common.Address) string {
+	return strings.ToLower(address.Hex())
+}
+
+// memoryNonceStore is the NonceStore used when no database is attached.
+// Reservations are lost on restart.
+type memoryNonceStore struct {
+	mu           sync.Mutex
+	next         map[string]uint64
+	reservations map[string]map[uint64]*nonceReservation
+	now          func() time.Time
+}
+
+type nonceReservation struct {
+	status    string
+	txHash    string
+	updatedAt time.Time
+}
+
+// NewMemoryNonceStore creates an in-process NonceStore
+func NewMemoryNonceStore() NonceStore {
+	return &memoryNonceStore{
+		next:         make(map[string]uint64),
+		reservations: make(map[string]map[uint64]*nonceReservation),
+		now:          time.Now,
+	}
+}
+
+func (s *memoryNonceStore) ReserveNonce(ctx context.Context, address string, chainPending, chainMined uint64, staleAfter time.Duration) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reservations, ok := s.reservations[address]
+	if !ok {
+		reservations = make(map[uint64]*nonceReservation)
+		s.reservations[address] = reservations
+	}
+
+	// Mined (or otherwise consumed) nonces no longer need tracking
+	for nonce := range reservations {
+		if nonce < chainMined {
+			delete(reservations, nonce)
+		}
+	}
+
+	next := s.next[address]
+	if next < chainPending {
+		next = chainPending
+	}
+	s.next[address] = next
+
+	// A gap at the node's pending nonce means the transaction holding it was dropped
+	// or never sent; everything we broadcast after it is stuck until it is filled. A sent
+	// transaction is left to the outbox, which rebroadcasts it or releases the nonce.
+	now := s.now()
+	if chainPending < next {
+		r, ok := reservations[chainPending]
+		switch {
+		case !ok || (r.status == NonceReserved && now.Sub(r.updatedAt) > staleAfter):
+			log.Printf("Nonce %d for %s is missing from the node, releasing for reuse", chainPending, address)
+			reservations[chainPending] = &nonceReservation{status: NonceReleased, updatedAt: now}
+		case r.status == NonceSent && now.Sub(r.updatedAt) > staleAfter:
+			log.Printf("Nonce %d for %s was sent as %s but is missing from the node", chainPending, address, r.txHash)
+		}
+	}
+
+	// Released nonces are handed out again, lowest first, before new ones
+	reusable, found := uint64(0), false
+	for nonce, r := range reservations {
+		if r.status == NonceReleased && (!found || nonce < reusable) {
+			reusable, found = nonce, true
+		}
+	}
+	if found {
+		reservations[reusable] = &nonceReservation{status: NonceReserved, updatedAt: now}
+		return reusable, nil
+	}
+
+	reservations[next] = &nonceReservation{status: NonceReserved, updatedAt: now}
+	s.next[address] = next + 1
+	return next, nil
+}
+
+func (s *memoryNonceStore) MarkNonceSent(ctx context.Context, address string, nonce uint64, txHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.reservations[address][nonce]
+	if !ok {
+		return fmt.Errorf("nonce %d is not reserved for %s", nonce, address)
+	}
+	r.status = NonceSent
+	r.txHash = txHash
+	r.updatedAt = s.now()
+	return nil
+}
+
+func (s *memoryNonceStore) ReleaseNonce(ctx context.Context, address string, nonce uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.reservations[address][nonce]
+	if !ok {
+		return nil
+	}
+
+	// Giving back the newest nonce simply rewinds the counter
+	if nonce+1 == s.next[address] {
+		delete(s.reservations[address], nonce)
+		s.next[address] = nonce
+		return nil
+	}
+
+	r.status = NonceReleased
+	r.updatedAt = s.now()
+	return nil
+}
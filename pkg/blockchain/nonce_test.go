@@ -0,0 +1,150 @@
+package blockchain
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type fakeNonceBackend struct {
+	mu      sync.Mutex
+	pending uint64
+	mined   uint64
+}
+
+func (b *fakeNonceBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pending, nil
+}
+
+func (b *fakeNonceBackend) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.mined, nil
+}
+
+var testSigner = common.HexToAddress("0x1234567890123456789012345678901234567890")
+
+func TestNonceManagerConcurrentReservations(t *testing.T) {
+	backend := &fakeNonceBackend{pending: 7, mined: 7}
+	manager := NewNonceManager(backend, NewMemoryNonceStore())
+	ctx := context.Background()
+
+	const workers = 20
+	nonces := make(chan uint64, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nonce, err := manager.Next(ctx, testSigner)
+			if err != nil {
+				t.Errorf("Next failed: %v", err)
+				return
+			}
+			nonces <- nonce
+		}()
+	}
+	wg.Wait()
+	close(nonces)
+
+	seen := make(map[uint64]bool)
+	for nonce := range nonces {
+		if seen[nonce] {
+			t.Errorf("Nonce %d handed out twice", nonce)
+		}
+		if nonce < 7 || nonce >= 7+workers {
+			t.Errorf("Nonce %d outside expected range [7, %d)", nonce, 7+workers)
+		}
+		seen[nonce] = true
+	}
+}
+
+func TestNonceManagerRelease(t *testing.T) {
+	backend := &fakeNonceBackend{pending: 0, mined: 0}
+	manager := NewNonceManager(backend, NewMemoryNonceStore())
+	ctx := context.Background()
+
+	first, _ := manager.Next(ctx, testSigner)
+	second, _ := manager.Next(ctx, testSigner)
+	third, _ := manager.Next(ctx, testSigner)
+
+	// Releasing the newest nonce rewinds the counter
+	manager.Release(ctx, testSigner, third)
+	if next, _ := manager.Next(ctx, testSigner); next != third {
+		t.Errorf("Expected rewound nonce %d, got %d", third, next)
+	}
+
+	// Releasing an older nonce leaves a gap that is filled first
+	manager.MarkSent(ctx, testSigner, first, common.Hash{1})
+	manager.Release(ctx, testSigner, second)
+	if next, _ := manager.Next(ctx, testSigner); next != second {
+		t.Errorf("Expected released nonce %d to be reused, got %d", second, next)
+	}
+	if next, _ := manager.Next(ctx, testSigner); next != third+1 {
+		t.Errorf("Expected fresh nonce %d, got %d", third+1, next)
+	}
+}
+
+func TestNonceManagerResyncsAfterDrop(t *testing.T) {
+	backend := &fakeNonceBackend{pending: 3, mined: 3}
+	store := NewMemoryNonceStore().(*memoryNonceStore)
+	manager := NewNonceManager(backend, store)
+	ctx := context.Background()
+
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	// The first nonce is reserved but its transaction is never sent, e.g. after a crash
+	abandoned, _ := manager.Next(ctx, testSigner)
+	queued, _ := manager.Next(ctx, testSigner)
+	manager.MarkSent(ctx, testSigner, queued, common.Hash{2})
+
+	// Before staleAfter it may still be on its way
+	if next, _ := manager.Next(ctx, testSigner); next != queued+1 {
+		t.Errorf("Expected fresh nonce %d while the gap is recent, got %d", queued+1, next)
+	}
+
+	// Once stale, the missing nonce is handed out again to unblock the queue
+	now = now.Add(DefaultNonceStaleAfter + time.Second)
+	if next, _ := manager.Next(ctx, testSigner); next != abandoned {
+		t.Errorf("Expected abandoned nonce %d to be reused, got %d", abandoned, next)
+	}
+
+	// Mined nonces are forgotten and the counter follows the node
+	backend.pending, backend.mined = 10, 10
+	if next, _ := manager.Next(ctx, testSigner); next != 10 {
+		t.Errorf("Expected nonce to resync to 10, got %d", next)
+	}
+}
+
+func TestNonceManagerKeepsStaleSentNonce(t *testing.T) {
+	backend := &fakeNonceBackend{pending: 3, mined: 3}
+	store := NewMemoryNonceStore().(*memoryNonceStore)
+	manager := NewNonceManager(backend, store)
+	ctx := context.Background()
+
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	sent, _ := manager.Next(ctx, testSigner)
+	manager.MarkSent(ctx, testSigner, sent, common.Hash{1})
+
+	// The node has not seen the transaction for longer than staleAfter, but it may
+	// still be mined; reusing its nonce could send two transactions for one intent
+	now = now.Add(DefaultNonceStaleAfter + time.Second)
+	if next, _ := manager.Next(ctx, testSigner); next == sent {
+		t.Fatalf("Sent nonce %d was reissued after staleAfter", sent)
+	}
+
+	// Once the outbox gives up on it and releases the nonce, it fills the gap
+	manager.Release(ctx, testSigner, sent)
+	if next, _ := manager.Next(ctx, testSigner); next != sent {
+		t.Errorf("Expected released nonce %d to be reused, got %d", sent, next)
+	}
+}
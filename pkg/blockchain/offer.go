@@ -0,0 +1,136 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// EIP-712 domain of escrow offers; the chain id and escrow contract complete it
+const (
+	OfferDomainName    = "EthJobEscrow"
+	OfferDomainVersion = "1"
+)
+
+var (
+	ErrOfferExpired         = errors.New("escrow offer has expired")
+	ErrOfferSignerMismatch  = errors.New("escrow offer was not signed by the client")
+	ErrOfferSignatureFormat = errors.New("invalid escrow offer signature")
+)
+
+// EscrowOffer is the escrow terms a client authorizes off-chain before funding the job
+// from their own wallet. The signed terms are exactly those verifyJobPostedEvent checks
+// the JobPosted event against.
+type EscrowOffer struct {
+	JobID      uint64
+	Freelancer common.Address
+	Client     common.Address
+	USDAmount  *big.Int // 8-decimal USD as the contract takes it
+	Expiry     uint64   // unix seconds after which the signature is no longer accepted
+}
+
+// offerTypes describes EscrowOffer for eth_signTypedData_v4
+var offerTypes = apitypes.Types{
+	"EIP712Domain": {
+		{Name: "name", Type: "string"},
+		{Name: "version", Type: "string"},
+		{Name: "chainId", Type: "uint256"},
+		{Name: "verifyingContract", Type: "address"},
+	},
+	"EscrowOffer": {
+		{Name: "jobId", Type: "uint256"},
+		{Name: "freelancer", Type: "address"},
+		{Name: "client", Type: "address"},
+		{Name: "usdAmount", Type: "uint256"},
+		{Name: "expiry", Type: "uint256"},
+	},
+}
+
+// TypedData returns the offer as EIP-712 typed data for the escrow contract on chainID,
+// ready to pass to a wallet's eth_signTypedData_v4
+func (o EscrowOffer) TypedData(chainID *big.Int, contract common.Address) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types:       offerTypes,
+		PrimaryType: "EscrowOffer",
+		Domain: apitypes.TypedDataDomain{
+			Name:              OfferDomainName,
+			Version:           OfferDomainVersion,
+			ChainId:           (*math.HexOrDecimal256)(chainID),
+			VerifyingContract: contract.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"jobId":      strconv.FormatUint(o.JobID, 10),
+			"freelancer": o.Freelancer.Hex(),
+			"client":     o.Client.Hex(),
+			"usdAmount":  o.USDAmount.String(),
+			"expiry":     strconv.FormatUint(o.Expiry, 10),
+		},
+	}
+}
+
+// Hash returns the EIP-712 digest a wallet signs for the offer
+func (o EscrowOffer) Hash(chainID *big.Int, contract common.Address) ([]byte, error) {
+	hash, _, err := apitypes.TypedDataAndHash(o.TypedData(chainID, contract))
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash escrow offer: %w", err)
+	}
+	return hash, nil
+}
+
+// VerifyEscrowOffer checks that signature is the offer's client signing it for the escrow
+// contract on chainID, and that the offer had not expired at now
+func VerifyEscrowOffer(o EscrowOffer, chainID *big.Int, contract common.Address, signature []byte, now time.Time) error {
+	if uint64(now.Unix()) > o.Expiry {
+		return ErrOfferExpired
+	}
+	if len(signature) != crypto.SignatureLength {
+		return fmt.Errorf("%w: length %d", ErrOfferSignatureFormat, len(signature))
+	}
+
+	hash, err := o.Hash(chainID, contract)
+	if err != nil {
+		return err
+	}
+
+	// Wallets return v as 27 or 28
+	sig := make([]byte, len(signature))
+	copy(sig, signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrOfferSignatureFormat, err)
+	}
+	if signer := crypto.PubkeyToAddress(*pub); signer != o.Client {
+		return fmt.Errorf("%w: signed by %s", ErrOfferSignerMismatch, signer.Hex())
+	}
+	return nil
+}
+
+// EscrowOfferTypedData returns an offer as typed data for this client's network and
+// escrow contract
+func (c *Client) EscrowOfferTypedData(ctx context.Context, o EscrowOffer) (apitypes.TypedData, error) {
+	chainID, err := c.ethClient.ChainID(ctx)
+	if err != nil {
+		return apitypes.TypedData{}, fmt.Errorf("failed to get chain ID: %w", err)
+	}
+	return o.TypedData(chainID, c.contractAddress), nil
+}
+
+// VerifyEscrowOffer checks an offer signature for this client's network and escrow contract
+func (c *Client) VerifyEscrowOffer(ctx context.Context, o EscrowOffer, signature []byte) error {
+	chainID, err := c.ethClient.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get chain ID: %w", err)
+	}
+	return VerifyEscrowOffer(o, chainID, c.contractAddress, signature, time.Now())
+}
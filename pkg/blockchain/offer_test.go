@@ -0,0 +1,76 @@
+package blockchain
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/fahedafzaal/go-integration/pkg/money"
+)
+
+func TestVerifyEscrowOffer(t *testing.T) {
+	clientKey, _ := crypto.GenerateKey()
+	chainID := big.NewInt(11155111)
+	contract := common.HexToAddress("0x00000000000000000000000000000000000e5c40")
+	now := time.Unix(1750000000, 0)
+
+	offer, err := PostJobRequest{
+		JobID:             42,
+		FreelancerAddress: "0x2222222222222222222222222222222222222222",
+		USDAmount:         money.MustParseUSD("150"),
+		ClientAddress:     crypto.PubkeyToAddress(clientKey.PublicKey).Hex(),
+		OfferExpiry:       uint64(now.Add(15 * time.Minute).Unix()),
+	}.Offer()
+	if err != nil {
+		t.Fatalf("Offer: %v", err)
+	}
+	if offer.USDAmount.Cmp(big.NewInt(150e8)) != 0 {
+		t.Fatalf("offer USD amount = %s, want the 8-decimal amount the contract checks", offer.USDAmount)
+	}
+
+	hash, err := offer.Hash(chainID, contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := crypto.Sign(hash, clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature[crypto.RecoveryIDOffset] += 27 // as eth_signTypedData_v4 returns it
+
+	if err := VerifyEscrowOffer(offer, chainID, contract, signature, now); err != nil {
+		t.Fatalf("valid offer rejected: %v", err)
+	}
+
+	if err := VerifyEscrowOffer(offer, chainID, contract, signature, now.Add(time.Hour)); !errors.Is(err, ErrOfferExpired) {
+		t.Errorf("expired offer: err = %v, want ErrOfferExpired", err)
+	}
+
+	// The signature covers every term, the chain and the contract
+	changed := offer
+	changed.USDAmount = big.NewInt(140e8)
+	if err := VerifyEscrowOffer(changed, chainID, contract, signature, now); !errors.Is(err, ErrOfferSignerMismatch) {
+		t.Errorf("changed amount: err = %v, want ErrOfferSignerMismatch", err)
+	}
+	changed = offer
+	changed.Freelancer = common.HexToAddress("0x3333333333333333333333333333333333333333")
+	if err := VerifyEscrowOffer(changed, chainID, contract, signature, now); !errors.Is(err, ErrOfferSignerMismatch) {
+		t.Errorf("changed freelancer: err = %v, want ErrOfferSignerMismatch", err)
+	}
+	if err := VerifyEscrowOffer(offer, big.NewInt(1), contract, signature, now); !errors.Is(err, ErrOfferSignerMismatch) {
+		t.Errorf("other chain: err = %v, want ErrOfferSignerMismatch", err)
+	}
+
+	otherKey, _ := crypto.GenerateKey()
+	forged, _ := crypto.Sign(hash, otherKey)
+	if err := VerifyEscrowOffer(offer, chainID, contract, forged, now); !errors.Is(err, ErrOfferSignerMismatch) {
+		t.Errorf("signed by someone else: err = %v, want ErrOfferSignerMismatch", err)
+	}
+	if err := VerifyEscrowOffer(offer, chainID, contract, signature[:64], now); !errors.Is(err, ErrOfferSignatureFormat) {
+		t.Errorf("short signature: err = %v, want ErrOfferSignatureFormat", err)
+	}
+}
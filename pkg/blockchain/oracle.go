@@ -0,0 +1,264 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+
+	"github.com/fahedafzaal/go-integration/contracts"
+)
+
+// ErrPriceQuorum is returned, wrapped in a *QuorumError, when too few price sources answer
+var ErrPriceQuorum = errors.New("too few price sources answered")
+
+// PriceOracle is a source of the ETH/USD price with 8 decimals, as Chainlink reports it
+type PriceOracle interface {
+	Name() string
+	LatestPrice(ctx context.Context) (*big.Int, error)
+}
+
+// PriceQuote is an aggregated price and the sources it was taken from
+type PriceQuote struct {
+	Price    *big.Int          `json:"price"`              // 8 decimals
+	Sources  []string          `json:"sources"`            // sources whose answers were aggregated
+	Rejected map[string]string `json:"rejected,omitempty"` // sources that failed, with the reason
+}
+
+// QuorumError reports which sources failed when fewer than the quorum answered. It
+// matches ErrPriceQuorum and each source's error with errors.Is and errors.As.
+type QuorumError struct {
+	Answered int
+	Quorum   int
+	Failures map[string]error
+}
+
+func (e *QuorumError) Error() string {
+	names := make([]string, 0, len(e.Failures))
+	for name := range e.Failures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	reasons := make([]string, len(names))
+	for i, name := range names {
+		reasons[i] = fmt.Sprintf("%s: %v", name, e.Failures[name])
+	}
+	return fmt.Sprintf("%v: %d of %d required (%s)", ErrPriceQuorum, e.Answered, e.Quorum, strings.Join(reasons, "; "))
+}
+
+func (e *QuorumError) Unwrap() []error {
+	errs := []error{ErrPriceQuorum}
+	for _, err := range e.Failures {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// MedianOracle asks every source and reports the median of those that answer. Fewer than
+// quorum answers is an error, so one source cannot set the price alone unless configured to.
+type MedianOracle struct {
+	sources []PriceOracle
+	quorum  int
+}
+
+// NewMedianOracle aggregates sources; a quorum of 0 requires a majority of them
+func NewMedianOracle(quorum int, sources ...PriceOracle) *MedianOracle {
+	if quorum <= 0 {
+		quorum = len(sources)/2 + 1
+	}
+	return &MedianOracle{sources: sources, quorum: quorum}
+}
+
+func (m *MedianOracle) Name() string {
+	return "median"
+}
+
+// LatestPrice returns the median price
+func (m *MedianOracle) LatestPrice(ctx context.Context) (*big.Int, error) {
+	quote, err := m.Quote(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return quote.Price, nil
+}
+
+// Quote asks every source concurrently and returns the median of the answers
+func (m *MedianOracle) Quote(ctx context.Context) (*PriceQuote, error) {
+	prices := make([]*big.Int, len(m.sources))
+	errs := make([]error, len(m.sources))
+
+	var wg sync.WaitGroup
+	for i, source := range m.sources {
+		wg.Add(1)
+		go func(i int, source PriceOracle) {
+			defer wg.Done()
+			prices[i], errs[i] = source.LatestPrice(ctx)
+		}(i, source)
+	}
+	wg.Wait()
+
+	quote := &PriceQuote{}
+	var answers []*big.Int
+	failures := make(map[string]error)
+	for i, source := range m.sources {
+		if errs[i] != nil {
+			failures[source.Name()] = errs[i]
+			continue
+		}
+		answers = append(answers, prices[i])
+		quote.Sources = append(quote.Sources, source.Name())
+	}
+
+	if len(answers) < m.quorum || len(answers) == 0 {
+		return nil, &QuorumError{Answered: len(answers), Quorum: m.quorum, Failures: failures}
+	}
+	if len(failures) > 0 {
+		quote.Rejected = make(map[string]string, len(failures))
+		for name, err := range failures {
+			quote.Rejected[name] = err.Error()
+		}
+	}
+
+	quote.Price = median(answers)
+	return quote, nil
+}
+
+// median returns the middle answer, or the mean of the middle two rounded down
+func median(answers []*big.Int) *big.Int {
+	sorted := append([]*big.Int(nil), answers...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Cmp(sorted[j]) < 0 })
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return new(big.Int).Set(sorted[mid])
+	}
+	sum := new(big.Int).Add(sorted[mid-1], sorted[mid])
+	return sum.Div(sum, big.NewInt(2))
+}
+
+// ContractOracle reads the price the escrow contract converts deposits at. It reads the
+// Chainlink feed without PriceFeed's guards, so it is only a source when no feed is
+// configured.
+type ContractOracle struct {
+	contract *contracts.EthJobEscrowCaller
+}
+
+// NewContractOracle reads the price through the escrow contract's getLatestEthUsd
+func NewContractOracle(contract *contracts.EthJobEscrow) *ContractOracle {
+	return &ContractOracle{contract: &contract.EthJobEscrowCaller}
+}
+
+func (o *ContractOracle) Name() string {
+	return "contract"
+}
+
+func (o *ContractOracle) LatestPrice(ctx context.Context) (*big.Int, error) {
+	price, err := o.contract.GetLatestEthUsd(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("failed to call getLatestEthUsd: %w", err)
+	}
+	if price.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: %s", ErrPriceNonPositive, price)
+	}
+	return price, nil
+}
+
+// Name identifies the direct aggregator read among price sources
+func (f *PriceFeed) Name() string {
+	return "chainlink"
+}
+
+// HTTPPriceOracle reads a USD price from a JSON HTTP API, such as CoinGecko's
+// /simple/price?ids=ethereum&vs_currencies=usd, which answers {"ethereum":{"usd":2000.5}}
+type HTTPPriceOracle struct {
+	name       string
+	url        string
+	field      []string
+	httpClient *http.Client
+}
+
+// NewHTTPPriceOracle reads the price at field, a dot-separated path into the JSON
+// response such as ethereum.usd. The value may be a JSON number or a decimal string.
+func NewHTTPPriceOracle(rawURL, field string) (*HTTPPriceOracle, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid price source URL %q", rawURL)
+	}
+	if field == "" {
+		return nil, fmt.Errorf("price source field is required")
+	}
+	return &HTTPPriceOracle{
+		name:       "http:" + parsed.Host,
+		url:        rawURL,
+		field:      strings.Split(field, "."),
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}, nil
+}
+
+func (o *HTTPPriceOracle) Name() string {
+	return o.name
+}
+
+func (o *HTTPPriceOracle) LatestPrice(ctx context.Context) (*big.Int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request failed with status %d", resp.StatusCode)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	for _, key := range o.field {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("response has no field %s", strings.Join(o.field, "."))
+		}
+		value = object[key]
+	}
+
+	var text string
+	switch v := value.(type) {
+	case json.Number:
+		text = v.String()
+	case string:
+		text = v
+	default:
+		return nil, fmt.Errorf("response field %s is not a number", strings.Join(o.field, "."))
+	}
+
+	// Parsed exactly; digits past the 8th decimal are truncated, as Chainlink drops them
+	usd, ok := new(big.Rat).SetString(text)
+	if !ok || strings.Contains(text, "/") {
+		return nil, fmt.Errorf("invalid price %q", text)
+	}
+	usd.Mul(usd, big.NewRat(100_000_000, 1))
+	priceE8 := new(big.Int).Quo(usd.Num(), usd.Denom())
+	if priceE8.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: %s", ErrPriceNonPositive, text)
+	}
+	return priceE8, nil
+}
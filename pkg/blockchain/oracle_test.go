@@ -0,0 +1,202 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/fahedafzaal/go-integration/internal/config"
+	"github.com/fahedafzaal/go-integration/pkg/money"
+)
+
+// fixedOracle answers with a fixed price or error
+type fixedOracle struct {
+	name  string
+	price int64
+	err   error
+}
+
+func (o fixedOracle) Name() string {
+	return o.name
+}
+
+func (o fixedOracle) LatestPrice(ctx context.Context) (*big.Int, error) {
+	if o.err != nil {
+		return nil, o.err
+	}
+	return big.NewInt(o.price), nil
+}
+
+func TestMedianOracle(t *testing.T) {
+	down := errors.New("source down")
+	a := fixedOracle{name: "a", price: 2000_00000000}
+	b := fixedOracle{name: "b", price: 2010_00000000}
+	c := fixedOracle{name: "c", price: 3000_00000000}
+	failing := fixedOracle{name: "failing", err: down}
+
+	tests := []struct {
+		name        string
+		oracle      *MedianOracle
+		wantPrice   int64
+		wantSources []string
+		wantErr     error
+	}{
+		{"odd count takes the middle", NewMedianOracle(0, c, a, b), 2010_00000000, []string{"c", "a", "b"}, nil},
+		{"even count takes the mean", NewMedianOracle(0, a, b), 2005_00000000, []string{"a", "b"}, nil},
+		{"failure within quorum", NewMedianOracle(0, a, failing, c), 2500_00000000, []string{"a", "c"}, nil},
+		{"majority quorum", NewMedianOracle(0, a, failing, fixedOracle{name: "other", err: down}), 0, nil, down},
+		{"explicit quorum", NewMedianOracle(3, a, b, failing), 0, nil, ErrPriceQuorum},
+		{"single source", NewMedianOracle(0, a), 2000_00000000, []string{"a"}, nil},
+		{"no sources", NewMedianOracle(0), 0, nil, ErrPriceQuorum},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, err := tt.oracle.Quote(context.Background())
+			if tt.wantErr != nil {
+				var quorumErr *QuorumError
+				if !errors.Is(err, tt.wantErr) || !errors.Is(err, ErrPriceQuorum) || !errors.As(err, &quorumErr) {
+					t.Fatalf("Quote error = %v, want a QuorumError matching %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Quote: %v", err)
+			}
+			if quote.Price.Int64() != tt.wantPrice || !reflect.DeepEqual(quote.Sources, tt.wantSources) {
+				t.Errorf("Quote = %s from %v, want %d from %v", quote.Price, quote.Sources, tt.wantPrice, tt.wantSources)
+			}
+		})
+	}
+}
+
+func TestHTTPPriceOracle(t *testing.T) {
+	var body atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := body.Load().(string)
+		if response == "" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	oracle, err := NewHTTPPriceOracle(server.URL+"/simple/price?ids=ethereum&vs_currencies=usd", "ethereum.usd")
+	if err != nil {
+		t.Fatalf("NewHTTPPriceOracle: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		want    int64
+		wantErr bool
+	}{
+		{"number", `{"ethereum":{"usd":2000.12345678}}`, 2000_12345678, false},
+		{"string", `{"ethereum":{"usd":"1999.5"}}`, 1999_50000000, false},
+		{"past 8 decimals", `{"ethereum":{"usd":"2000.123456789"}}`, 2000_12345678, false},
+		{"exponent", `{"ethereum":{"usd":2.00012345678e3}}`, 2000_12345678, false},
+		{"not exact in binary", `{"ethereum":{"usd":0.29}}`, 29000000, false},
+		{"fraction", `{"ethereum":{"usd":"4001/2"}}`, 0, true},
+		{"below 8 decimals", `{"ethereum":{"usd":"0.000000001"}}`, 0, true},
+		{"zero", `{"ethereum":{"usd":0}}`, 0, true},
+		{"missing field", `{"bitcoin":{"usd":60000}}`, 0, true},
+		{"not an object", `[2000]`, 0, true},
+		{"server error", "", 0, true},
+	}
+	for _, tt := range tests {
+		body.Store(tt.body)
+		price, err := oracle.LatestPrice(context.Background())
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: LatestPrice = %s, want an error", tt.name, price)
+			}
+			continue
+		}
+		if err != nil || price.Int64() != tt.want {
+			t.Errorf("%s: LatestPrice = %v, %v; want %d", tt.name, price, err, tt.want)
+		}
+	}
+}
+
+func TestPriceQuoteSources(t *testing.T) {
+	t.Parallel()
+	env := newEscrowEnv(t)
+
+	var up atomic.Bool
+	up.Store(true)
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"ethereum":{"usd":2100}}`))
+	}))
+	defer stub.Close()
+
+	cfg := &config.Config{
+		ContractAddress: env.escrow.Hex(),
+		ETHUSDPriceFeed: env.feed.Address.Hex(),
+		PriceHTTPURL:    stub.URL,
+		PriceHTTPField:  "ethereum.usd",
+		PriceQuorum:     1,
+		GasLimit:        300000,
+	}
+	client, err := NewClientWithBackend(cfg, env.eth, NewFakeSigner())
+	if err != nil {
+		t.Fatalf("NewClientWithBackend: %v", err)
+	}
+	service := NewPaymentGatewayServiceWithClient(client)
+
+	// The contract reads the feed's aggregator, so only the guarded feed read is a source
+	resp, err := service.GetRequiredETH(env.ctx, money.MustParseUSD("100"))
+	if err != nil {
+		t.Fatalf("GetRequiredETH: %v", err)
+	}
+	if want := []string{"chainlink", "http:" + stub.Listener.Addr().String()}; !reflect.DeepEqual(resp.PriceSources, want) {
+		t.Errorf("price sources = %v, want %v", resp.PriceSources, want)
+	}
+	if resp.Error != "ETH_PRICE:2050.00" {
+		t.Errorf("required ETH priced at %s, want the median 2050.00", resp.Error)
+	}
+
+	// With a quorum of one the feed answers alone
+	up.Store(false)
+	quote, err := client.PriceQuote(env.ctx)
+	if err != nil {
+		t.Fatalf("PriceQuote with the HTTP source down: %v", err)
+	}
+	if !reflect.DeepEqual(quote.Sources, []string{"chainlink"}) || len(quote.Rejected) != 1 {
+		t.Errorf("quote sources = %v, rejected %v; want the feed and the HTTP source rejected", quote.Sources, quote.Rejected)
+	}
+	if quote.Price.Cmp(ethUSDPrice) != 0 {
+		t.Errorf("quote price = %s, want %s", quote.Price, ethUSDPrice)
+	}
+
+	// By default the feed cannot outvote the HTTP source on its own
+	cfg.PriceQuorum = 0
+	client, err = NewClientWithBackend(cfg, env.eth, NewFakeSigner())
+	if err != nil {
+		t.Fatalf("NewClientWithBackend: %v", err)
+	}
+	if _, err := client.PriceQuote(env.ctx); !errors.Is(err, ErrPriceQuorum) {
+		t.Errorf("PriceQuote with a majority quorum and the HTTP source down = %v, want ErrPriceQuorum", err)
+	}
+
+	// Without a feed the contract is the on-chain source
+	cfg.ETHUSDPriceFeed, cfg.PriceHTTPURL = "", ""
+	client, err = NewClientWithBackend(cfg, env.eth, NewFakeSigner())
+	if err != nil {
+		t.Fatalf("NewClientWithBackend: %v", err)
+	}
+	quote, err = client.PriceQuote(env.ctx)
+	if err != nil || !reflect.DeepEqual(quote.Sources, []string{"contract"}) {
+		t.Errorf("PriceQuote without a feed = %v, %v; want the contract", quote, err)
+	}
+}
@@ -0,0 +1,152 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/fahedafzaal/go-integration/contracts"
+)
+
+// Reasons a price feed answer is rejected. They are returned wrapped in a *PriceFeedError.
+var (
+	ErrPriceNonPositive     = errors.New("price feed answer is zero or negative")
+	ErrPriceStale           = errors.New("price feed answer is older than the feed heartbeat")
+	ErrPriceIncompleteRound = errors.New("price feed round is incomplete")
+	ErrPriceDeviation       = errors.New("price feed answer moved too far from the last accepted price")
+)
+
+// PriceFeedError is a rejected Chainlink answer. Err is one of the ErrPrice* sentinels,
+// so callers can match it with errors.Is.
+type PriceFeedError struct {
+	Err     error
+	RoundID *big.Int
+	Answer  *big.Int
+	Detail  string
+}
+
+func (e *PriceFeedError) Error() string {
+	return fmt.Sprintf("price feed round %s rejected: %v (answer %s, %s)", e.RoundID, e.Err, e.Answer, e.Detail)
+}
+
+func (e *PriceFeedError) Unwrap() error {
+	return e.Err
+}
+
+// RoundData is a Chainlink aggregator round as latestRoundData returns it
+type RoundData struct {
+	RoundID         *big.Int
+	Answer          *big.Int
+	StartedAt       *big.Int
+	UpdatedAt       *big.Int
+	AnsweredInRound *big.Int
+}
+
+// PriceFeedConfig holds price feed guard settings
+type PriceFeedConfig struct {
+	Heartbeat       time.Duration // Optional, defaults to 1 hour; older answers are stale
+	MaxDeviationBps int64         // Optional, defaults to 1000 (10%) from the last accepted price
+}
+
+// PriceFeed reads a Chainlink ETH/USD aggregator and rejects answers that are stale,
+// from an incomplete round, not positive, or too far from the last accepted answer. An
+// answer is compared with the last accepted one only while that is within a heartbeat,
+// so a genuine move past the deviation limit is accepted once the old price has aged out.
+type PriceFeed struct {
+	feed         *contracts.AggregatorV3InterfaceCaller
+	eth          EthBackend
+	heartbeat    time.Duration
+	maxDeviation int64
+
+	mu         sync.Mutex
+	last       *big.Int
+	lastUpdate time.Time
+}
+
+// NewPriceFeed binds the aggregator at address
+func NewPriceFeed(address common.Address, eth EthBackend, cfg PriceFeedConfig) (*PriceFeed, error) {
+	if cfg.Heartbeat <= 0 {
+		cfg.Heartbeat = time.Hour
+	}
+	if cfg.MaxDeviationBps <= 0 {
+		cfg.MaxDeviationBps = 1000
+	}
+
+	feed, err := contracts.NewAggregatorV3InterfaceCaller(address, eth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind price feed: %w", err)
+	}
+	return &PriceFeed{
+		feed:         feed,
+		eth:          eth,
+		heartbeat:    cfg.Heartbeat,
+		maxDeviation: cfg.MaxDeviationBps,
+	}, nil
+}
+
+// LatestPrice returns the latest answer, 8 decimals for ETH/USD, if it passes the guards.
+// Staleness is measured against the latest block's time rather than the local clock.
+func (f *PriceFeed) LatestPrice(ctx context.Context) (*big.Int, error) {
+	round, err := f.feed.LatestRoundData(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read latestRoundData: %w", err)
+	}
+
+	head, err := f.eth.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest block: %w", err)
+	}
+
+	return f.accept(RoundData{
+		RoundID:         round.RoundId,
+		Answer:          round.Answer,
+		StartedAt:       round.StartedAt,
+		UpdatedAt:       round.UpdatedAt,
+		AnsweredInRound: round.AnsweredInRound,
+	}, time.Unix(int64(head.Time), 0))
+}
+
+// accept checks round as of now and records it as the last accepted answer
+func (f *PriceFeed) accept(round RoundData, now time.Time) (*big.Int, error) {
+	reject := func(reason error, detail string) (*big.Int, error) {
+		return nil, &PriceFeedError{Err: reason, RoundID: round.RoundID, Answer: round.Answer, Detail: detail}
+	}
+
+	if round.Answer == nil || round.Answer.Sign() <= 0 {
+		return reject(ErrPriceNonPositive, "the feed reports no usable price")
+	}
+	if round.UpdatedAt == nil || round.UpdatedAt.Sign() == 0 {
+		return reject(ErrPriceIncompleteRound, "round has no update time")
+	}
+	if round.AnsweredInRound == nil || round.AnsweredInRound.Cmp(round.RoundID) < 0 {
+		return reject(ErrPriceIncompleteRound, fmt.Sprintf("answered in round %s", round.AnsweredInRound))
+	}
+
+	updated := time.Unix(round.UpdatedAt.Int64(), 0)
+	if age := now.Sub(updated); age > f.heartbeat {
+		return reject(ErrPriceStale, fmt.Sprintf("updated %s ago, heartbeat %s", age.Truncate(time.Second), f.heartbeat))
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.last != nil && now.Sub(f.lastUpdate) <= f.heartbeat {
+		// |answer - last| / last > maxDeviation / 10000
+		delta := new(big.Int).Sub(round.Answer, f.last)
+		delta.Abs(delta).Mul(delta, big.NewInt(10000))
+		limit := new(big.Int).Mul(f.last, big.NewInt(f.maxDeviation))
+		if delta.Cmp(limit) > 0 {
+			return reject(ErrPriceDeviation, fmt.Sprintf("last accepted %s, limit %d bps", f.last, f.maxDeviation))
+		}
+	}
+
+	f.last = new(big.Int).Set(round.Answer)
+	f.lastUpdate = updated
+	return new(big.Int).Set(round.Answer), nil
+}
@@ -0,0 +1,131 @@
+package blockchain
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/fahedafzaal/go-integration/pkg/money"
+)
+
+func TestPriceFeedAccept(t *testing.T) {
+	now := time.Unix(1750000000, 0)
+	round := func(id, answer int64, updated time.Time, answeredIn int64) RoundData {
+		return RoundData{
+			RoundID:         big.NewInt(id),
+			Answer:          big.NewInt(answer),
+			StartedAt:       big.NewInt(updated.Unix()),
+			UpdatedAt:       big.NewInt(updated.Unix()),
+			AnsweredInRound: big.NewInt(answeredIn),
+		}
+	}
+
+	tests := []struct {
+		name  string
+		round RoundData
+		want  error
+	}{
+		{"fresh", round(7, 2000_00000000, now.Add(-10*time.Minute), 7), nil},
+		{"zero answer", round(7, 0, now, 7), ErrPriceNonPositive},
+		{"negative answer", round(7, -1, now, 7), ErrPriceNonPositive},
+		{"never updated", round(7, 2000_00000000, time.Unix(0, 0), 7), ErrPriceIncompleteRound},
+		{"answered in an earlier round", round(7, 2000_00000000, now, 6), ErrPriceIncompleteRound},
+		{"older than the heartbeat", round(7, 2000_00000000, now.Add(-61*time.Minute), 7), ErrPriceStale},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed := &PriceFeed{heartbeat: time.Hour, maxDeviation: 1000}
+			price, err := feed.accept(tt.round, now)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("accept error = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil {
+				if price.Cmp(tt.round.Answer) != 0 {
+					t.Errorf("price = %s, want %s", price, tt.round.Answer)
+				}
+				return
+			}
+			var feedErr *PriceFeedError
+			if !errors.As(err, &feedErr) || feedErr.RoundID.Cmp(tt.round.RoundID) != 0 {
+				t.Errorf("error %v is not a *PriceFeedError for round %s", err, tt.round.RoundID)
+			}
+		})
+	}
+}
+
+func TestPriceFeedDeviation(t *testing.T) {
+	now := time.Unix(1750000000, 0)
+	feed := &PriceFeed{heartbeat: time.Hour, maxDeviation: 1000}
+	accept := func(id, answer int64, updated time.Time) error {
+		_, err := feed.accept(RoundData{
+			RoundID:         big.NewInt(id),
+			Answer:          big.NewInt(answer),
+			StartedAt:       big.NewInt(updated.Unix()),
+			UpdatedAt:       big.NewInt(updated.Unix()),
+			AnsweredInRound: big.NewInt(id),
+		}, now)
+		return err
+	}
+
+	if err := accept(1, 2000_00000000, now.Add(-50*time.Minute)); err != nil {
+		t.Fatalf("first answer: %v", err)
+	}
+	if err := accept(2, 2200_00000000, now.Add(-40*time.Minute)); err != nil {
+		t.Errorf("10%% move rejected: %v", err)
+	}
+	if err := accept(3, 1900_00000000, now.Add(-30*time.Minute)); !errors.Is(err, ErrPriceDeviation) {
+		t.Errorf("13.6%% move: err = %v, want ErrPriceDeviation", err)
+	}
+
+	// Once the last accepted answer is older than a heartbeat the new level is accepted
+	now = now.Add(25 * time.Minute)
+	if err := accept(3, 1900_00000000, now.Add(-5*time.Minute)); err != nil {
+		t.Errorf("move after the last answer aged out rejected: %v", err)
+	}
+}
+
+func TestPriceFeedGuardsOnChain(t *testing.T) {
+	t.Parallel()
+	env := newEscrowEnv(t)
+
+	price, err := env.gateway.GetETHUSDPrice(env.ctx)
+	if err != nil || price.Cmp(ethUSDPrice) != 0 {
+		t.Fatalf("GetETHUSDPrice = %v, %v; want %s", price, err, ethUSDPrice)
+	}
+
+	// A jump beyond the deviation limit is refused
+	update, err := env.feed.UpdateAnswer(env.deployer, new(big.Int).Mul(ethUSDPrice, big.NewInt(2)))
+	if err != nil {
+		t.Fatalf("UpdateAnswer: %v", err)
+	}
+	env.waitMined(update)
+	if _, err := env.gateway.GetETHUSDPrice(env.ctx); !errors.Is(err, ErrPriceDeviation) {
+		t.Errorf("doubled price: err = %v, want ErrPriceDeviation", err)
+	}
+
+	head, err := env.eth.HeaderByNumber(env.ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := new(big.Int).SetUint64(head.Time - 2*3600)
+	update, err = env.feed.UpdateRoundData(env.deployer, big.NewInt(9), ethUSDPrice, stale, stale)
+	if err != nil {
+		t.Fatalf("UpdateRoundData: %v", err)
+	}
+	env.waitMined(update)
+	if _, err := env.gateway.GetETHUSDPrice(env.ctx); !errors.Is(err, ErrPriceStale) {
+		t.Errorf("two hour old answer: err = %v, want ErrPriceStale", err)
+	}
+
+	// A zero answer is an error, not a division by zero
+	now := new(big.Int).SetUint64(head.Time)
+	update, err = env.feed.UpdateRoundData(env.deployer, big.NewInt(10), big.NewInt(0), now, now)
+	if err != nil {
+		t.Fatalf("UpdateRoundData: %v", err)
+	}
+	env.waitMined(update)
+	if _, err := env.service().CalculateRequiredETH(env.ctx, money.MustParseUSD("100")); !errors.Is(err, ErrPriceNonPositive) {
+		t.Errorf("zero answer: CalculateRequiredETH err = %v, want ErrPriceNonPositive", err)
+	}
+}
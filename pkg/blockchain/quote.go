@@ -0,0 +1,97 @@
+package blockchain
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/fahedafzaal/go-integration/pkg/money"
+)
+
+var (
+	// ErrQuoteExpired is returned for a deposit made after its price quote expired
+	ErrQuoteExpired = errors.New("price quote has expired")
+	// ErrQuoteMismatch is returned for a price quote issued for other terms than the deposit's
+	ErrQuoteMismatch = errors.New("price quote does not match the escrow terms")
+)
+
+// Quote locks the ETH a deposit of USDAmountE8 for JobID must carry until ExpiresAt, so a
+// price move between quoting and the deposit being mined cannot fail its verification
+type Quote struct {
+	ID            string
+	JobID         uint64
+	USDAmountE8   *big.Int
+	RequiredWei   *big.Int
+	EthUSDPriceE8 *big.Int
+	PriceSources  []string
+	ExpiresAt     time.Time
+	RedeemedBy    string // deposit transaction the quote was redeemed for once found valid
+}
+
+// NewQuote converts usdAmount at the current price and locks the amount for ttl
+func (s *PaymentGatewayService) NewQuote(ctx context.Context, jobID uint64, usdAmount money.USD, ttl time.Duration) (*Quote, error) {
+	requiredEth, price, err := s.QuoteRequiredETH(ctx, usdAmount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate required ETH: %w", err)
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate quote ID: %w", err)
+	}
+
+	return &Quote{
+		ID:            hex.EncodeToString(id),
+		JobID:         jobID,
+		USDAmountE8:   usdAmount.E8(),
+		RequiredWei:   requiredEth,
+		EthUSDPriceE8: price.Price,
+		PriceSources:  price.Sources,
+		ExpiresAt:     time.Now().Add(ttl).Truncate(time.Second),
+	}, nil
+}
+
+// covers returns an error unless the quote was issued for req's job and amount
+func (q *Quote) covers(req PostJobRequest) error {
+	offer, err := req.Offer()
+	if err != nil {
+		return err
+	}
+	if q.JobID != offer.JobID || q.USDAmountE8.Cmp(offer.USDAmount) != 0 {
+		return fmt.Errorf("%w: quote %s is for job %d, %s USD E8", ErrQuoteMismatch, q.ID, q.JobID, q.USDAmountE8)
+	}
+	return nil
+}
+
+// validAt returns ErrQuoteExpired if the quote had expired by t
+func (q *Quote) validAt(t time.Time) error {
+	if t.After(q.ExpiresAt) {
+		return fmt.Errorf("%w: quote %s expired at %s", ErrQuoteExpired, q.ID, q.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// requiredETH is the ETH a deposit for req must carry: the amount its quote locked, or
+// the agreed amount converted at the current price when it has none. Whether the quote
+// is still valid depends on when the deposit was made, so callers check that.
+func (s *PaymentGatewayService) requiredETH(ctx context.Context, req PostJobRequest) (*big.Int, error) {
+	if req.Quote != nil {
+		if err := req.Quote.covers(req); err != nil {
+			return nil, err
+		}
+		return req.Quote.RequiredWei, nil
+	}
+	if req.QuoteID != "" {
+		return nil, fmt.Errorf("price quote %s was not loaded", req.QuoteID)
+	}
+
+	requiredEth, err := s.CalculateRequiredETH(ctx, req.USDAmount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate required ETH: %w", err)
+	}
+	return requiredEth, nil
+}
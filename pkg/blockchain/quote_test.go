@@ -0,0 +1,90 @@
+package blockchain
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/fahedafzaal/go-integration/pkg/money"
+)
+
+func TestQuoteLocksDepositAmount(t *testing.T) {
+	t.Parallel()
+	env := newEscrowEnv(t)
+	const jobID = 505
+	service := env.service()
+
+	clientWallet := env.stranger.signer
+	req := PostJobRequest{
+		JobID:             jobID,
+		FreelancerAddress: env.freelancer.Hex(),
+		USDAmount:         money.MustParseUSD("250"),
+		ClientAddress:     clientWallet.Address().Hex(),
+	}
+
+	quote, err := service.NewQuote(env.ctx, jobID, req.USDAmount, time.Hour)
+	if err != nil {
+		t.Fatalf("NewQuote: %v", err)
+	}
+	if want := big.NewInt(125e15); quote.RequiredWei.Cmp(want) != 0 || quote.EthUSDPriceE8.Cmp(ethUSDPrice) != 0 {
+		t.Fatalf("quote = %s wei at %s, want %s at %s", quote.RequiredWei, quote.EthUSDPriceE8, want, ethUSDPrice)
+	}
+	req.QuoteID = quote.ID
+	req.Quote = quote
+
+	// The price rises 5% between quoting and the deposit
+	update, err := env.feed.UpdateAnswer(env.deployer, big.NewInt(2100_00000000))
+	if err != nil {
+		t.Fatalf("UpdateAnswer: %v", err)
+	}
+	env.waitMined(update)
+
+	unsigned, err := service.BuildPostJobTx(env.ctx, req)
+	if err != nil {
+		t.Fatalf("BuildPostJobTx: %v", err)
+	}
+	if unsigned.Value.ToInt().Cmp(quote.RequiredWei) != 0 {
+		t.Fatalf("value = %s, want the quoted %s", unsigned.Value.ToInt(), quote.RequiredWei)
+	}
+	signed, err := clientWallet.SignTx(env.ctx, unsigned.Transaction(), unsigned.ChainID.ToInt())
+	if err != nil {
+		t.Fatalf("SignTx: %v", err)
+	}
+	if err := env.eth.SendTransaction(env.ctx, signed); err != nil {
+		t.Fatalf("SendTransaction: %v", err)
+	}
+	env.waitMined(signed)
+	req.ClientTxHash = signed.Hash().Hex()
+
+	if err := service.VerifyClientDeposit(env.ctx, req); err != nil {
+		t.Errorf("deposit at the quoted amount rejected: %v", err)
+	}
+
+	// Checked against the current price, the same deposit is off by more than 1%
+	unquoted := req
+	unquoted.QuoteID, unquoted.Quote = "", nil
+	if err := service.VerifyClientDeposit(env.ctx, unquoted); err == nil {
+		t.Error("deposit verified against the moved price, want a value mismatch")
+	}
+
+	expired := *quote
+	expired.ExpiresAt = time.Now().Add(-time.Hour)
+	req.Quote = &expired
+	if err := service.VerifyClientDeposit(env.ctx, req); !errors.Is(err, ErrQuoteExpired) {
+		t.Errorf("deposit mined after the quote expired: err = %v, want ErrQuoteExpired", err)
+	}
+
+	// A quote redeemed for the deposit was checked when the gateway accepted it
+	expired.RedeemedBy = req.ClientTxHash
+	if err := service.VerifyClientDeposit(env.ctx, req); err != nil {
+		t.Errorf("deposit with its redeemed quote rejected: %v", err)
+	}
+
+	other := *quote
+	other.JobID = jobID + 1
+	req.Quote = &other
+	if err := service.VerifyClientDeposit(env.ctx, req); !errors.Is(err, ErrQuoteMismatch) {
+		t.Errorf("quote for another job: err = %v, want ErrQuoteMismatch", err)
+	}
+}
@@ -0,0 +1,313 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Replacement kinds recorded against an application
+const (
+	ReplacementSpeedUp = "speed_up" // same call re-signed with higher fees
+	ReplacementCancel  = "cancel"   // zero-value self-send that voids the nonce
+)
+
+// minFeeBumpPercent is the smallest fee increase nodes accept for a same-nonce replacement
+const minFeeBumpPercent = 10
+
+var (
+	// ErrFeeCeilingReached is returned when a replacement would exceed the configured max fee
+	ErrFeeCeilingReached = errors.New("replacement fee would exceed the configured ceiling")
+	// ErrTransactionReplaced is returned when a transaction's nonce was consumed by a
+	// transaction the waiter was not tracking, such as a cancel sent by an operator
+	ErrTransactionReplaced = errors.New("transaction nonce was used by another transaction")
+)
+
+// ReplacementRecorder persists replacement transaction hashes against the application
+// (escrow job) they belong to
+type ReplacementRecorder interface {
+	RecordTxReplacement(ctx context.Context, applicationID int32, originalTxHash, replacementTxHash, kind string) error
+}
+
+// SetReplacementRecorder records every replacement the client broadcasts
+func (c *Client) SetReplacementRecorder(recorder ReplacementRecorder) {
+	c.replacements = recorder
+}
+
+// SpeedUpTransaction re-signs a pending transaction under the same nonce with bumped fees
+func (c *Client) SpeedUpTransaction(ctx context.Context, jobID uint64, original common.Hash, pending *types.Transaction) (*types.Transaction, error) {
+	return c.replaceTransaction(ctx, jobID, original, pending, ReplacementSpeedUp)
+}
+
+// CancelTransaction voids a pending transaction by spending its nonce on a zero-value
+// self-send with bumped fees
+func (c *Client) CancelTransaction(ctx context.Context, jobID uint64, original common.Hash, pending *types.Transaction) (*types.Transaction, error) {
+	return c.replaceTransaction(ctx, jobID, original, pending, ReplacementCancel)
+}
+
+// replaceTransaction builds, signs and broadcasts a replacement for pending, which is
+// either the original transaction or its latest replacement
+func (c *Client) replaceTransaction(ctx context.Context, jobID uint64, original common.Hash, pending *types.Transaction, kind string) (*types.Transaction, error) {
+	chainID, err := c.ethClient.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain ID: %w", err)
+	}
+
+	to, value, data, gas := pending.To(), pending.Value(), pending.Data(), pending.Gas()
+	if kind == ReplacementCancel {
+		to, value, data, gas = &c.publicAddress, big.NewInt(0), nil, params.TxGas
+	}
+
+	var replacement *types.Transaction
+	if pending.Type() == types.DynamicFeeTxType {
+		tipCap, feeCap, err := c.bumpDynamicFees(ctx, pending)
+		if err != nil {
+			return nil, err
+		}
+
+		replacement = types.NewTx(&types.DynamicFeeTx{
+			ChainID:   chainID,
+			Nonce:     pending.Nonce(),
+			GasTipCap: tipCap,
+			GasFeeCap: feeCap,
+			Gas:       gas,
+			To:        to,
+			Value:     value,
+			Data:      data,
+		})
+	} else {
+		gasPrice, err := c.bumpLegacyGasPrice(ctx, pending)
+		if err != nil {
+			return nil, err
+		}
+
+		replacement = types.NewTx(&types.LegacyTx{
+			Nonce:    pending.Nonce(),
+			GasPrice: gasPrice,
+			Gas:      gas,
+			To:       to,
+			Value:    value,
+			Data:     data,
+		})
+	}
+
+	signed, err := c.signer.SignTx(ctx, replacement, chainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign replacement: %w", err)
+	}
+
+	if err := c.ethClient.SendTransaction(ctx, signed); err != nil {
+		return nil, fmt.Errorf("failed to broadcast replacement: %w", err)
+	}
+
+	log.Printf("Replaced transaction %s with %s (%s, nonce %d)", pending.Hash().Hex(), signed.Hash().Hex(), kind, signed.Nonce())
+
+	c.nonces.MarkSent(ctx, c.publicAddress, signed.Nonce(), signed.Hash())
+
+	if c.replacements != nil {
+		if err := c.replacements.RecordTxReplacement(ctx, int32(jobID), original.Hex(), signed.Hash().Hex(), kind); err != nil {
+			log.Printf("Warning: Failed to record replacement %s for application %d: %v", signed.Hash().Hex(), jobID, err)
+		}
+	}
+
+	return signed, nil
+}
+
+// bumpDynamicFees returns EIP-1559 fees at least minFeeBumpPercent above pending's,
+// following the current network suggestion when it is higher
+func (c *Client) bumpDynamicFees(ctx context.Context, pending *types.Transaction) (*big.Int, *big.Int, error) {
+	header, err := c.ethClient.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get latest block: %w", err)
+	}
+
+	suggestedTip, err := c.ethClient.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to suggest gas tip cap: %w", err)
+	}
+
+	tipCap := maxBig(c.bumpFee(pending.GasTipCap()), suggestedTip)
+
+	feeCap := c.bumpFee(pending.GasFeeCap())
+	if header.BaseFee != nil {
+		feeCap = maxBig(feeCap, new(big.Int).Add(new(big.Int).Mul(header.BaseFee, big.NewInt(2)), tipCap))
+	}
+
+	// The network suggestion may be clamped to the ceiling, the minimum bump may not
+	ceiling := c.maxFeeCap()
+	if c.bumpFee(pending.GasFeeCap()).Cmp(ceiling) > 0 || c.bumpFee(pending.GasTipCap()).Cmp(ceiling) > 0 {
+		return nil, nil, ErrFeeCeilingReached
+	}
+	if feeCap.Cmp(ceiling) > 0 {
+		feeCap = ceiling
+	}
+	if tipCap.Cmp(feeCap) > 0 {
+		tipCap = new(big.Int).Set(feeCap)
+	}
+
+	return tipCap, feeCap, nil
+}
+
+// bumpLegacyGasPrice returns a gas price at least minFeeBumpPercent above pending's
+func (c *Client) bumpLegacyGasPrice(ctx context.Context, pending *types.Transaction) (*big.Int, error) {
+	suggested, err := c.ethClient.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest gas price: %w", err)
+	}
+
+	bumped := c.bumpFee(pending.GasPrice())
+	ceiling := c.maxFeeCap()
+	if bumped.Cmp(ceiling) > 0 {
+		return nil, ErrFeeCeilingReached
+	}
+
+	// Same 10% buffer over the suggestion as newTransactor
+	buffered := new(big.Int).Mul(suggested, big.NewInt(110))
+	buffered.Div(buffered, big.NewInt(100))
+
+	gasPrice := maxBig(bumped, buffered)
+	if gasPrice.Cmp(ceiling) > 0 {
+		gasPrice = ceiling
+	}
+	return gasPrice, nil
+}
+
+// bumpFee raises fee by the configured percentage, rounding up so the result always
+// clears the replacement threshold
+func (c *Client) bumpFee(fee *big.Int) *big.Int {
+	percent := int64(c.config.FeeBumpPercent)
+	if percent < minFeeBumpPercent {
+		percent = minFeeBumpPercent
+	}
+
+	bumped := new(big.Int).Mul(fee, big.NewInt(100+percent))
+	bumped.Add(bumped, big.NewInt(99))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+// maxFeeCap returns the fee ceiling in wei
+func (c *Client) maxFeeCap() *big.Int {
+	gwei := c.config.MaxFeeCapGwei
+	if gwei <= 0 {
+		gwei = 500
+	}
+	return new(big.Int).Mul(big.NewInt(gwei), big.NewInt(params.GWei))
+}
+
+// ReplaceAfter returns how long a transaction may stay unmined before it is replaced
+func (c *Client) ReplaceAfter() time.Duration {
+	if c.config.TxReplaceAfter <= 0 {
+		return 60 * time.Second
+	}
+	return time.Duration(c.config.TxReplaceAfter) * time.Second
+}
+
+// waitMinedAny polls until one of the candidate transactions (all sharing a nonce) is
+// mined, or until the nonce is consumed by a transaction outside the candidates
+func (c *Client) waitMinedAny(ctx context.Context, candidates []*types.Transaction) (*types.Receipt, *types.Transaction, error) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	hashes := make([]common.Hash, len(candidates))
+	for i, candidate := range candidates {
+		hashes[i] = candidate.Hash()
+	}
+
+	for {
+		receipt, err := c.FindMinedTransaction(ctx, candidates[0].Nonce(), hashes)
+		if err != nil {
+			return nil, nil, err
+		}
+		if receipt != nil {
+			for _, candidate := range candidates {
+				if candidate.Hash() == receipt.TxHash {
+					return receipt, candidate, nil
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// FindMinedTransaction returns the receipt of whichever of hashes (all sent with nonce) has
+// been mined. It returns nil while all of them are pending, and ErrTransactionReplaced when
+// the nonce was consumed by some other transaction.
+func (c *Client) FindMinedTransaction(ctx context.Context, nonce uint64, hashes []common.Hash) (*types.Receipt, error) {
+	return c.FindMinedTransactionFrom(ctx, c.publicAddress, nonce, hashes)
+}
+
+// FindMinedTransactionFrom is FindMinedTransaction for transactions sent by from rather
+// than the gateway wallet
+func (c *Client) FindMinedTransactionFrom(ctx context.Context, from common.Address, nonce uint64, hashes []common.Hash) (*types.Receipt, error) {
+	receipt, err := c.findReceipt(ctx, hashes)
+	if err != nil || receipt != nil {
+		return receipt, err
+	}
+
+	consumed, err := c.ethClient.NonceAt(ctx, from, nil)
+	if err != nil || consumed <= nonce {
+		// Lookup failures are treated as still pending; the caller polls again
+		return nil, nil
+	}
+
+	// Check once more in case a candidate was mined between the two lookups
+	receipt, err = c.findReceipt(ctx, hashes)
+	if err != nil || receipt != nil {
+		return receipt, err
+	}
+	return nil, ErrTransactionReplaced
+}
+
+// findReceipt returns the receipt of whichever hash has been mined, if any
+func (c *Client) findReceipt(ctx context.Context, hashes []common.Hash) (*types.Receipt, error) {
+	for _, hash := range hashes {
+		receipt, err := c.ethClient.TransactionReceipt(ctx, hash)
+		if err == nil {
+			return receipt, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+	return nil, nil
+}
+
+// PendingTransaction returns a transaction sent by the gateway that is still waiting in the mempool
+func (c *Client) PendingTransaction(ctx context.Context, txHash common.Hash) (*types.Transaction, error) {
+	tx, isPending, err := c.ethClient.TransactionByHash(ctx, txHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction %s: %w", txHash.Hex(), err)
+	}
+	if !isPending {
+		return nil, fmt.Errorf("transaction %s is already mined", txHash.Hex())
+	}
+
+	sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover sender: %w", err)
+	}
+	if sender != c.publicAddress {
+		return nil, fmt.Errorf("transaction %s was not sent by the gateway", txHash.Hex())
+	}
+
+	return tx, nil
+}
+
+func maxBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return new(big.Int).Set(a)
+	}
+	return new(big.Int).Set(b)
+}
@@ -0,0 +1,45 @@
+package blockchain
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/fahedafzaal/go-integration/internal/config"
+)
+
+func TestBumpFeeClearsReplacementThreshold(t *testing.T) {
+	// Configured below the network minimum, so the 10% floor applies
+	c := &Client{config: &config.Config{FeeBumpPercent: 5}}
+
+	for _, fee := range []int64{1, 9, 10, 11, 999, 1_000_000_007} {
+		bumped := c.bumpFee(big.NewInt(fee))
+
+		// bumped * 100 >= fee * 110, i.e. at least +10% with no rounding loss
+		lhs := new(big.Int).Mul(bumped, big.NewInt(100))
+		rhs := new(big.Int).Mul(big.NewInt(fee), big.NewInt(110))
+		if lhs.Cmp(rhs) < 0 {
+			t.Errorf("bumpFee(%d) = %s, below +10%%", fee, bumped)
+		}
+	}
+}
+
+func TestBumpFeeUsesConfiguredPercent(t *testing.T) {
+	c := &Client{config: &config.Config{FeeBumpPercent: 25}}
+
+	if got := c.bumpFee(big.NewInt(1000)); got.Cmp(big.NewInt(1250)) != 0 {
+		t.Errorf("bumpFee(1000) = %s, want 1250", got)
+	}
+}
+
+func TestMaxFeeCap(t *testing.T) {
+	c := &Client{config: &config.Config{MaxFeeCapGwei: 3}}
+	if got := c.maxFeeCap(); got.Cmp(big.NewInt(3_000_000_000)) != 0 {
+		t.Errorf("maxFeeCap() = %s, want 3 gwei", got)
+	}
+
+	// Unset falls back to the default ceiling
+	c = &Client{config: &config.Config{}}
+	if got := c.maxFeeCap(); got.Cmp(big.NewInt(500_000_000_000)) != 0 {
+		t.Errorf("maxFeeCap() = %s, want 500 gwei", got)
+	}
+}
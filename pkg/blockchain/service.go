@@ -8,13 +8,16 @@ import (
 	"log"
 	"math/big"
 	"net/http"
-	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/fahedafzaal/go-integration/contracts"
 	"github.com/fahedafzaal/go-integration/internal/config"
+	"github.com/fahedafzaal/go-integration/pkg/auth"
+	"github.com/fahedafzaal/go-integration/pkg/money"
 )
 
 // PaymentMode defines the mode of operation for the payment gateway
@@ -36,6 +39,8 @@ type PaymentGatewayService struct {
 	client     *Client        // For direct blockchain interaction
 	baseURL    string         // For HTTP calls
 	httpClient *http.Client   // For HTTP calls
+	apiKeyID   string         // Signs HTTP calls when set
+	apiSecret  []byte         // Secret of apiKeyID
 	config     *config.Config // Configuration for direct mode
 }
 
@@ -43,10 +48,19 @@ type PaymentGatewayService struct {
 type ServiceConfig struct {
 	Mode            PaymentMode
 	BaseURL         string // Required for HTTP and Hybrid modes
+	APIKeyID        string // Optional, gateway API key id HTTP calls are signed with
+	APISecret       string // Secret of APIKeyID
 	EthereumRPCURL  string // Required for Direct and Hybrid modes
 	ContractAddress string // Required for Direct and Hybrid modes
-	PrivateKey      string // Required for Direct and Hybrid modes
+	PrivateKey      string // Required for Direct and Hybrid modes unless Signer is set
+	Signer          Signer // Optional, signs instead of PrivateKey
 	GasLimit        uint64 // Optional, defaults to 300000
+	ETHUSDPriceFeed string // Optional, defaults to the Sepolia ETH/USD feed
+	PriceHTTPURL    string // Optional, JSON price API quoted alongside the on-chain sources
+	PriceHTTPField  string // Optional, path to the price in its response, defaults to ethereum.usd
+	PriceQuorum     int    // Optional, price sources that must answer, defaults to a majority
+
+	ConfirmationDepth uint64 // Optional, defaults to 1 block
 }
 
 // NewPaymentGatewayService creates a new payment gateway service
@@ -56,14 +70,16 @@ func NewPaymentGatewayService(cfg ServiceConfig) (*PaymentGatewayService, error)
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		apiKeyID:  cfg.APIKeyID,
+		apiSecret: []byte(cfg.APISecret),
 	}
 
 	// Initialize based on mode
 	switch cfg.Mode {
 	case DirectMode, HybridMode:
 		// Initialize blockchain client for direct interaction
-		if cfg.EthereumRPCURL == "" || cfg.ContractAddress == "" || cfg.PrivateKey == "" {
-			return nil, fmt.Errorf("ethereum RPC URL, contract address, and private key are required for direct mode")
+		if cfg.EthereumRPCURL == "" || cfg.ContractAddress == "" || (cfg.PrivateKey == "" && cfg.Signer == nil) {
+			return nil, fmt.Errorf("ethereum RPC URL, contract address, and a private key or signer are required for direct mode")
 		}
 
 		gasLimit := cfg.GasLimit
@@ -71,14 +87,34 @@ func NewPaymentGatewayService(cfg ServiceConfig) (*PaymentGatewayService, error)
 			gasLimit = 300000
 		}
 
+		priceFeed := cfg.ETHUSDPriceFeed
+		if priceFeed == "" {
+			priceFeed = config.Networks[11155111].ETHUSDPriceFeed
+		}
+		priceField := cfg.PriceHTTPField
+		if priceField == "" {
+			priceField = "ethereum.usd"
+		}
+
 		config := &config.Config{
-			EthereumRPCURL:  cfg.EthereumRPCURL,
-			ContractAddress: cfg.ContractAddress,
-			PrivateKey:      cfg.PrivateKey,
-			GasLimit:        gasLimit,
+			EthereumRPCURL:    cfg.EthereumRPCURL,
+			ContractAddress:   cfg.ContractAddress,
+			PrivateKey:        cfg.PrivateKey,
+			GasLimit:          gasLimit,
+			ConfirmationDepth: cfg.ConfirmationDepth,
+			ETHUSDPriceFeed:   priceFeed,
+			PriceHTTPURL:      cfg.PriceHTTPURL,
+			PriceHTTPField:    priceField,
+			PriceQuorum:       cfg.PriceQuorum,
 		}
 
-		client, err := NewClient(config)
+		var client *Client
+		var err error
+		if cfg.Signer != nil {
+			client, err = NewClientWithSigner(config, cfg.Signer)
+		} else {
+			client, err = NewClient(config)
+		}
 		if err != nil {
 			if cfg.Mode == DirectMode {
 				return nil, fmt.Errorf("failed to initialize blockchain client: %w", err)
@@ -123,6 +159,17 @@ func NewPaymentGatewayServiceDirect(ethereumRPCURL, contractAddress, privateKey
 	})
 }
 
+// NewPaymentGatewayServiceWithClient creates a service in direct mode on an existing
+// client, so callers share its node connection and nonce management. The client stays
+// owned by the caller; do not Close the service.
+func NewPaymentGatewayServiceWithClient(client *Client) *PaymentGatewayService {
+	return &PaymentGatewayService{
+		mode:   DirectMode,
+		client: client,
+		config: client.config,
+	}
+}
+
 // NewPaymentGatewayServiceHybrid creates a service in hybrid mode (direct + HTTP fallback)
 func NewPaymentGatewayServiceHybrid(ethereumRPCURL, contractAddress, privateKey, baseURL string) (*PaymentGatewayService, error) {
 	return NewPaymentGatewayService(ServiceConfig{
@@ -136,11 +183,32 @@ func NewPaymentGatewayServiceHybrid(ethereumRPCURL, contractAddress, privateKey,
 
 // PostJobRequest represents the request for posting a job to escrow
 type PostJobRequest struct {
-	JobID             uint64 `json:"job_id"`             // application.id
-	FreelancerAddress string `json:"freelancer_address"` // applicant wallet
-	USDAmount         string `json:"usd_amount"`         // agreed_usd_amount
-	ClientAddress     string `json:"client_address"`     // poster wallet
-	ClientTxHash      string `json:"client_tx_hash"`     // transaction hash from client's wallet
+	JobID             uint64    `json:"job_id"`             // application.id
+	FreelancerAddress string    `json:"freelancer_address"` // applicant wallet
+	USDAmount         money.USD `json:"usd_amount"`         // agreed_usd_amount
+	ClientAddress     string    `json:"client_address"`     // poster wallet
+	ClientTxHash      string    `json:"client_tx_hash"`     // transaction hash from client's wallet
+	OfferExpiry       uint64    `json:"offer_expiry"`       // EscrowOffer expiry the client signed, unix seconds
+	OfferSignature    string    `json:"offer_signature"`    // client's EIP-712 EscrowOffer signature, 0x hex
+	QuoteID           string    `json:"quote_id,omitempty"` // price quote locking the deposit's ETH amount
+
+	// Quote is the quote QuoteID refers to, loaded by the gateway. A deposit is checked
+	// against the ETH it locked rather than the current price.
+	Quote *Quote `json:"-"`
+}
+
+// Offer returns the escrow terms of the request as the EscrowOffer the client signs
+func (r PostJobRequest) Offer() (EscrowOffer, error) {
+	if !common.IsHexAddress(r.FreelancerAddress) || !common.IsHexAddress(r.ClientAddress) {
+		return EscrowOffer{}, fmt.Errorf("invalid freelancer or client address")
+	}
+	return EscrowOffer{
+		JobID:      r.JobID,
+		Freelancer: common.HexToAddress(r.FreelancerAddress),
+		Client:     common.HexToAddress(r.ClientAddress),
+		USDAmount:  r.USDAmount.E8(),
+		Expiry:     r.OfferExpiry,
+	}, nil
 }
 
 // TransactionResponse represents a blockchain transaction response
@@ -150,26 +218,59 @@ type TransactionResponse struct {
 	GasUsed     uint64 `json:"gas_used"`
 	Success     bool   `json:"success"`
 	Error       string `json:"error,omitempty"`
+	OperationID int64  `json:"operation_id,omitempty"` // Set when the gateway queued the transaction
+	Status      string `json:"status,omitempty"`       // Operation status of the queued transaction
+
+	PriceSources []string `json:"price_sources,omitempty"` // Price sources an ETH amount was quoted from
+}
+
+// Operation statuses reported by the payment gateway
+const (
+	OperationQueued    = "queued"
+	OperationSubmitted = "submitted"
+	OperationMined     = "mined"
+	OperationConfirmed = "confirmed"
+	OperationFailed    = "failed"
+)
+
+// Operation is a chain transaction queued by the payment gateway
+type Operation struct {
+	ID            int64     `json:"id"`
+	Kind          string    `json:"kind"` // deposit, release or refund
+	ApplicationID int32     `json:"application_id"`
+	Status        string    `json:"status"`
+	TxHash        string    `json:"tx_hash,omitempty"`
+	BlockNumber   uint64    `json:"block_number,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	RevertReason  string    `json:"revert_reason,omitempty"`
+	ErrorCode     string    `json:"error_code,omitempty"` // contract error code, see ContractErrorByCode
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Done reports whether the operation has reached a final status
+func (o *Operation) Done() bool {
+	return o.Status == OperationConfirmed || o.Status == OperationFailed
 }
 
 // JobStatusResponse represents job status from the payment gateway
 type JobStatusResponse struct {
-	JobID             uint64 `json:"job_id"`
-	ApplicationID     int32  `json:"application_id"`
-	FreelancerAddress string `json:"freelancer_address"`
-	ClientAddress     string `json:"client_address"`
-	USDAmount         string `json:"usd_amount"`
-	PaymentStatus     string `json:"payment_status"`
-	ApplicationStatus string `json:"application_status"`
-	TxHashDeposit     string `json:"tx_hash_deposit,omitempty"`
-	TxHashRelease     string `json:"tx_hash_release,omitempty"`
-	TxHashRefund      string `json:"tx_hash_refund,omitempty"`
+	JobID             uint64    `json:"job_id"`
+	ApplicationID     int32     `json:"application_id"`
+	FreelancerAddress string    `json:"freelancer_address"`
+	ClientAddress     string    `json:"client_address"`
+	USDAmount         money.USD `json:"usd_amount"`
+	PaymentStatus     string    `json:"payment_status"`
+	ApplicationStatus string    `json:"application_status"`
+	TxHashDeposit     string    `json:"tx_hash_deposit,omitempty"`
+	TxHashRelease     string    `json:"tx_hash_release,omitempty"`
+	TxHashRefund      string    `json:"tx_hash_refund,omitempty"`
 }
 
 // PostJob initiates escrow funding when candidate accepts offer
 func (s *PaymentGatewayService) PostJob(ctx context.Context, req PostJobRequest) (*TransactionResponse, error) {
 	// DEBUG: Log the incoming request
-	log.Printf("DEBUG PostJob: Starting PostJob for JobID=%d, USDAmount='%s', Freelancer=%s, Client=%s, ClientTxHash=%s",
+	log.Printf("DEBUG PostJob: Starting PostJob for JobID=%d, USDAmount=%s, Freelancer=%s, Client=%s, ClientTxHash=%s",
 		req.JobID, req.USDAmount, req.FreelancerAddress, req.ClientAddress, req.ClientTxHash)
 
 	// Let the smart contract be the single source of truth for job existence validation
@@ -179,22 +280,30 @@ func (s *PaymentGatewayService) PostJob(ctx context.Context, req PostJobRequest)
 		return nil, fmt.Errorf("client transaction hash is required")
 	}
 
-	// Calculate required ETH amount
-	requiredEth, err := s.CalculateRequiredETH(ctx, req.USDAmount)
-	if err != nil {
-		return nil, fmt.Errorf("failed to calculate required ETH: %w", err)
-	}
-
-	log.Printf("DEBUG PostJob: Required ETH amount for job %d: %s wei", req.JobID, requiredEth.String())
-
 	// Verify the transaction using event-based approach
 	if s.canUseDirect() {
-		err := s.verifyJobPostedTransaction(ctx, req, requiredEth)
+		// Calculate required ETH amount
+		requiredEth, err := s.requiredETH(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Printf("DEBUG PostJob: Required ETH amount for job %d: %s wei", req.JobID, requiredEth.String())
+
+		// The client must have signed the exact terms before their transaction is accepted
+		if err := s.VerifyOffer(ctx, req); err != nil {
+			return nil, err
+		}
+
+		err = s.verifyJobPostedTransaction(ctx, req, requiredEth)
 		if err != nil {
 			return nil, fmt.Errorf("transaction verification failed: %w", err)
 		}
 
 		log.Printf("DEBUG PostJob: Client transaction verified successfully for job %d", req.JobID)
+	} else if s.canUseHTTP() {
+		// The gateway verifies the offer and transaction and records the deposit
+		return s.postJobHTTP(ctx, req)
 	}
 
 	// Return success with client's transaction hash
@@ -204,6 +313,26 @@ func (s *PaymentGatewayService) PostJob(ctx context.Context, req PostJobRequest)
 	}, nil
 }
 
+// VerifyOffer checks the client's EIP-712 EscrowOffer signature over the request's terms
+// (direct mode only)
+func (s *PaymentGatewayService) VerifyOffer(ctx context.Context, req PostJobRequest) error {
+	if !s.canUseDirect() {
+		return fmt.Errorf("direct mode not available for offer verification")
+	}
+	if req.OfferSignature == "" {
+		return fmt.Errorf("client offer signature is required")
+	}
+	signature, err := hexutil.Decode(req.OfferSignature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrOfferSignatureFormat, err)
+	}
+	offer, err := req.Offer()
+	if err != nil {
+		return err
+	}
+	return s.client.VerifyEscrowOffer(ctx, offer, signature)
+}
+
 // verifyJobPostedTransaction verifies the client's transaction using event-based approach
 func (s *PaymentGatewayService) verifyJobPostedTransaction(ctx context.Context, req PostJobRequest, requiredEth *big.Int) error {
 	txHash := common.HexToHash(req.ClientTxHash)
@@ -227,6 +356,15 @@ func (s *PaymentGatewayService) verifyJobPostedTransaction(ctx context.Context,
 		return fmt.Errorf("transaction failed with status: %d", receipt.Status)
 	}
 
+	// Require the deposit to be buried deep enough to survive a shallow reorg
+	confirmations, err := s.client.Confirmations(ctx, receipt)
+	if err != nil {
+		return fmt.Errorf("failed to check confirmations: %w", err)
+	}
+	if depth := s.client.ConfirmationDepth(); confirmations < depth {
+		return fmt.Errorf("transaction has %d of %d required confirmations", confirmations, depth)
+	}
+
 	// Verify transaction sender using block hash (not tx hash)
 	from, err := s.client.ethClient.TransactionSender(ctx, tx, receipt.BlockHash, uint(receipt.TransactionIndex))
 	if err != nil {
@@ -244,20 +382,28 @@ func (s *PaymentGatewayService) verifyJobPostedTransaction(ctx context.Context,
 		return fmt.Errorf("transaction is not to the correct contract address")
 	}
 
+	// A quoted amount holds only for deposits mined before the quote expired. One already
+	// redeemed for this transaction was checked when the gateway accepted it.
+	if req.Quote != nil && !strings.EqualFold(req.Quote.RedeemedBy, req.ClientTxHash) {
+		header, err := s.client.ethClient.HeaderByNumber(ctx, receipt.BlockNumber)
+		if err != nil {
+			return fmt.Errorf("failed to get deposit block: %w", err)
+		}
+		if err := req.Quote.validAt(time.Unix(int64(header.Time), 0)); err != nil {
+			return err
+		}
+	}
+
 	// Verify transaction value with 1% tolerance
-	tolerance := new(big.Int).Div(requiredEth, big.NewInt(100)) // 1% tolerance
-	delta := new(big.Int).Sub(tx.Value(), requiredEth)
-	deltaAbs := new(big.Int).Abs(delta)
-	if deltaAbs.Cmp(tolerance) == 1 {
-		return fmt.Errorf("transaction value %s differs from required %s by more than 1%% tolerance",
-			tx.Value().String(), requiredEth.String())
+	if err := checkDepositValue(tx.Value(), requiredEth); err != nil {
+		return err
 	}
 
-	log.Printf("DEBUG PostJob: Value check passed - TX: %s, Required: %s, Tolerance: %s",
-		tx.Value().String(), requiredEth.String(), tolerance.String())
+	log.Printf("DEBUG PostJob: Value check passed - TX: %s, Required: %s",
+		tx.Value().String(), requiredEth.String())
 
 	// Most importantly: Verify JobPosted event was emitted
-	err = s.verifyJobPostedEvent(ctx, receipt, req)
+	err = s.verifyJobPostedEvent(ctx, receipt, req, requiredEth)
 	if err != nil {
 		return fmt.Errorf("failed to verify JobPosted event: %w", err)
 	}
@@ -265,8 +411,19 @@ func (s *PaymentGatewayService) verifyJobPostedTransaction(ctx context.Context,
 	return nil
 }
 
+// checkDepositValue accepts a deposit within 1% of the ETH the agreed amount requires
+func checkDepositValue(value, requiredEth *big.Int) error {
+	tolerance := new(big.Int).Div(requiredEth, big.NewInt(100)) // 1% tolerance
+	delta := new(big.Int).Sub(value, requiredEth)
+	if delta.Abs(delta).Cmp(tolerance) == 1 {
+		return fmt.Errorf("transaction value %s differs from required %s by more than 1%% tolerance",
+			value.String(), requiredEth.String())
+	}
+	return nil
+}
+
 // verifyJobPostedEvent verifies that the JobPosted event was emitted with correct parameters
-func (s *PaymentGatewayService) verifyJobPostedEvent(ctx context.Context, receipt *types.Receipt, req PostJobRequest) error {
+func (s *PaymentGatewayService) verifyJobPostedEvent(ctx context.Context, receipt *types.Receipt, req PostJobRequest, requiredEth *big.Int) error {
 	// Get the contract instance for event parsing
 	contract, err := s.client.GetContract()
 	if err != nil {
@@ -277,13 +434,7 @@ func (s *PaymentGatewayService) verifyJobPostedEvent(ctx context.Context, receip
 	expectedJobID := big.NewInt(int64(req.JobID))
 	expectedClient := common.HexToAddress(req.ClientAddress)
 	expectedFreelancer := common.HexToAddress(req.FreelancerAddress)
-
-	usdAmountFloat, err := strconv.ParseFloat(req.USDAmount, 64)
-	if err != nil {
-		return fmt.Errorf("invalid USD amount: %w", err)
-	}
-	val := new(big.Float).Mul(big.NewFloat(usdAmountFloat), big.NewFloat(1e8))
-	expectedUSDAmount, _ := val.Int(nil)
+	expectedUSDAmount := req.USDAmount.E8()
 
 	// Look for JobPosted event in transaction logs
 	var foundEvent *contracts.EthJobEscrowJobPosted
@@ -328,11 +479,6 @@ func (s *PaymentGatewayService) verifyJobPostedEvent(ctx context.Context, receip
 	}
 
 	// Verify the ETH amount is reasonable (within our tolerance)
-	requiredEth, err := s.CalculateRequiredETH(ctx, req.USDAmount)
-	if err != nil {
-		return fmt.Errorf("failed to recalculate required ETH: %w", err)
-	}
-
 	tolerance := new(big.Int).Div(requiredEth, big.NewInt(100)) // 1% tolerance
 	ethDelta := new(big.Int).Sub(foundEvent.EthAmount, requiredEth)
 	ethDeltaAbs := new(big.Int).Abs(ethDelta)
@@ -437,11 +583,20 @@ func (s *PaymentGatewayService) GetJobStatus(ctx context.Context, jobID uint64)
 
 // GetETHUSDPrice gets current ETH/USD price
 func (s *PaymentGatewayService) GetETHUSDPrice(ctx context.Context) (*big.Int, error) {
+	quote, err := s.GetETHUSDQuote(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return quote.Price, nil
+}
+
+// GetETHUSDQuote gets the current ETH/USD price and the price sources it was taken from
+func (s *PaymentGatewayService) GetETHUSDQuote(ctx context.Context) (*PriceQuote, error) {
 	// Try direct blockchain interaction first (if available)
 	if s.canUseDirect() {
-		price, err := s.client.GetETHUSDPrice(ctx)
+		quote, err := s.client.PriceQuote(ctx)
 		if err == nil {
-			return price, nil
+			return quote, nil
 		}
 
 		log.Printf("Direct blockchain call failed: %v", err)
@@ -457,7 +612,7 @@ func (s *PaymentGatewayService) GetETHUSDPrice(ctx context.Context) (*big.Int, e
 
 	// Use HTTP mode
 	if s.canUseHTTP() {
-		return s.getETHUSDPriceHTTP(ctx)
+		return s.getETHUSDQuoteHTTP(ctx)
 	}
 
 	return nil, fmt.Errorf("no available payment method")
@@ -511,8 +666,10 @@ func (s *PaymentGatewayService) getJobStatusDirect(ctx context.Context, jobID ui
 		return nil, err
 	}
 
-	// Convert amounts back to strings using fromUsdE8
-	usdAmountStr := fromUsdE8(details.USDAmount)
+	usdAmount, err := money.USDFromE8(details.USDAmount)
+	if err != nil {
+		return nil, fmt.Errorf("job %d has an invalid USD amount: %w", jobID, err)
+	}
 
 	// Determine payment status
 	paymentStatus := "pending"
@@ -529,13 +686,13 @@ func (s *PaymentGatewayService) getJobStatusDirect(ctx context.Context, jobID ui
 		ApplicationID:     int32(jobID), // Assuming jobID maps to application ID
 		FreelancerAddress: details.Freelancer.Hex(),
 		ClientAddress:     details.Client.Hex(),
-		USDAmount:         usdAmountStr,
+		USDAmount:         usdAmount,
 		PaymentStatus:     paymentStatus,
 		ApplicationStatus: "active", // This would need to be determined from your DB
 	}, nil
 }
 
-func (s *PaymentGatewayService) getETHUSDPriceHTTP(ctx context.Context) (*big.Int, error) {
+func (s *PaymentGatewayService) getETHUSDQuoteHTTP(ctx context.Context) (*PriceQuote, error) {
 	url := fmt.Sprintf("%s/eth-price", s.baseURL)
 
 	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
@@ -543,7 +700,7 @@ func (s *PaymentGatewayService) getETHUSDPriceHTTP(ctx context.Context) (*big.In
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	resp, err := s.httpClient.Do(httpReq)
+	resp, err := s.doHTTP(httpReq, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -553,20 +710,32 @@ func (s *PaymentGatewayService) getETHUSDPriceHTTP(ctx context.Context) (*big.In
 		return nil, fmt.Errorf("request failed with status %d", resp.StatusCode)
 	}
 
-	var result map[string]interface{}
+	var result struct {
+		Price   string   `json:"eth_usd_price"` // 8 decimals
+		Sources []string `json:"sources"`
+	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	// Extract price from response
-	priceFloat, ok := result["price"].(float64)
+	price, ok := new(big.Int).SetString(result.Price, 10)
 	if !ok {
 		return nil, fmt.Errorf("invalid price format in response")
 	}
+	return &PriceQuote{Price: price, Sources: result.Sources}, nil
+}
 
-	// Convert to big.Int (assuming price is in USD with 8 decimal places like Chainlink)
-	price := big.NewInt(int64(priceFloat * 100000000))
-	return price, nil
+// doHTTP sends a request to the gateway, on behalf of the user set with
+// auth.WithActingUserToken and signed with the service's API key when one is configured.
+// body must be the request's body.
+func (s *PaymentGatewayService) doHTTP(req *http.Request, body []byte) (*http.Response, error) {
+	if token, ok := auth.ActingUserToken(req.Context()); ok {
+		req.Header.Set(auth.HeaderActingUser, token)
+	}
+	if s.apiKeyID != "" {
+		auth.SignRequest(req, s.apiKeyID, s.apiSecret, body)
+	}
+	return s.httpClient.Do(req)
 }
 
 // HTTP-based methods (existing implementation)
@@ -576,39 +745,86 @@ func (s *PaymentGatewayService) postJobHTTP(ctx context.Context, req PostJobRequ
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", s.baseURL+"/post-job", bytes.NewReader(body))
+	return s.submitOperationHTTP(ctx, s.baseURL+"/post-job", body)
+}
+
+func (s *PaymentGatewayService) completeJobHTTP(ctx context.Context, jobID uint64) (*TransactionResponse, error) {
+	return s.submitOperationHTTP(ctx, fmt.Sprintf("%s/complete-job?job_id=%d", s.baseURL, jobID), nil)
+}
+
+func (s *PaymentGatewayService) cancelJobHTTP(ctx context.Context, jobID uint64) (*TransactionResponse, error) {
+	return s.submitOperationHTTP(ctx, fmt.Sprintf("%s/cancel-job?job_id=%d", s.baseURL, jobID), nil)
+}
+
+// submitOperationHTTP calls a mutating gateway endpoint. The gateway answers 202 Accepted
+// with an operation to poll through WaitOperation, or 200 OK with a transaction response
+// when the work was already done.
+func (s *PaymentGatewayService) submitOperationHTTP(ctx context.Context, url string, body []byte) (*TransactionResponse, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
 
-	resp, err := s.httpClient.Do(httpReq)
+	resp, err := s.doHTTP(httpReq, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	switch resp.StatusCode {
+	case http.StatusAccepted:
+		var op Operation
+		if err := json.NewDecoder(resp.Body).Decode(&op); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return &TransactionResponse{
+			TxHash:      op.TxHash,
+			BlockNumber: op.BlockNumber,
+			Success:     op.Status != OperationFailed,
+			Error:       op.Error,
+			OperationID: op.ID,
+			Status:      op.Status,
+		}, nil
+
+	case http.StatusOK:
+		var result TransactionResponse
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return &result, nil
+
+	default:
+		// Contract rejections carry a code that maps back to the same sentinel as in direct mode
+		var errResp struct {
+			Error string `json:"error"`
+			Code  string `json:"code"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&errResp) == nil {
+			if contractErr, ok := ContractErrorByCode(errResp.Code); ok {
+				return nil, fmt.Errorf("request failed with status %d: %w", resp.StatusCode, contractErr)
+			}
+		}
 		return nil, fmt.Errorf("request failed with status %d", resp.StatusCode)
 	}
+}
 
-	var result TransactionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+// GetOperation fetches the current state of an operation (HTTP only)
+func (s *PaymentGatewayService) GetOperation(ctx context.Context, id int64) (*Operation, error) {
+	if !s.canUseHTTP() {
+		return nil, fmt.Errorf("HTTP mode not available for operations")
 	}
 
-	return &result, nil
-}
-
-func (s *PaymentGatewayService) completeJobHTTP(ctx context.Context, jobID uint64) (*TransactionResponse, error) {
-	url := fmt.Sprintf("%s/complete-job?job_id=%d", s.baseURL, jobID)
+	url := fmt.Sprintf("%s/operations/%d", s.baseURL, id)
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	resp, err := s.httpClient.Do(httpReq)
+	resp, err := s.doHTTP(httpReq, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -618,38 +834,51 @@ func (s *PaymentGatewayService) completeJobHTTP(ctx context.Context, jobID uint6
 		return nil, fmt.Errorf("request failed with status %d", resp.StatusCode)
 	}
 
-	var result TransactionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	var op Operation
+	if err := json.NewDecoder(resp.Body).Decode(&op); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return &result, nil
+	return &op, nil
 }
 
-func (s *PaymentGatewayService) cancelJobHTTP(ctx context.Context, jobID uint64) (*TransactionResponse, error) {
-	url := fmt.Sprintf("%s/cancel-job?job_id=%d", s.baseURL, jobID)
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// WaitOperation polls an operation until it is confirmed or failed, or the context ends.
+// A failed operation is returned together with an error carrying its revert reason.
+func (s *PaymentGatewayService) WaitOperation(ctx context.Context, id int64, pollInterval time.Duration) (*Operation, error) {
+	if !s.canUseHTTP() {
+		return nil, fmt.Errorf("HTTP mode not available for operations")
 	}
-
-	resp, err := s.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+	if pollInterval <= 0 {
+		pollInterval = 2 * time.Second
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("request failed with status %d", resp.StatusCode)
-	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
 
-	var result TransactionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
+	for {
+		op, err := s.GetOperation(ctx, id)
+		if err != nil {
+			log.Printf("Warning: Failed to poll operation %d: %v", id, err)
+		} else if op.Done() {
+			if op.Status == OperationFailed {
+				if contractErr, ok := ContractErrorByCode(op.ErrorCode); ok {
+					return op, fmt.Errorf("operation %d failed: transaction reverted: %w", id, contractErr)
+				}
+				reason := op.Error
+				if op.RevertReason != "" {
+					reason = "transaction reverted: " + op.RevertReason
+				}
+				return op, fmt.Errorf("operation %d failed: %s", id, reason)
+			}
+			return op, nil
+		}
 
-	return &result, nil
+		select {
+		case <-ctx.Done():
+			return op, ctx.Err()
+		case <-ticker.C:
+		}
+	}
 }
 
 func (s *PaymentGatewayService) getJobStatusHTTP(ctx context.Context, jobID uint64) (*JobStatusResponse, error) {
@@ -660,7 +889,7 @@ func (s *PaymentGatewayService) getJobStatusHTTP(ctx context.Context, jobID uint
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	resp, err := s.httpClient.Do(httpReq)
+	resp, err := s.doHTTP(httpReq, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -678,7 +907,8 @@ func (s *PaymentGatewayService) getJobStatusHTTP(ctx context.Context, jobID uint
 	return &result, nil
 }
 
-// ConfirmDeposit confirms that a deposit transaction has been mined (HTTP only for now)
+// ConfirmDeposit confirms that a deposit transaction has been mined (HTTP only for now).
+// The gateway only accepts it from an API key with the admin scope.
 func (s *PaymentGatewayService) ConfirmDeposit(ctx context.Context, jobID uint64) error {
 	if !s.canUseHTTP() {
 		return fmt.Errorf("HTTP mode not available for confirmation")
@@ -691,7 +921,7 @@ func (s *PaymentGatewayService) ConfirmDeposit(ctx context.Context, jobID uint64
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	resp, err := s.httpClient.Do(httpReq)
+	resp, err := s.doHTTP(httpReq, nil)
 	if err != nil {
 		return fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -704,7 +934,8 @@ func (s *PaymentGatewayService) ConfirmDeposit(ctx context.Context, jobID uint64
 	return nil
 }
 
-// ConfirmRelease confirms that a release transaction has been mined (HTTP only for now)
+// ConfirmRelease confirms that a release transaction has been mined (HTTP only for now).
+// The gateway only accepts it from an API key with the admin scope.
 func (s *PaymentGatewayService) ConfirmRelease(ctx context.Context, jobID uint64) error {
 	if !s.canUseHTTP() {
 		return fmt.Errorf("HTTP mode not available for confirmation")
@@ -717,7 +948,7 @@ func (s *PaymentGatewayService) ConfirmRelease(ctx context.Context, jobID uint64
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	resp, err := s.httpClient.Do(httpReq)
+	resp, err := s.doHTTP(httpReq, nil)
 	if err != nil {
 		return fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -793,8 +1024,10 @@ func (s *PaymentGatewayService) CheckAndReconcileJobState(ctx context.Context, j
 		}, nil
 	}
 
-	// Convert amounts back to strings using fromUsdE8
-	usdAmountStr := fromUsdE8(details.USDAmount)
+	usdAmount, err := money.USDFromE8(details.USDAmount)
+	if err != nil {
+		return nil, fmt.Errorf("job %d has an invalid USD amount: %w", jobID, err)
+	}
 
 	// Determine payment status based on smart contract state
 	paymentStatus := "pending_deposit"
@@ -818,103 +1051,86 @@ func (s *PaymentGatewayService) CheckAndReconcileJobState(ctx context.Context, j
 		ApplicationID:     int32(jobID),
 		FreelancerAddress: details.Freelancer.Hex(),
 		ClientAddress:     details.Client.Hex(),
-		USDAmount:         usdAmountStr,
+		USDAmount:         usdAmount,
 		PaymentStatus:     paymentStatus,
 		ApplicationStatus: "active",
 	}, nil
 }
 
-// fromUsdE8 converts 8-decimal "micro-dollars" back to human-readable USD string
-func fromUsdE8(u *big.Int) string {
-	f := new(big.Float).Quo(new(big.Float).SetInt(u), big.NewFloat(1e8))
-	return f.Text('f', 2) // "1234.56"
-}
-
 // CalculateRequiredETH calculates the required ETH amount for a USD amount
-func (s *PaymentGatewayService) CalculateRequiredETH(ctx context.Context, usd string) (*big.Int, error) {
-	usdFloat, err := strconv.ParseFloat(usd, 64)
-	if err != nil {
-		return nil, fmt.Errorf("invalid USD amount: %w", err)
-	}
+func (s *PaymentGatewayService) CalculateRequiredETH(ctx context.Context, usd money.USD) (*big.Int, error) {
+	wei, _, err := s.QuoteRequiredETH(ctx, usd)
+	return wei, err
+}
 
-	val := new(big.Float).Mul(big.NewFloat(usdFloat), big.NewFloat(1e8))
-	usdE8, _ := val.Int(nil)
+// QuoteRequiredETH calculates the required ETH amount for a USD amount and returns the
+// price quote it was converted at
+func (s *PaymentGatewayService) QuoteRequiredETH(ctx context.Context, usd money.USD) (*big.Int, *PriceQuote, error) {
+	usdE8 := usd.E8()
 
-	price, err := s.GetETHUSDPrice(ctx) // 8-decimals
+	quote, err := s.GetETHUSDQuote(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get ETH price: %w", err)
+		return nil, nil, fmt.Errorf("failed to get ETH price: %w", err)
+	}
+	price := quote.Price // 8-decimals
+	if price.Sign() <= 0 {
+		return nil, nil, fmt.Errorf("%w: %s", ErrPriceNonPositive, price)
 	}
 
 	wei := new(big.Int).Mul(usdE8, big.NewInt(1e18))
 	wei.Div(wei, price)
 
-	log.Printf("DEBUG CalculateRequiredETH: USD=%.2f, USDe8=%s, ETHPrice=%s, RequiredWei=%s",
-		usdFloat, usdE8.String(), price.String(), wei.String())
+	log.Printf("DEBUG CalculateRequiredETH: USD=%s, USDe8=%s, ETHPrice=%s, RequiredWei=%s",
+		usd, usdE8.String(), price.String(), wei.String())
 
-	return wei, nil
+	return wei, quote, nil
 }
 
 // GetRequiredETH returns the required ETH amount for a job
-func (s *PaymentGatewayService) GetRequiredETH(ctx context.Context, usdAmount string) (*TransactionResponse, error) {
-	// Calculate required ETH amount
-	requiredEth, err := s.CalculateRequiredETH(ctx, usdAmount)
+func (s *PaymentGatewayService) GetRequiredETH(ctx context.Context, usdAmount money.USD) (*TransactionResponse, error) {
+	// Calculate required ETH amount, and the price it was converted at for reference
+	requiredEth, quote, err := s.QuoteRequiredETH(ctx, usdAmount)
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate required ETH: %w", err)
 	}
 
-	// Get current ETH price for reference
-	ethPrice, err := s.GetETHUSDPrice(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get ETH price: %w", err)
-	}
-
 	// Convert to float for display
-	ethPriceFloat := new(big.Float).Quo(new(big.Float).SetInt(ethPrice), new(big.Float).SetInt64(1e8))
+	ethPriceFloat := new(big.Float).Quo(new(big.Float).SetInt(quote.Price), new(big.Float).SetInt64(1e8))
 	ethPriceStr := ethPriceFloat.Text('f', 2)
 
 	// Return response with required ETH amount
 	return &TransactionResponse{
-		TxHash:  requiredEth.String(), // Use TxHash field to return the required ETH amount
-		Success: true,
-		Error:   fmt.Sprintf("ETH_PRICE:%s", ethPriceStr), // Include current ETH price in error field
+		TxHash:       requiredEth.String(), // Use TxHash field to return the required ETH amount
+		Success:      true,
+		Error:        fmt.Sprintf("ETH_PRICE:%s", ethPriceStr), // Include current ETH price in error field
+		PriceSources: quote.Sources,
 	}, nil
 }
 
 // GetContractInteractionData returns the data needed for the client to interact with the contract
 func (s *PaymentGatewayService) GetContractInteractionData(ctx context.Context, req PostJobRequest) (*TransactionResponse, error) {
-	// Calculate required ETH amount
-	requiredEth, err := s.CalculateRequiredETH(ctx, req.USDAmount)
+	// Calculate required ETH amount, and the price it was converted at for reference
+	requiredEth, quote, err := s.QuoteRequiredETH(ctx, req.USDAmount)
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate required ETH: %w", err)
 	}
 
-	// Get current ETH price for reference
-	ethPrice, err := s.GetETHUSDPrice(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get ETH price: %w", err)
-	}
-
 	// Convert to float for display
-	ethPriceFloat := new(big.Float).Quo(new(big.Float).SetInt(ethPrice), new(big.Float).SetInt64(1e8))
+	ethPriceFloat := new(big.Float).Quo(new(big.Float).SetInt(quote.Price), new(big.Float).SetInt64(1e8))
 	ethPriceStr := ethPriceFloat.Text('f', 2)
 
 	// Parse addresses
 	freelancerAddr := common.HexToAddress(req.FreelancerAddress)
 	clientAddr := common.HexToAddress(req.ClientAddress)
 
-	// Parse USD amount
-	usdAmountFloat, err := strconv.ParseFloat(req.USDAmount, 64)
-	if err != nil {
-		return nil, fmt.Errorf("invalid USD amount: %w", err)
-	}
-	val := new(big.Float).Mul(big.NewFloat(usdAmountFloat), big.NewFloat(1e8))
-	usdE8, _ := val.Int(nil)
+	usdE8 := req.USDAmount.E8()
 
 	// Generate contract interaction data
 	contractData := map[string]interface{}{
 		"contract_address": s.config.ContractAddress,
 		"required_eth":     requiredEth.String(),
 		"eth_price_usd":    ethPriceStr,
+		"price_sources":    quote.Sources,
 		"job_id":           req.JobID,
 		"freelancer":       freelancerAddr.Hex(),
 		"client":           clientAddr.Hex(),
@@ -940,13 +1156,7 @@ func (s *PaymentGatewayService) GetTransactionData(ctx context.Context, req Post
 	freelancerAddr := common.HexToAddress(req.FreelancerAddress)
 	clientAddr := common.HexToAddress(req.ClientAddress)
 
-	// Parse USD amount
-	usdAmountFloat, err := strconv.ParseFloat(req.USDAmount, 64)
-	if err != nil {
-		return "", fmt.Errorf("invalid USD amount: %w", err)
-	}
-	val := new(big.Float).Mul(big.NewFloat(usdAmountFloat), big.NewFloat(1e8))
-	usdE8, _ := val.Int(nil)
+	usdE8 := req.USDAmount.E8()
 
 	// Get the contract ABI
 	abi, err := contracts.EthJobEscrowMetaData.GetAbi()
@@ -963,3 +1173,39 @@ func (s *PaymentGatewayService) GetTransactionData(ctx context.Context, req Post
 	// Return the hex-encoded data
 	return common.Bytes2Hex(data), nil
 }
+
+// BuildPostJobTx returns the PostJob transaction the client sends from their own wallet to
+// fund the escrow, complete with gas limit, fees and nonce. The value is the ETH the
+// contract requires at the current price; PostJob accepts the deposit only within 1% of
+// it, so no slippage buffer is added.
+func (s *PaymentGatewayService) BuildPostJobTx(ctx context.Context, req PostJobRequest) (*UnsignedTx, error) {
+	if !s.canUseDirect() {
+		return nil, fmt.Errorf("direct mode not available for building transactions")
+	}
+
+	offer, err := req.Offer()
+	if err != nil {
+		return nil, err
+	}
+
+	requiredEth, err := s.requiredETH(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Quote != nil {
+		if err := req.Quote.validAt(time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	abi, err := contracts.EthJobEscrowMetaData.GetAbi()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contract ABI: %w", err)
+	}
+	data, err := abi.Pack("postJob", new(big.Int).SetUint64(offer.JobID), offer.Freelancer, offer.USDAmount, offer.Client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transaction data: %w", err)
+	}
+
+	return s.client.BuildUnsignedTx(ctx, offer.Client, s.client.contractAddress, requiredEth, data)
+}
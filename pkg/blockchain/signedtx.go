@@ -0,0 +1,122 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/fahedafzaal/go-integration/contracts"
+)
+
+// ErrSignedTxMismatch is returned for a signed transaction that does not post the expected job
+var ErrSignedTxMismatch = errors.New("signed transaction does not match the escrow terms")
+
+// DecodeSignedTx decodes a raw signed transaction, legacy or EIP-2718 typed, as wallets
+// return it from eth_signTransaction
+func DecodeSignedTx(raw []byte) (*types.Transaction, error) {
+	var tx types.Transaction
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("failed to decode signed transaction: %w", err)
+	}
+	return &tx, nil
+}
+
+// CheckSignedPostJob checks a transaction the client signed before it is broadcast on
+// their behalf: it must be for this network, call postJob on the escrow contract with
+// exactly req's terms, be signed by req's client and carry the ETH the terms require.
+// The client's signature over the calldata is their agreement to the terms, as an
+// EscrowOffer signature is for a deposit they send themselves.
+func (s *PaymentGatewayService) CheckSignedPostJob(ctx context.Context, tx *types.Transaction, req PostJobRequest) error {
+	if !s.canUseDirect() {
+		return fmt.Errorf("direct mode not available for checking signed transactions")
+	}
+
+	offer, err := req.Offer()
+	if err != nil {
+		return err
+	}
+
+	chainID, err := s.client.ethClient.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get chain ID: %w", err)
+	}
+	if tx.ChainId().Cmp(chainID) != 0 {
+		return fmt.Errorf("%w: signed for chain %s, expected %s", ErrSignedTxMismatch, tx.ChainId(), chainID)
+	}
+
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), tx)
+	if err != nil {
+		return fmt.Errorf("%w: cannot recover sender: %v", ErrSignedTxMismatch, err)
+	}
+	if sender != offer.Client {
+		return fmt.Errorf("%w: signed by %s, expected the client %s", ErrSignedTxMismatch, sender.Hex(), offer.Client.Hex())
+	}
+
+	if tx.To() == nil || *tx.To() != s.client.contractAddress {
+		return fmt.Errorf("%w: not sent to the escrow contract", ErrSignedTxMismatch)
+	}
+
+	parsed, err := contracts.EthJobEscrowMetaData.GetAbi()
+	if err != nil {
+		return fmt.Errorf("failed to get contract ABI: %w", err)
+	}
+	method := parsed.Methods["postJob"]
+	data := tx.Data()
+	if len(data) < 4 || !bytes.Equal(data[:4], method.ID) {
+		return fmt.Errorf("%w: not a postJob call", ErrSignedTxMismatch)
+	}
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil || len(args) != 4 {
+		return fmt.Errorf("%w: malformed postJob arguments", ErrSignedTxMismatch)
+	}
+	jobID, _ := args[0].(*big.Int)
+	freelancer, _ := args[1].(common.Address)
+	usdAmount, _ := args[2].(*big.Int)
+	client, _ := args[3].(common.Address)
+	if jobID == nil || !jobID.IsUint64() || jobID.Uint64() != offer.JobID {
+		return fmt.Errorf("%w: job ID %v, expected %d", ErrSignedTxMismatch, jobID, offer.JobID)
+	}
+	if freelancer != offer.Freelancer {
+		return fmt.Errorf("%w: freelancer %s, expected %s", ErrSignedTxMismatch, freelancer.Hex(), offer.Freelancer.Hex())
+	}
+	if usdAmount == nil || usdAmount.Cmp(offer.USDAmount) != 0 {
+		return fmt.Errorf("%w: USD amount %v, expected %s", ErrSignedTxMismatch, usdAmount, offer.USDAmount)
+	}
+	if client != offer.Client {
+		return fmt.Errorf("%w: client %s, expected %s", ErrSignedTxMismatch, client.Hex(), offer.Client.Hex())
+	}
+
+	requiredEth, err := s.requiredETH(ctx, req)
+	if err != nil {
+		return err
+	}
+	if req.Quote != nil {
+		if err := req.Quote.validAt(time.Now()); err != nil {
+			return err
+		}
+	}
+	if err := checkDepositValue(tx.Value(), requiredEth); err != nil {
+		return fmt.Errorf("%w: %v", ErrSignedTxMismatch, err)
+	}
+	return nil
+}
+
+// VerifyClientDeposit checks a mined deposit the client sent from their own wallet the
+// same way PostJob does, once req.ClientTxHash has enough confirmations
+func (s *PaymentGatewayService) VerifyClientDeposit(ctx context.Context, req PostJobRequest) error {
+	if !s.canUseDirect() {
+		return fmt.Errorf("direct mode not available for deposit verification")
+	}
+
+	requiredEth, err := s.requiredETH(ctx, req)
+	if err != nil {
+		return err
+	}
+	return s.verifyJobPostedTransaction(ctx, req, requiredEth)
+}
@@ -0,0 +1,91 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/fahedafzaal/go-integration/pkg/money"
+)
+
+func TestSubmitSignedPostJob(t *testing.T) {
+	t.Parallel()
+	env := newEscrowEnv(t)
+	const jobID = 505
+
+	clientWallet := env.stranger.signer
+	terms := PostJobRequest{
+		JobID:             jobID,
+		FreelancerAddress: env.freelancer.Hex(),
+		USDAmount:         money.MustParseUSD("300"),
+		ClientAddress:     clientWallet.Address().Hex(),
+	}
+	service := env.service()
+
+	unsigned, err := service.BuildPostJobTx(env.ctx, terms)
+	if err != nil {
+		t.Fatalf("BuildPostJobTx: %v", err)
+	}
+	chainID := unsigned.ChainID.ToInt()
+	signed, err := clientWallet.SignTx(env.ctx, unsigned.Transaction(), chainID)
+	if err != nil {
+		t.Fatalf("SignTx: %v", err)
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeSignedTx(raw)
+	if err != nil {
+		t.Fatalf("DecodeSignedTx: %v", err)
+	}
+
+	if err := service.CheckSignedPostJob(env.ctx, decoded, terms); err != nil {
+		t.Fatalf("valid signed transaction rejected: %v", err)
+	}
+
+	// The calldata must carry the application's terms
+	other := terms
+	other.FreelancerAddress = common.HexToAddress("0x3333333333333333333333333333333333333333").Hex()
+	if err := service.CheckSignedPostJob(env.ctx, decoded, other); !errors.Is(err, ErrSignedTxMismatch) {
+		t.Errorf("other freelancer: err = %v, want ErrSignedTxMismatch", err)
+	}
+	other = terms
+	other.USDAmount = money.MustParseUSD("299")
+	if err := service.CheckSignedPostJob(env.ctx, decoded, other); !errors.Is(err, ErrSignedTxMismatch) {
+		t.Errorf("other amount: err = %v, want ErrSignedTxMismatch", err)
+	}
+
+	// The same call signed by anyone but the poster is refused
+	forged, err := env.gateway.signer.SignTx(env.ctx, unsigned.Transaction(), chainID)
+	if err != nil {
+		t.Fatalf("SignTx: %v", err)
+	}
+	if err := service.CheckSignedPostJob(env.ctx, forged, terms); !errors.Is(err, ErrSignedTxMismatch) {
+		t.Errorf("signed by another wallet: err = %v, want ErrSignedTxMismatch", err)
+	}
+
+	// Broadcasting it leaves the gateway's own nonce reservations alone, and the mined
+	// deposit verifies as one the client sent themselves
+	if err := env.gateway.SendSignedTransaction(env.ctx, decoded); err != nil {
+		t.Fatalf("SendSignedTransaction: %v", err)
+	}
+	if err := env.gateway.SendSignedTransaction(env.ctx, decoded); err != nil {
+		t.Fatalf("repeated SendSignedTransaction: %v", err)
+	}
+	env.waitMined(decoded)
+
+	receipt, err := env.gateway.FindMinedTransactionFrom(env.ctx, clientWallet.Address(), decoded.Nonce(), []common.Hash{decoded.Hash()})
+	if err != nil || receipt == nil || receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("FindMinedTransactionFrom = %v, %v; want the successful receipt", receipt, err)
+	}
+
+	deposit := terms
+	deposit.ClientTxHash = decoded.Hash().Hex()
+	if err := service.VerifyClientDeposit(env.ctx, deposit); err != nil {
+		t.Errorf("VerifyClientDeposit: %v", err)
+	}
+	env.requireStatus(jobID, "deposited")
+}
@@ -0,0 +1,352 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"github.com/fahedafzaal/go-integration/internal/config"
+)
+
+// Signer types selected with SIGNER_TYPE
+const (
+	SignerPrivateKey = "private_key" // raw hex PRIVATE_KEY, for development only
+	SignerKeystore   = "keystore"    // encrypted go-ethereum keystore JSON file
+	SignerExternal   = "external"    // Clef-style JSON-RPC signer (account_signTransaction)
+	SignerHTTP       = "http"        // generic HTTP signing service
+)
+
+// Signer signs gateway transactions. Implementations keep the key material wherever
+// they like; the client only ever sees the sending address and signed transactions.
+type Signer interface {
+	// Address is the account transactions are sent from
+	Address() common.Address
+	// SignTx returns tx signed for chainID by Address
+	SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+// NewSignerFromConfig builds the signer selected by cfg.SignerType. An empty type falls
+// back to the private key signer for existing PRIVATE_KEY deployments.
+func NewSignerFromConfig(ctx context.Context, cfg *config.Config) (Signer, error) {
+	switch cfg.SignerType {
+	case "", SignerPrivateKey:
+		if cfg.PrivateKey == "" {
+			return nil, errors.New("PRIVATE_KEY is required for the private_key signer")
+		}
+		return NewPrivateKeySigner(cfg.PrivateKey)
+
+	case SignerKeystore:
+		if cfg.KeystorePath == "" {
+			return nil, errors.New("KEYSTORE_PATH is required for the keystore signer")
+		}
+		passphrase := cfg.KeystorePassword
+		if cfg.KeystorePasswordFile != "" {
+			contents, err := os.ReadFile(cfg.KeystorePasswordFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read keystore password file: %w", err)
+			}
+			passphrase = strings.TrimRight(string(contents), "\r\n")
+		}
+		return NewKeystoreSigner(cfg.KeystorePath, passphrase)
+
+	case SignerExternal:
+		if cfg.SignerURL == "" || cfg.SignerAddress == "" {
+			return nil, errors.New("SIGNER_URL and SIGNER_ADDRESS are required for the external signer")
+		}
+		return NewExternalSigner(ctx, cfg.SignerURL, common.HexToAddress(cfg.SignerAddress))
+
+	case SignerHTTP:
+		if cfg.SignerURL == "" || cfg.SignerAddress == "" {
+			return nil, errors.New("SIGNER_URL and SIGNER_ADDRESS are required for the http signer")
+		}
+		return NewHTTPSigner(cfg.SignerURL, common.HexToAddress(cfg.SignerAddress), cfg.SignerAuthToken), nil
+
+	default:
+		return nil, fmt.Errorf("unknown signer type %q", cfg.SignerType)
+	}
+}
+
+// keySigner signs with an in-process ECDSA key
+type keySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+// NewPrivateKeySigner signs with a raw hex private key ("0x" prefix optional)
+func NewPrivateKeySigner(hexKey string) (Signer, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(hexKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	return &keySigner{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}, nil
+}
+
+// NewKeystoreSigner decrypts a go-ethereum keystore JSON file with passphrase
+func NewKeystoreSigner(path, passphrase string) (Signer, error) {
+	keyJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore file: %w", err)
+	}
+
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore file: %w", err)
+	}
+	return &keySigner{key: key.PrivateKey, address: key.Address}, nil
+}
+
+func (s *keySigner) Address() common.Address {
+	return s.address
+}
+
+func (s *keySigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
+
+// ExternalSigner asks a Clef-style JSON-RPC signer to sign with account_signTransaction
+type ExternalSigner struct {
+	client  *rpc.Client
+	address common.Address
+}
+
+// NewExternalSigner connects to the signer at endpoint (http(s), ws(s) or an IPC path)
+// and checks that it manages address
+func NewExternalSigner(ctx context.Context, endpoint string, address common.Address) (*ExternalSigner, error) {
+	client, err := rpc.DialContext(ctx, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to external signer: %w", err)
+	}
+
+	var accounts []common.Address
+	if err := client.CallContext(ctx, &accounts, "account_list"); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to list external signer accounts: %w", err)
+	}
+	for _, account := range accounts {
+		if account == address {
+			return &ExternalSigner{client: client, address: address}, nil
+		}
+	}
+
+	client.Close()
+	return nil, fmt.Errorf("external signer does not manage %s", address.Hex())
+}
+
+func (s *ExternalSigner) Address() common.Address {
+	return s.address
+}
+
+func (s *ExternalSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	data := hexutil.Bytes(tx.Data())
+	args := apitypes.SendTxArgs{
+		From:    common.NewMixedcaseAddress(s.address),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   hexutil.Big(*tx.Value()),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		Input:   &data,
+		ChainID: (*hexutil.Big)(chainID),
+	}
+	if tx.To() != nil {
+		to := common.NewMixedcaseAddress(*tx.To())
+		args.To = &to
+	}
+
+	switch tx.Type() {
+	case types.LegacyTxType:
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	case types.DynamicFeeTxType:
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+		accessList := tx.AccessList()
+		args.AccessList = &accessList
+	default:
+		return nil, fmt.Errorf("unsupported transaction type %d", tx.Type())
+	}
+
+	var result struct {
+		Raw hexutil.Bytes      `json:"raw"`
+		Tx  *types.Transaction `json:"tx"`
+	}
+	if err := s.client.CallContext(ctx, &result, "account_signTransaction", args); err != nil {
+		return nil, fmt.Errorf("external signer refused transaction: %w", err)
+	}
+	if result.Tx == nil {
+		return nil, errors.New("external signer returned no transaction")
+	}
+
+	return checkSigned(tx, result.Tx, chainID, s.address)
+}
+
+// Close disconnects from the signer
+func (s *ExternalSigner) Close() {
+	s.client.Close()
+}
+
+// HTTPSigner posts unsigned transactions to a signing service, such as a KMS-backed
+// proxy. The request and response are JSON:
+//
+//	POST <url>  {"address": "0x..", "chain_id": "11155111", "tx": "0x<unsigned tx>"}
+//	200         {"signed_tx": "0x<signed tx>"}
+//
+// Transactions use the go-ethereum binary encoding (RLP, typed envelope for EIP-1559).
+type HTTPSigner struct {
+	url        string
+	address    common.Address
+	authToken  string
+	httpClient *http.Client
+}
+
+// NewHTTPSigner creates a signer for the service at url. A non-empty authToken is sent
+// as a bearer token.
+func NewHTTPSigner(url string, address common.Address, authToken string) *HTTPSigner {
+	return &HTTPSigner{
+		url:        url,
+		address:    address,
+		authToken:  authToken,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *HTTPSigner) Address() common.Address {
+	return s.address
+}
+
+func (s *HTTPSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	unsigned, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transaction: %w", err)
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"address":  s.address.Hex(),
+		"chain_id": chainID.String(),
+		"tx":       hexutil.Encode(unsigned),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode signing request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signing request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.authToken)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("signing request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("signing service returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var result struct {
+		SignedTx hexutil.Bytes `json:"signed_tx"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode signing response: %w", err)
+	}
+
+	var signed types.Transaction
+	if err := signed.UnmarshalBinary(result.SignedTx); err != nil {
+		return nil, fmt.Errorf("failed to decode signed transaction: %w", err)
+	}
+
+	return checkSigned(tx, &signed, chainID, s.address)
+}
+
+// checkSigned verifies that a remote signer signed exactly the transaction it was given,
+// for the right chain, with the expected account
+func checkSigned(unsigned, signed *types.Transaction, chainID *big.Int, address common.Address) (*types.Transaction, error) {
+	signer := types.LatestSignerForChainID(chainID)
+	if signer.Hash(signed) != signer.Hash(unsigned) {
+		return nil, errors.New("signer returned a different transaction than requested")
+	}
+
+	sender, err := types.Sender(signer, signed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover signer: %w", err)
+	}
+	if sender != address {
+		return nil, fmt.Errorf("transaction was signed by %s, expected %s", sender.Hex(), address.Hex())
+	}
+	return signed, nil
+}
+
+// FakeSigner signs with a throwaway key and records every transaction it signs.
+// It is meant for tests.
+type FakeSigner struct {
+	keySigner
+
+	mu     sync.Mutex
+	signed []*types.Transaction
+	err    error
+}
+
+// NewFakeSigner creates a fake signer with a freshly generated key
+func NewFakeSigner() *FakeSigner {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		panic(fmt.Sprintf("failed to generate key: %v", err))
+	}
+	return &FakeSigner{keySigner: keySigner{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}}
+}
+
+// Key returns the fake signer's private key, e.g. to fund it in a simulated backend
+func (s *FakeSigner) Key() *ecdsa.PrivateKey {
+	return s.key
+}
+
+// FailWith makes subsequent SignTx calls return err; nil restores signing
+func (s *FakeSigner) FailWith(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// Signed returns the transactions signed so far
+func (s *FakeSigner) Signed() []*types.Transaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*types.Transaction(nil), s.signed...)
+}
+
+func (s *FakeSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	signed, err := s.keySigner.SignTx(ctx, tx, chainID)
+	if err != nil {
+		return nil, err
+	}
+	s.signed = append(s.signed, signed)
+	return signed, nil
+}
@@ -0,0 +1,254 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+func testTx() *types.Transaction {
+	to := common.HexToAddress("0x000000000000000000000000000000000000dEaD")
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(11155111),
+		Nonce:     7,
+		GasTipCap: big.NewInt(1e9),
+		GasFeeCap: big.NewInt(2e9),
+		Gas:       21000,
+		To:        &to,
+		Value:     big.NewInt(1),
+	})
+}
+
+func assertSignedBy(t *testing.T, tx *types.Transaction, chainID *big.Int, want common.Address) {
+	t.Helper()
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), tx)
+	if err != nil {
+		t.Fatalf("failed to recover sender: %v", err)
+	}
+	if sender != want {
+		t.Errorf("signed by %s, want %s", sender.Hex(), want.Hex())
+	}
+}
+
+func TestKeystoreSigner(t *testing.T) {
+	fake := NewFakeSigner()
+	ks := keystore.NewKeyStore(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.ImportECDSA(fake.Key(), "passphrase")
+	if err != nil {
+		t.Fatalf("failed to import key: %v", err)
+	}
+	path := account.URL.Path
+
+	if _, err := NewKeystoreSigner(path, "wrong"); err == nil {
+		t.Error("expected the wrong passphrase to be rejected")
+	}
+
+	signer, err := NewKeystoreSigner(path, "passphrase")
+	if err != nil {
+		t.Fatalf("NewKeystoreSigner() error = %v", err)
+	}
+	if signer.Address() != fake.Address() {
+		t.Errorf("Address() = %s, want %s", signer.Address().Hex(), fake.Address().Hex())
+	}
+
+	chainID := big.NewInt(11155111)
+	signed, err := signer.SignTx(context.Background(), testTx(), chainID)
+	if err != nil {
+		t.Fatalf("SignTx() error = %v", err)
+	}
+	assertSignedBy(t, signed, chainID, fake.Address())
+}
+
+func TestHTTPSigner(t *testing.T) {
+	backend := NewFakeSigner()
+	chainID := big.NewInt(11155111)
+
+	tamper := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			Tx hexutil.Bytes `json:"tx"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var tx types.Transaction
+		if err := tx.UnmarshalBinary(req.Tx); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		toSign := &tx
+		if tamper {
+			toSign = types.NewTx(&types.DynamicFeeTx{
+				ChainID: chainID, Nonce: tx.Nonce(), GasTipCap: tx.GasTipCap(), GasFeeCap: tx.GasFeeCap(),
+				Gas: tx.Gas(), To: &common.Address{}, Value: big.NewInt(1e18),
+			})
+		}
+
+		signed, err := backend.SignTx(r.Context(), toSign, chainID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		raw, _ := signed.MarshalBinary()
+		json.NewEncoder(w).Encode(map[string]hexutil.Bytes{"signed_tx": raw})
+	}))
+	defer server.Close()
+
+	signer := NewHTTPSigner(server.URL, backend.Address(), "token")
+	signed, err := signer.SignTx(context.Background(), testTx(), chainID)
+	if err != nil {
+		t.Fatalf("SignTx() error = %v", err)
+	}
+	assertSignedBy(t, signed, chainID, backend.Address())
+
+	tamper = true
+	if _, err := signer.SignTx(context.Background(), testTx(), chainID); err == nil {
+		t.Error("expected a transaction altered by the signing service to be rejected")
+	}
+
+	if _, err := NewHTTPSigner(server.URL, backend.Address(), "").SignTx(context.Background(), testTx(), chainID); err == nil {
+		t.Error("expected an unauthorized signing request to fail")
+	}
+}
+
+// clefServer answers account_list and account_signTransaction the way Clef does, signing
+// with backend. sign may alter the request's arguments, key and chain before signing.
+func clefServer(t *testing.T, backend *FakeSigner, requests chan<- map[string]any, sign func(*apitypes.SendTxArgs) (Signer, *big.Int)) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var call struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&call); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var result any
+		switch call.Method {
+		case "account_list":
+			result = []common.Address{backend.Address()}
+		case "account_signTransaction":
+			var shape map[string]any
+			json.Unmarshal(call.Params[0], &shape)
+			requests <- shape
+
+			var args apitypes.SendTxArgs
+			if err := json.Unmarshal(call.Params[0], &args); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			key, chainID := sign(&args)
+			tx, err := args.ToTransaction()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			signed, err := key.SignTx(r.Context(), tx, chainID)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			raw, _ := signed.MarshalBinary()
+			result = map[string]any{"raw": hexutil.Bytes(raw), "tx": signed}
+		default:
+			json.NewEncoder(w).Encode(map[string]any{
+				"jsonrpc": "2.0", "id": call.ID,
+				"error": map[string]any{"code": -32601, "message": "method not found"},
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": call.ID, "result": result})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestExternalSigner(t *testing.T) {
+	backend := NewFakeSigner()
+	chainID := big.NewInt(11155111)
+	ctx := context.Background()
+
+	var sign func(*apitypes.SendTxArgs) (Signer, *big.Int)
+	requests := make(chan map[string]any, 1)
+	server := clefServer(t, backend, requests, func(args *apitypes.SendTxArgs) (Signer, *big.Int) {
+		return sign(args)
+	})
+
+	if _, err := NewExternalSigner(ctx, server.URL, common.HexToAddress("0x01")); err == nil {
+		t.Error("expected an account the signer does not manage to be rejected")
+	}
+	signer, err := NewExternalSigner(ctx, server.URL, backend.Address())
+	if err != nil {
+		t.Fatalf("NewExternalSigner() error = %v", err)
+	}
+	defer signer.Close()
+
+	sign = func(*apitypes.SendTxArgs) (Signer, *big.Int) { return backend, chainID }
+	signed, err := signer.SignTx(ctx, testTx(), chainID)
+	if err != nil {
+		t.Fatalf("SignTx() error = %v", err)
+	}
+	assertSignedBy(t, signed, chainID, backend.Address())
+	if local, _ := backend.SignTx(ctx, testTx(), chainID); signed.Hash() != local.Hash() {
+		t.Errorf("signed transaction %s, want %s", signed.Hash().Hex(), local.Hash().Hex())
+	}
+
+	// The request carries the EIP-1559 fields Clef expects, not a gas price
+	request := <-requests
+	want := map[string]any{
+		"from":                 backend.Address().Hex(),
+		"to":                   "0x000000000000000000000000000000000000dEaD",
+		"gas":                  "0x5208",
+		"maxFeePerGas":         "0x77359400",
+		"maxPriorityFeePerGas": "0x3b9aca00",
+		"value":                "0x1",
+		"nonce":                "0x7",
+		"input":                "0x",
+		"chainId":              "0xaa36a7",
+	}
+	for field, value := range want {
+		if request[field] != value {
+			t.Errorf("account_signTransaction %s = %v, want %v", field, request[field], value)
+		}
+	}
+	if request["gasPrice"] != nil {
+		t.Errorf("account_signTransaction gasPrice = %v, want none for a dynamic fee transaction", request["gasPrice"])
+	}
+
+	// Signatures by another key or for another chain are rejected
+	sign = func(*apitypes.SendTxArgs) (Signer, *big.Int) { return NewFakeSigner(), chainID }
+	if _, err := signer.SignTx(ctx, testTx(), chainID); err == nil || !strings.Contains(err.Error(), "signed by") {
+		t.Errorf("SignTx() with another key error = %v, want it rejected", err)
+	}
+	<-requests
+
+	sign = func(args *apitypes.SendTxArgs) (Signer, *big.Int) {
+		args.ChainID = (*hexutil.Big)(big.NewInt(1))
+		return backend, big.NewInt(1)
+	}
+	if _, err := signer.SignTx(ctx, testTx(), chainID); !errors.Is(err, types.ErrInvalidChainId) {
+		t.Errorf("SignTx() for another chain error = %v, want ErrInvalidChainId", err)
+	}
+	<-requests
+}
@@ -0,0 +1,133 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/fahedafzaal/go-integration/contracts"
+)
+
+// The methods below split sending into sign, broadcast and track steps so a durable
+// caller can persist the signed transaction before it reaches the network.
+
+// PrepareMarkJobCompleted signs, but does not send, a MarkJobCompleted transaction.
+// Its nonce stays reserved until BroadcastTransaction or DiscardTransaction.
+func (c *Client) PrepareMarkJobCompleted(ctx context.Context, jobID uint64) (*types.Transaction, error) {
+	return c.prepare(ctx, func(auth *bind.TransactOpts) (*types.Transaction, error) {
+		return c.contract.MarkJobCompleted(auth, big.NewInt(int64(jobID)))
+	})
+}
+
+// PrepareCancelJob signs, but does not send, a CancelJob transaction.
+// Its nonce stays reserved until BroadcastTransaction or DiscardTransaction.
+func (c *Client) PrepareCancelJob(ctx context.Context, jobID uint64) (*types.Transaction, error) {
+	return c.prepare(ctx, func(auth *bind.TransactOpts) (*types.Transaction, error) {
+		return c.contract.CancelJob(auth, big.NewInt(int64(jobID)))
+	})
+}
+
+// SimulateMarkJobCompleted runs MarkJobCompleted as a call against the latest block.
+// A contract rejection is returned as one of the ErrJob* sentinels.
+func (c *Client) SimulateMarkJobCompleted(ctx context.Context, jobID uint64) error {
+	return c.simulate(ctx, "markJobCompleted", big.NewInt(int64(jobID)))
+}
+
+// SimulateCancelJob runs CancelJob as a call against the latest block.
+// A contract rejection is returned as one of the ErrJob* sentinels.
+func (c *Client) SimulateCancelJob(ctx context.Context, jobID uint64) error {
+	return c.simulate(ctx, "cancelJob", big.NewInt(int64(jobID)))
+}
+
+func (c *Client) simulate(ctx context.Context, method string, args ...interface{}) error {
+	parsed, err := contracts.EthJobEscrowMetaData.GetAbi()
+	if err != nil {
+		return fmt.Errorf("failed to parse contract ABI: %w", err)
+	}
+
+	input, err := parsed.Pack(method, args...)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s call: %w", method, err)
+	}
+
+	msg := ethereum.CallMsg{
+		From: c.publicAddress,
+		To:   &c.contractAddress,
+		Data: input,
+	}
+	if _, err := c.ethClient.CallContract(ctx, msg, nil); err != nil {
+		return fmt.Errorf("%s would fail: %w", method, parseCallError(err))
+	}
+	return nil
+}
+
+func (c *Client) prepare(ctx context.Context, call func(*bind.TransactOpts) (*types.Transaction, error)) (*types.Transaction, error) {
+	auth, err := c.GetAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+	auth.NoSend = true
+
+	tx, err := call(auth)
+	if err != nil {
+		c.nonces.Release(ctx, c.publicAddress, auth.Nonce.Uint64())
+		return nil, fmt.Errorf("failed to build transaction: %w", parseCallError(err))
+	}
+	return tx, nil
+}
+
+// BroadcastTransaction sends a signed transaction. Re-broadcasting a transaction the
+// node already has is not an error, so the call is safe to repeat after a crash.
+func (c *Client) BroadcastTransaction(ctx context.Context, tx *types.Transaction) error {
+	if err := c.SendSignedTransaction(ctx, tx); err != nil {
+		return err
+	}
+
+	c.nonces.MarkSent(ctx, c.publicAddress, tx.Nonce(), tx.Hash())
+	return nil
+}
+
+// SendSignedTransaction broadcasts a transaction signed outside the gateway, such as one
+// from a client's wallet. Its nonce is not the gateway's, so no reservation is touched.
+// Like BroadcastTransaction it is safe to repeat.
+func (c *Client) SendSignedTransaction(ctx context.Context, tx *types.Transaction) error {
+	err := c.ethClient.SendTransaction(ctx, tx)
+	if err != nil && !strings.Contains(err.Error(), "already known") {
+		return fmt.Errorf("failed to broadcast transaction %s: %w", tx.Hash().Hex(), err)
+	}
+	return nil
+}
+
+// DiscardTransaction releases the nonce of a prepared transaction that will never be sent
+func (c *Client) DiscardTransaction(ctx context.Context, tx *types.Transaction) {
+	c.nonces.Release(ctx, c.publicAddress, tx.Nonce())
+}
+
+// TransactionKnown reports whether the node has the transaction, mined or in its mempool
+func (c *Client) TransactionKnown(ctx context.Context, txHash common.Hash) (bool, error) {
+	_, _, err := c.ethClient.TransactionByHash(ctx, txHash)
+	if errors.Is(err, ethereum.NotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get transaction %s: %w", txHash.Hex(), err)
+	}
+	return true, nil
+}
+
+// TransactionReceipt returns the receipt of a mined transaction, or ethereum.NotFound
+func (c *Client) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return c.ethClient.TransactionReceipt(ctx, txHash)
+}
+
+// RevertReason returns the decoded revert reason of a failed transaction
+func (c *Client) RevertReason(ctx context.Context, txHash common.Hash) string {
+	return c.getRevertReason(ctx, txHash)
+}
@@ -0,0 +1,115 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// UnsignedTx is an EIP-1559 transaction for a wallet to sign and send. Its JSON form uses
+// the field names and hex encoding of eth_sendTransaction parameters.
+type UnsignedTx struct {
+	Type                 hexutil.Uint64 `json:"type"`
+	From                 common.Address `json:"from"`
+	To                   common.Address `json:"to"`
+	Value                *hexutil.Big   `json:"value"`
+	Data                 hexutil.Bytes  `json:"data"`
+	ChainID              *hexutil.Big   `json:"chainId"`
+	Gas                  hexutil.Uint64 `json:"gas"`
+	MaxFeePerGas         *hexutil.Big   `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big   `json:"maxPriorityFeePerGas"`
+	Nonce                hexutil.Uint64 `json:"nonce"` // pending nonce of From when built; the wallet may override it
+}
+
+// Transaction returns the unsigned transaction as a go-ethereum transaction
+func (u *UnsignedTx) Transaction() *types.Transaction {
+	to := u.To
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   u.ChainID.ToInt(),
+		Nonce:     uint64(u.Nonce),
+		GasTipCap: u.MaxPriorityFeePerGas.ToInt(),
+		GasFeeCap: u.MaxFeePerGas.ToInt(),
+		Gas:       uint64(u.Gas),
+		To:        &to,
+		Value:     u.Value.ToInt(),
+		Data:      u.Data,
+	})
+}
+
+// MarshalRLP returns the EIP-2718 encoding of the unsigned transaction:
+// 0x02 || rlp([chainId, nonce, maxPriorityFeePerGas, maxFeePerGas, gas, to, value, data, accessList]).
+// Its keccak256 hash is the digest the sender signs.
+func (u *UnsignedTx) MarshalRLP() ([]byte, error) {
+	payload, err := rlp.EncodeToBytes([]interface{}{
+		u.ChainID.ToInt(),
+		uint64(u.Nonce),
+		u.MaxPriorityFeePerGas.ToInt(),
+		u.MaxFeePerGas.ToInt(),
+		uint64(u.Gas),
+		u.To,
+		u.Value.ToInt(),
+		[]byte(u.Data),
+		types.AccessList{},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode unsigned transaction: %w", err)
+	}
+	return append([]byte{types.DynamicFeeTxType}, payload...), nil
+}
+
+// BuildUnsignedTx prices a call from an external wallet: the gas limit is estimated as
+// sent from from, fees follow the same EIP-1559 pricing as the gateway's own transactions
+// and the nonce is from's pending nonce. A call that would revert is returned as one of
+// the ErrJob* sentinels.
+func (c *Client) BuildUnsignedTx(ctx context.Context, from, to common.Address, value *big.Int, data []byte) (*UnsignedTx, error) {
+	chainID, err := c.ethClient.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain ID: %w", err)
+	}
+
+	block, err := c.ethClient.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest block: %w", err)
+	}
+	if block.BaseFee == nil {
+		return nil, fmt.Errorf("network does not support EIP-1559 transactions")
+	}
+	tipCap, feeCap, err := c.dynamicFees(ctx, block.BaseFee)
+	if err != nil {
+		return nil, err
+	}
+
+	gas, err := c.ethClient.EstimateGas(ctx, ethereum.CallMsg{
+		From:  from,
+		To:    &to,
+		Value: value,
+		Data:  data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate gas: %w", parseCallError(err))
+	}
+
+	nonce, err := c.ethClient.PendingNonceAt(ctx, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending nonce: %w", err)
+	}
+
+	return &UnsignedTx{
+		Type:                 types.DynamicFeeTxType,
+		From:                 from,
+		To:                   to,
+		Value:                (*hexutil.Big)(value),
+		Data:                 data,
+		ChainID:              (*hexutil.Big)(chainID),
+		Gas:                  hexutil.Uint64(gas),
+		MaxFeePerGas:         (*hexutil.Big)(feeCap),
+		MaxPriorityFeePerGas: (*hexutil.Big)(tipCap),
+		Nonce:                hexutil.Uint64(nonce),
+	}, nil
+}
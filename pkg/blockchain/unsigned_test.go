@@ -0,0 +1,69 @@
+package blockchain
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/fahedafzaal/go-integration/pkg/money"
+)
+
+func TestBuildPostJobTxFromClientWallet(t *testing.T) {
+	t.Parallel()
+	env := newEscrowEnv(t)
+	const jobID = 404
+
+	// The stranger is funded and takes the part of a client paying from their own wallet
+	clientWallet := env.stranger.signer
+	req := PostJobRequest{
+		JobID:             jobID,
+		FreelancerAddress: env.freelancer.Hex(),
+		USDAmount:         money.MustParseUSD("250"),
+		ClientAddress:     clientWallet.Address().Hex(),
+	}
+
+	unsigned, err := env.service().BuildPostJobTx(env.ctx, req)
+	if err != nil {
+		t.Fatalf("BuildPostJobTx: %v", err)
+	}
+	if unsigned.To != env.escrow || unsigned.From != clientWallet.Address() {
+		t.Errorf("from %s to %s, want from the client to the escrow", unsigned.From.Hex(), unsigned.To.Hex())
+	}
+	if want := big.NewInt(125e15); unsigned.Value.ToInt().Cmp(want) != 0 {
+		t.Errorf("value = %s, want %s", unsigned.Value.ToInt(), want)
+	}
+	if unsigned.Gas == 0 || unsigned.MaxFeePerGas.ToInt().Cmp(unsigned.MaxPriorityFeePerGas.ToInt()) < 0 {
+		t.Errorf("gas = %d, fees = %s/%s; want an estimate and max fee >= tip",
+			unsigned.Gas, unsigned.MaxFeePerGas.ToInt(), unsigned.MaxPriorityFeePerGas.ToInt())
+	}
+
+	// Underfunding is caught when estimating, before the wallet is asked to sign
+	_, err = env.stranger.BuildUnsignedTx(env.ctx, clientWallet.Address(), env.escrow, big.NewInt(1), unsigned.Data)
+	if !errors.Is(err, ErrInsufficientEthSent) {
+		t.Errorf("underfunded BuildUnsignedTx error = %v, want ErrInsufficientEthSent", err)
+	}
+
+	// The RLP form hashes to the digest the wallet signs
+	raw, err := unsigned.MarshalRLP()
+	if err != nil {
+		t.Fatalf("MarshalRLP: %v", err)
+	}
+	tx := unsigned.Transaction()
+	chainID := unsigned.ChainID.ToInt()
+	if got, want := crypto.Keccak256Hash(raw), types.LatestSignerForChainID(chainID).Hash(tx); got != want {
+		t.Fatalf("keccak256(rlp) = %s, want signing hash %s", got.Hex(), want.Hex())
+	}
+
+	signed, err := clientWallet.SignTx(env.ctx, tx, chainID)
+	if err != nil {
+		t.Fatalf("SignTx: %v", err)
+	}
+	if err := env.eth.SendTransaction(env.ctx, signed); err != nil {
+		t.Fatalf("SendTransaction: %v", err)
+	}
+	env.waitMined(signed)
+	env.requireStatus(jobID, "deposited")
+}
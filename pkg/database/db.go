@@ -2,25 +2,38 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/fahedafzaal/go-integration/pkg/money"
+	"github.com/fahedafzaal/go-integration/pkg/payment"
 )
 
 type DB struct {
 	Pool *pgxpool.Pool
 }
 
+var (
+	// ErrApplicationNotFound is returned when no application has the requested id
+	ErrApplicationNotFound = errors.New("application not found")
+	// ErrWalletNotVerified is returned when a wallet address has no ownership proof
+	ErrWalletNotVerified = errors.New("wallet address not verified")
+)
+
 // ApplicationPaymentDetails represents payment-related data from your existing schema
 type ApplicationPaymentDetails struct {
 	ApplicationID          int32
 	JobID                  int32
 	ApplicantUserID        int32
 	PosterUserID           int32
-	AgreedUSDAmount        *int32
-	PaymentStatus          string
+	AgreedUSDAmount        *money.USD // the main application stores whole dollars
+	PaymentStatus          payment.Status
 	EscrowJobID            *int32
+	EscrowChainID          *int64 // chain the escrow job is on; nil until a deposit is queued
 	EscrowTxHashDeposit    *string
 	EscrowTxHashRelease    *string
 	EscrowTxHashRefund     *string
@@ -55,6 +68,7 @@ func (db *DB) GetApplicationPaymentDetails(ctx context.Context, applicationID in
 			a.agreed_usd_amount,
 			COALESCE(a.payment_status, 'pending_deposit') as payment_status,
 			a.escrow_job_id,
+			a.escrow_chain_id,
 			a.escrow_tx_hash_deposit,
 			a.escrow_tx_hash_release,
 			a.escrow_tx_hash_refund,
@@ -77,6 +91,7 @@ func (db *DB) GetApplicationPaymentDetails(ctx context.Context, applicationID in
 		&details.AgreedUSDAmount,
 		&details.PaymentStatus,
 		&details.EscrowJobID,
+		&details.EscrowChainID,
 		&details.EscrowTxHashDeposit,
 		&details.EscrowTxHashRelease,
 		&details.EscrowTxHashRefund,
@@ -84,6 +99,9 @@ func (db *DB) GetApplicationPaymentDetails(ctx context.Context, applicationID in
 		&details.PosterWalletAddress,
 		&details.ApplicationStatus,
 	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrApplicationNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error querying application payment details: %v", err)
 	}
@@ -91,62 +109,69 @@ func (db *DB) GetApplicationPaymentDetails(ctx context.Context, applicationID in
 	return details, nil
 }
 
-// UpdatePaymentStatus updates the payment status and transaction hash
-func (db *DB) UpdatePaymentStatus(ctx context.Context, applicationID int32, status string, txHash *string, txType string) error {
-	var query string
-	var args []interface{}
+// UpdatePaymentStatus updates the payment status and transaction hash, attributing the
+// change to origin in the payment ledger. A status the application cannot move to from
+// its current one is rejected with a *payment.TransitionError and nothing is written.
+func (db *DB) UpdatePaymentStatus(ctx context.Context, applicationID int32, status payment.Status, txHash *string, txType string, origin Origin) error {
+	var column string
 
 	switch txType {
 	case "deposit":
-		query = `
-			UPDATE applications 
-			SET payment_status = $1, escrow_tx_hash_deposit = $2
-			WHERE id = $3
-		`
-		args = []interface{}{status, txHash, applicationID}
+		column = "escrow_tx_hash_deposit"
 	case "release":
-		query = `
-			UPDATE applications 
-			SET payment_status = $1, escrow_tx_hash_release = $2
-			WHERE id = $3
-		`
-		args = []interface{}{status, txHash, applicationID}
+		column = "escrow_tx_hash_release"
 	case "refund":
-		query = `
-			UPDATE applications 
-			SET payment_status = $1, escrow_tx_hash_refund = $2
-			WHERE id = $3
-		`
-		args = []interface{}{status, txHash, applicationID}
-	default:
-		query = `
-			UPDATE applications 
-			SET payment_status = $1
-			WHERE id = $2
-		`
-		args = []interface{}{status, applicationID}
+		column = "escrow_tx_hash_refund"
 	}
 
-	_, err := db.Pool.Exec(ctx, query, args...)
+	tx, err := db.Pool.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("error updating payment status: %v", err)
+		return fmt.Errorf("failed to begin transaction: %v", err)
 	}
+	defer tx.Rollback(ctx)
 
-	return nil
+	// The hash goes first so the webhook event snapshot includes it
+	if column != "" {
+		query := fmt.Sprintf(`UPDATE applications SET %s = $1 WHERE id = $2`, column)
+		if _, err := tx.Exec(ctx, query, txHash, applicationID); err != nil {
+			return fmt.Errorf("error updating payment status: %v", err)
+		}
+	}
+
+	cause := paymentEntry{origin: origin}
+	if txHash != nil {
+		cause.txHash = *txHash
+	}
+	if _, err := setPaymentStatus(ctx, tx, applicationID, status, cause); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
 }
 
-// ValidateApplicationForBlockchain checks if application is ready for blockchain operations
+// ValidateApplicationForBlockchain checks if application is ready for blockchain operations.
+// Both wallets must have been proven through BindWallet, since escrow sent to a mistyped
+// address cannot be recovered.
 func (db *DB) ValidateApplicationForBlockchain(ctx context.Context, applicationID int32) error {
 	var status string
 	var applicantWallet, posterWallet *string
-	var agreedAmount *int32
+	var agreedAmount *money.USD
+	var applicantVerified, posterVerified bool
 
 	query := `
 		SELECT 
 			a.status,
 			a.agreed_usd_amount,
 			applicant.wallet_address as applicant_wallet,
-			poster.wallet_address as poster_wallet
+			poster.wallet_address as poster_wallet,
+			EXISTS (
+				SELECT 1 FROM wallet_bindings b
+				WHERE b.user_id = applicant.id AND b.address = LOWER(applicant.wallet_address)
+			) as applicant_verified,
+			EXISTS (
+				SELECT 1 FROM wallet_bindings b
+				WHERE b.user_id = poster.id AND b.address = LOWER(poster.wallet_address)
+			) as poster_verified
 		FROM applications a
 		JOIN jobs j ON a.job_id = j.id
 		JOIN users applicant ON a.user_id = applicant.id
@@ -159,6 +184,8 @@ func (db *DB) ValidateApplicationForBlockchain(ctx context.Context, applicationI
 		&agreedAmount,
 		&applicantWallet,
 		&posterWallet,
+		&applicantVerified,
+		&posterVerified,
 	)
 	if err != nil {
 		return fmt.Errorf("application not found: %v", err)
@@ -172,7 +199,15 @@ func (db *DB) ValidateApplicationForBlockchain(ctx context.Context, applicationI
 		return fmt.Errorf("poster wallet address not set")
 	}
 
-	if agreedAmount == nil || *agreedAmount <= 0 {
+	if !applicantVerified {
+		return fmt.Errorf("applicant %w", ErrWalletNotVerified)
+	}
+
+	if !posterVerified {
+		return fmt.Errorf("poster %w", ErrWalletNotVerified)
+	}
+
+	if agreedAmount == nil || agreedAmount.IsZero() {
 		return fmt.Errorf("agreed USD amount not set or invalid")
 	}
 
@@ -209,8 +244,8 @@ func (db *DB) CheckEscrowIdempotency(ctx context.Context, applicationID int32) (
 }
 
 // AtomicStartEscrowDeposit atomically marks an application as having escrow deposit initiated
-// This prevents race conditions from duplicate calls
-func (db *DB) AtomicStartEscrowDeposit(ctx context.Context, applicationID int32, txHash string) error {
+// on chainID. This prevents race conditions from duplicate calls
+func (db *DB) AtomicStartEscrowDeposit(ctx context.Context, chainID int64, applicationID int32, txHash string, origin Origin) error {
 	// Use a transaction to ensure atomicity
 	tx, err := db.Pool.Begin(ctx)
 	if err != nil {
@@ -218,17 +253,24 @@ func (db *DB) AtomicStartEscrowDeposit(ctx context.Context, applicationID int32,
 	}
 	defer tx.Rollback(ctx)
 
+	if err := startEscrowDeposit(ctx, tx, chainID, applicationID, txHash, origin); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func startEscrowDeposit(ctx context.Context, tx pgx.Tx, chainID int64, applicationID int32, txHash string, origin Origin) error {
 	// Check current state and update only if still pending
 	query := `
 		UPDATE applications 
-		SET payment_status = 'deposit_initiated', 
-		    escrow_tx_hash_deposit = $2
+		SET escrow_tx_hash_deposit = $2, escrow_chain_id = $3
 		WHERE id = $1 
 		AND (payment_status = 'pending_deposit' OR payment_status IS NULL OR payment_status = '')
 		AND (escrow_tx_hash_deposit IS NULL OR escrow_tx_hash_deposit = '')
+		AND (escrow_chain_id IS NULL OR escrow_chain_id = $3)
 	`
 
-	result, err := tx.Exec(ctx, query, applicationID, txHash)
+	result, err := tx.Exec(ctx, query, applicationID, txHash, chainID)
 	if err != nil {
 		return fmt.Errorf("error updating application for escrow deposit: %v", err)
 	}
@@ -255,7 +297,48 @@ func (db *DB) AtomicStartEscrowDeposit(ctx context.Context, applicationID int32,
 		return fmt.Errorf("failed to initiate escrow deposit - application may be in wrong state")
 	}
 
-	return tx.Commit(ctx)
+	_, err = setPaymentStatus(ctx, tx, applicationID, payment.DepositInitiated, paymentEntry{origin: origin, txHash: txHash}, payment.PendingDeposit)
+	return err
+}
+
+// AssignDefaultChain puts rows written before the gateway served several chains on
+// chainID, the chain it served then: applications past pending_deposit, unfinished
+// transaction intents, price quotes and the signer's nonces. It returns the number of
+// applications assigned.
+func (db *DB) AssignDefaultChain(ctx context.Context, chainID int64) (int64, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE applications SET escrow_chain_id = $1
+		WHERE escrow_chain_id IS NULL
+		  AND COALESCE(NULLIF(payment_status, ''), 'pending_deposit') <> 'pending_deposit'
+	`, chainID)
+	if err != nil {
+		return 0, fmt.Errorf("error assigning applications to chain %d: %v", chainID, err)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE tx_intents SET chain_id = $1 WHERE chain_id IS NULL`, chainID); err != nil {
+		return 0, fmt.Errorf("error assigning transaction intents to chain %d: %v", chainID, err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE price_quotes SET chain_id = $1 WHERE chain_id IS NULL`, chainID); err != nil {
+		return 0, fmt.Errorf("error assigning price quotes to chain %d: %v", chainID, err)
+	}
+	// Migration 0012 stores nonces from before as chain 0
+	if _, err := tx.Exec(ctx, `UPDATE signer_nonces SET chain_id = $1 WHERE chain_id = 0`, chainID); err != nil {
+		return 0, fmt.Errorf("error assigning signer nonces to chain %d: %v", chainID, err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE nonce_reservations SET chain_id = $1 WHERE chain_id = 0`, chainID); err != nil {
+		return 0, fmt.Errorf("error assigning nonce reservations to chain %d: %v", chainID, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit chain assignment: %v", err)
+	}
+	return result.RowsAffected(), nil
 }
 
 // Close closes the database connection pool
@@ -0,0 +1,506 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/fahedafzaal/go-integration/pkg/payment"
+)
+
+// ChainEvent is an escrow contract event as consumed by the indexer.
+// EscrowJobID is the on-chain job id, which is the application id.
+type ChainEvent struct {
+	Name        string // JobPosted, JobCompleted, PaymentReleased, JobCancelled
+	ChainID     int64  // chain the contract emitting the event is on
+	EscrowJobID int32
+	TxHash      string
+	BlockNumber uint64
+	BlockHash   string
+	LogIndex    uint
+}
+
+// PendingBlock is a block holding events that have not reached confirmation depth yet
+type PendingBlock struct {
+	Number uint64
+	Hash   string
+}
+
+// chainEventTransition describes the payment status an escrow event moves an
+// application into and the statuses it may move it from. Applications already
+// past the target status are left alone, which makes re-applying an event a no-op.
+type chainEventTransition struct {
+	status payment.Status
+	from   []payment.Status
+}
+
+var chainEventTransitions = map[string]chainEventTransition{
+	"JobPosted":       {status: payment.Deposited, from: []payment.Status{payment.PendingDeposit, payment.DepositInitiated}},
+	"JobCompleted":    {status: payment.ReleaseInitiated, from: []payment.Status{payment.Deposited}},
+	"PaymentReleased": {status: payment.Released, from: []payment.Status{payment.Deposited, payment.ReleaseInitiated}},
+	"JobCancelled":    {status: payment.Refunded, from: []payment.Status{payment.Deposited, payment.RefundInitiated}},
+}
+
+// chainEventTxHashColumns maps events to the application column recording their tx hash
+var chainEventTxHashColumns = map[string]string{
+	"JobPosted":       "escrow_tx_hash_deposit",
+	"PaymentReleased": "escrow_tx_hash_release",
+	"JobCancelled":    "escrow_tx_hash_refund",
+}
+
+func (t chainEventTransition) allows(status payment.Status) bool {
+	return slices.Contains(t.from, status)
+}
+
+// chainEventCause attributes a payment status change to an escrow event
+func chainEventCause(event ChainEvent) paymentEntry {
+	return paymentEntry{origin: indexerOrigin, txHash: event.TxHash, blockNumber: event.BlockNumber, detail: event.Name}
+}
+
+// GetIndexerCursor returns the last block fully processed by the named indexer.
+// The boolean is false when the indexer has never run.
+func (db *DB) GetIndexerCursor(ctx context.Context, name string) (uint64, bool, error) {
+	var lastBlock int64
+	err := db.Pool.QueryRow(ctx, `SELECT last_block FROM indexer_cursors WHERE name = $1`, name).Scan(&lastBlock)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("error reading indexer cursor: %v", err)
+	}
+	return uint64(lastBlock), true, nil
+}
+
+// RenameIndexer moves the cursor and pending events of indexer from to indexer to. It
+// does nothing when from has no cursor or to already has one.
+func (db *DB) RenameIndexer(ctx context.Context, from, to string) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE indexer_cursors SET name = $2, updated_at = NOW()
+		WHERE name = $1 AND NOT EXISTS (SELECT 1 FROM indexer_cursors WHERE name = $2)
+	`, from, to)
+	if err != nil {
+		return fmt.Errorf("error renaming indexer cursor: %v", err)
+	}
+	if result.RowsAffected() == 0 {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE pending_chain_events SET indexer = $2 WHERE indexer = $1
+	`, from, to); err != nil {
+		return fmt.Errorf("error renaming pending chain events: %v", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit indexer rename: %v", err)
+	}
+
+	log.Printf("Indexer: cursor %s renamed to %s", from, to)
+	return nil
+}
+
+// ApplyChainEvents records a scanned block range in a single transaction, so a crash
+// can never leave a range half-applied or skipped. Events at or below finalizedBlock
+// move payment_status directly; newer events park the application in
+// pending_confirmations until FinalizeChainEvents or RollbackChainEvents resolves them.
+// The cursor is advanced to cursorBlock.
+func (db *DB) ApplyChainEvents(ctx context.Context, name string, events []ChainEvent, cursorBlock, finalizedBlock uint64) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, event := range events {
+		var staged bool
+		err := tx.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM pending_chain_events WHERE indexer = $1 AND tx_hash = $2 AND log_index = $3)
+		`, name, event.TxHash, int32(event.LogIndex)).Scan(&staged)
+		if err != nil {
+			return fmt.Errorf("error checking pending chain event: %v", err)
+		}
+		if staged {
+			continue // Already waiting for confirmations
+		}
+
+		if event.BlockNumber <= finalizedBlock {
+			err = applyChainEvent(ctx, tx, event)
+		} else {
+			err = stageChainEvent(ctx, tx, name, event)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO indexer_cursors (name, last_block, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (name) DO UPDATE SET last_block = GREATEST(indexer_cursors.last_block, EXCLUDED.last_block), updated_at = NOW()
+	`
+	if _, err := tx.Exec(ctx, query, name, int64(cursorBlock)); err != nil {
+		return fmt.Errorf("error saving indexer cursor: %v", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ListPendingBlocks returns the blocks holding unconfirmed events for the named indexer
+func (db *DB) ListPendingBlocks(ctx context.Context, name string) ([]PendingBlock, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT DISTINCT block_number, block_hash
+		FROM pending_chain_events
+		WHERE indexer = $1
+		ORDER BY block_number
+	`, name)
+	if err != nil {
+		return nil, fmt.Errorf("error listing pending blocks: %v", err)
+	}
+	defer rows.Close()
+
+	var blocks []PendingBlock
+	for rows.Next() {
+		var number int64
+		var block PendingBlock
+		if err := rows.Scan(&number, &block.Hash); err != nil {
+			return nil, fmt.Errorf("error scanning pending block: %v", err)
+		}
+		block.Number = uint64(number)
+		blocks = append(blocks, block)
+	}
+
+	return blocks, rows.Err()
+}
+
+// FinalizeChainEvents applies every pending event at or below finalizedBlock in chain order
+func (db *DB) FinalizeChainEvents(ctx context.Context, name string, finalizedBlock uint64) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	type pendingEvent struct {
+		id           int64
+		event        ChainEvent
+		targetStatus payment.Status
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, application_id, event_name, tx_hash, block_number, block_hash, log_index, target_status
+		FROM pending_chain_events
+		WHERE indexer = $1 AND block_number <= $2
+		ORDER BY block_number, log_index
+		FOR UPDATE
+	`, name, int64(finalizedBlock))
+	if err != nil {
+		return fmt.Errorf("error querying pending chain events: %v", err)
+	}
+
+	var pending []pendingEvent
+	for rows.Next() {
+		var p pendingEvent
+		var blockNumber int64
+		var logIndex int32
+		if err := rows.Scan(&p.id, &p.event.EscrowJobID, &p.event.Name, &p.event.TxHash,
+			&blockNumber, &p.event.BlockHash, &logIndex, &p.targetStatus); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning pending chain event: %v", err)
+		}
+		p.event.BlockNumber = uint64(blockNumber)
+		p.event.LogIndex = uint(logIndex)
+		pending = append(pending, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error reading pending chain events: %v", err)
+	}
+
+	for _, p := range pending {
+		if _, err := tx.Exec(ctx, `DELETE FROM pending_chain_events WHERE id = $1`, p.id); err != nil {
+			return fmt.Errorf("error removing pending chain event: %v", err)
+		}
+
+		if err := setChainEventTxHash(ctx, tx, p.event); err != nil {
+			return err
+		}
+
+		// Later events for the same application keep it in pending_confirmations, now
+		// waiting on the next of them
+		var nextTarget payment.Status
+		err := tx.QueryRow(ctx, `
+			SELECT target_status FROM pending_chain_events
+			WHERE indexer = $1 AND application_id = $2
+			ORDER BY block_number, log_index
+			LIMIT 1
+		`, name, p.event.EscrowJobID).Scan(&nextTarget)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("error reading pending chain events: %v", err)
+		}
+		if nextTarget != "" {
+			if err := setPaymentStage(ctx, tx, p.event.EscrowJobID, p.targetStatus, nextTarget); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, err := setPaymentStatus(ctx, tx, p.event.EscrowJobID, p.targetStatus, chainEventCause(p.event), payment.PendingConfirmations); err != nil {
+			return err
+		}
+
+		log.Printf("Indexer: application %d confirmed as %s by %s (tx %s, block %d)",
+			p.event.EscrowJobID, p.targetStatus, p.event.Name, p.event.TxHash, p.event.BlockNumber)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RollbackChainEvents discards pending events at or above fromBlock after a reorg,
+// restores the payment status each affected application had before them, and rewinds
+// the cursor so the replacement blocks are scanned again
+func (db *DB) RollbackChainEvents(ctx context.Context, name string, fromBlock uint64) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The earliest discarded event per application holds the status to go back to
+	rows, err := tx.Query(ctx, `
+		SELECT DISTINCT ON (application_id) application_id, prior_status
+		FROM pending_chain_events
+		WHERE indexer = $1 AND block_number >= $2
+		ORDER BY application_id, block_number, log_index
+	`, name, int64(fromBlock))
+	if err != nil {
+		return fmt.Errorf("error querying reorged chain events: %v", err)
+	}
+
+	priorStatuses := make(map[int32]payment.Status)
+	for rows.Next() {
+		var applicationID int32
+		var priorStatus payment.Status
+		if err := rows.Scan(&applicationID, &priorStatus); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning reorged chain event: %v", err)
+		}
+		priorStatuses[applicationID] = priorStatus
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error reading reorged chain events: %v", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM pending_chain_events WHERE indexer = $1 AND block_number >= $2
+	`, name, int64(fromBlock)); err != nil {
+		return fmt.Errorf("error removing reorged chain events: %v", err)
+	}
+
+	detail := fmt.Sprintf("unconfirmed events from block %d were reorged out", fromBlock)
+	for applicationID, priorStatus := range priorStatuses {
+		if err := recordPaymentEvent(ctx, tx, paymentEntry{
+			applicationID: applicationID,
+			eventType:     PaymentEventReorg,
+			origin:        indexerOrigin,
+			blockNumber:   fromBlock,
+			detail:        detail,
+		}); err != nil {
+			return err
+		}
+
+		// Earlier pending events that survived the reorg keep the application pending
+		var remaining int
+		if err := tx.QueryRow(ctx, `
+			SELECT COUNT(*) FROM pending_chain_events WHERE indexer = $1 AND application_id = $2
+		`, name, applicationID).Scan(&remaining); err != nil {
+			return fmt.Errorf("error counting pending chain events: %v", err)
+		}
+		if remaining > 0 {
+			continue
+		}
+
+		restored, err := setPaymentStatus(ctx, tx, applicationID, priorStatus, paymentEntry{origin: indexerOrigin, blockNumber: fromBlock, detail: detail}, payment.PendingConfirmations)
+		if err != nil {
+			return err
+		}
+
+		// A deposit that was reorged out no longer ties the application to its chain
+		if restored && priorStatus == payment.PendingDeposit {
+			if _, err := tx.Exec(ctx, `
+				UPDATE applications SET escrow_chain_id = NULL WHERE id = $1
+			`, applicationID); err != nil {
+				return fmt.Errorf("error clearing escrow chain: %v", err)
+			}
+		}
+
+		log.Printf("Indexer: reorg at block %d rolled application %d back to %s", fromBlock, applicationID, priorStatus)
+	}
+
+	if fromBlock > 0 {
+		if _, err := tx.Exec(ctx, `
+			UPDATE indexer_cursors SET last_block = LEAST(last_block, $2), updated_at = NOW()
+			WHERE name = $1
+		`, name, int64(fromBlock-1)); err != nil {
+			return fmt.Errorf("error rewinding indexer cursor: %v", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// applyChainEvent moves a single application according to chainEventTransitions
+func applyChainEvent(ctx context.Context, tx pgx.Tx, event ChainEvent) error {
+	transition, ok := chainEventTransitions[event.Name]
+	if !ok {
+		return fmt.Errorf("unknown chain event %q", event.Name)
+	}
+
+	status, found, err := currentPaymentStatus(ctx, tx, event.EscrowJobID)
+	if err != nil {
+		return err
+	}
+	if !found || !transition.allows(status) {
+		log.Printf("Indexer: %s for application %d (tx %s) did not change payment status", event.Name, event.EscrowJobID, event.TxHash)
+		return nil
+	}
+	if onChain, err := eventOnEscrowChain(ctx, tx, event); err != nil || !onChain {
+		return err
+	}
+
+	// The hash goes first so the webhook event snapshot includes it
+	if err := setChainEventTxHash(ctx, tx, event); err != nil {
+		return err
+	}
+
+	if _, err := setPaymentStatus(ctx, tx, event.EscrowJobID, transition.status, chainEventCause(event)); err != nil {
+		return fmt.Errorf("error applying %s: %w", event.Name, err)
+	}
+
+	log.Printf("Indexer: application %d moved to %s by %s (tx %s, block %d)", event.EscrowJobID, transition.status, event.Name, event.TxHash, event.BlockNumber)
+	return nil
+}
+
+// stageChainEvent parks an application in pending_confirmations until the event is deep enough
+func stageChainEvent(ctx context.Context, tx pgx.Tx, name string, event ChainEvent) error {
+	transition, ok := chainEventTransitions[event.Name]
+	if !ok {
+		return fmt.Errorf("unknown chain event %q", event.Name)
+	}
+
+	status, found, err := currentPaymentStatus(ctx, tx, event.EscrowJobID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		log.Printf("Indexer: %s for unknown application %d (tx %s) ignored", event.Name, event.EscrowJobID, event.TxHash)
+		return nil
+	}
+
+	// With earlier events still pending, this one applies on top of their outcome
+	var pendingTarget payment.Status
+	err = tx.QueryRow(ctx, `
+		SELECT target_status FROM pending_chain_events
+		WHERE indexer = $1 AND application_id = $2
+		ORDER BY block_number DESC, log_index DESC
+		LIMIT 1
+	`, name, event.EscrowJobID).Scan(&pendingTarget)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("error reading pending chain events: %v", err)
+	}
+	if pendingTarget != "" {
+		status = pendingTarget
+	}
+
+	if !transition.allows(status) {
+		log.Printf("Indexer: %s for application %d (tx %s) did not change payment status", event.Name, event.EscrowJobID, event.TxHash)
+		return nil
+	}
+	if onChain, err := eventOnEscrowChain(ctx, tx, event); err != nil || !onChain {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO pending_chain_events
+			(indexer, application_id, event_name, tx_hash, block_number, block_hash, log_index, prior_status, target_status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, name, event.EscrowJobID, event.Name, event.TxHash, int64(event.BlockNumber), event.BlockHash,
+		int32(event.LogIndex), status, transition.status); err != nil {
+		return fmt.Errorf("error staging %s for application %d: %v", event.Name, event.EscrowJobID, err)
+	}
+
+	if _, err := stagePaymentStatus(ctx, tx, event.EscrowJobID, transition.status, chainEventCause(event)); err != nil {
+		return err
+	}
+
+	log.Printf("Indexer: application %d awaiting confirmations for %s (tx %s, block %d)", event.EscrowJobID, event.Name, event.TxHash, event.BlockNumber)
+	return nil
+}
+
+// eventOnEscrowChain reports whether event was emitted on the chain the application's
+// escrow job is on, so a job id posted to the contracts of two chains only counts on one.
+// A JobPosted event for an application that is not on a chain yet records its chain.
+func eventOnEscrowChain(ctx context.Context, tx pgx.Tx, event ChainEvent) (bool, error) {
+	var chainID *int64
+	if err := tx.QueryRow(ctx, `
+		SELECT escrow_chain_id FROM applications WHERE id = $1
+	`, event.EscrowJobID).Scan(&chainID); err != nil {
+		return false, fmt.Errorf("error reading escrow chain of application %d: %v", event.EscrowJobID, err)
+	}
+
+	if chainID == nil {
+		if event.Name != "JobPosted" {
+			return true, nil
+		}
+		if _, err := tx.Exec(ctx, `
+			UPDATE applications SET escrow_chain_id = $2 WHERE id = $1
+		`, event.EscrowJobID, event.ChainID); err != nil {
+			return false, fmt.Errorf("error recording escrow chain of application %d: %v", event.EscrowJobID, err)
+		}
+		return true, nil
+	}
+
+	if *chainID != event.ChainID {
+		log.Printf("Indexer: %s for application %d on chain %d ignored, its escrow job is on chain %d (tx %s)",
+			event.Name, event.EscrowJobID, event.ChainID, *chainID, event.TxHash)
+		return false, nil
+	}
+	return true, nil
+}
+
+// setChainEventTxHash records the event's transaction hash on the application
+func setChainEventTxHash(ctx context.Context, tx pgx.Tx, event ChainEvent) error {
+	column, ok := chainEventTxHashColumns[event.Name]
+	if !ok {
+		return nil
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE applications
+		SET %[1]s = COALESCE(NULLIF(%[1]s, ''), $1)
+		WHERE id = $2
+	`, column)
+	if event.Name == "JobPosted" {
+		query = `
+			UPDATE applications
+			SET escrow_tx_hash_deposit = COALESCE(NULLIF(escrow_tx_hash_deposit, ''), $1),
+			    escrow_job_id = $2
+			WHERE id = $2
+		`
+	}
+
+	if _, err := tx.Exec(ctx, query, event.TxHash, event.EscrowJobID); err != nil {
+		return fmt.Errorf("error recording %s tx hash for application %d: %v", event.Name, event.EscrowJobID, err)
+	}
+	return nil
+}
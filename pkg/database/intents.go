@@ -0,0 +1,614 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/fahedafzaal/go-integration/pkg/payment"
+)
+
+// Transaction intent kinds
+const (
+	IntentDeposit       = "deposit"        // PostJob funded from the gateway wallet
+	IntentClientDeposit = "client_deposit" // PostJob signed by the client, broadcast by the gateway
+	IntentRelease       = "release"        // MarkJobCompleted, pays the freelancer
+	IntentRefund        = "refund"         // CancelJob, refunds the client
+)
+
+// Transaction intent statuses
+const (
+	IntentQueued    = "queued"    // status change committed, transaction not signed yet
+	IntentSubmitted = "submitted" // signed and broadcast, waiting to be mined
+	IntentMined     = "mined"     // mined, waiting for confirmation depth
+	IntentConfirmed = "confirmed" // final
+	IntentFailed    = "failed"    // reverted or never sent; payment status rolled back
+)
+
+var (
+	// ErrIntentNotAllowed is returned when the application's payment status does not permit the intent
+	ErrIntentNotAllowed = errors.New("payment status does not allow this transaction")
+	// ErrTxIntentNotFound is returned when no intent has the requested id
+	ErrTxIntentNotFound = errors.New("transaction intent not found")
+	// ErrEscrowChainMismatch is returned for a transaction on another chain than the one
+	// the application's escrow job is on
+	ErrEscrowChainMismatch = errors.New("application's escrow job is on another chain")
+	// ErrTxIntentInFlight is returned when failing an intent whose transaction may still
+	// be mined
+	ErrTxIntentInFlight = errors.New("transaction intent's transaction may still be mined")
+)
+
+// txIntentKind describes the payment status flow of an intent kind
+type txIntentKind struct {
+	from      payment.Status // payment status required to enqueue
+	initiated payment.Status // payment status while the transaction is in flight
+	final     payment.Status // payment status once the transaction is confirmed
+	column    string         // application column recording the transaction hash
+}
+
+var txIntentKinds = map[string]txIntentKind{
+	IntentDeposit:       {from: payment.PendingDeposit, initiated: payment.DepositInitiated, final: payment.Deposited, column: "escrow_tx_hash_deposit"},
+	IntentClientDeposit: {from: payment.PendingDeposit, initiated: payment.DepositInitiated, final: payment.Deposited, column: "escrow_tx_hash_deposit"},
+	IntentRelease:       {from: payment.Deposited, initiated: payment.ReleaseInitiated, final: payment.Released, column: "escrow_tx_hash_release"},
+	IntentRefund:        {from: payment.Deposited, initiated: payment.RefundInitiated, final: payment.Refunded, column: "escrow_tx_hash_refund"},
+}
+
+// TxIntent is an outbox row: a contract call the gateway has committed to sending on
+// ChainID. TxHashes holds every version broadcast under Nonce, original first; TxHash is
+// the latest one, or the one that was mined. CancelTxHash is a zero-value self-send
+// broadcast under Nonce to void the call; it is in TxHashes too, but never becomes TxHash.
+type TxIntent struct {
+	ID            int64          `json:"id"`
+	ApplicationID int32          `json:"application_id"`
+	ChainID       int64          `json:"chain_id"`
+	Kind          string         `json:"kind"`
+	Status        string         `json:"status"`
+	PriorStatus   payment.Status `json:"-"`
+	Nonce         *int64         `json:"nonce,omitempty"`
+	TxHash        *string        `json:"tx_hash,omitempty"`
+	TxHashes      []string       `json:"-"`
+	CancelTxHash  *string        `json:"cancel_tx_hash,omitempty"`
+	RawTx         []byte         `json:"-"`
+	BlockNumber   *int64         `json:"block_number,omitempty"`
+	BlockHash     *string        `json:"block_hash,omitempty"`
+	Error         *string        `json:"error,omitempty"`
+	RevertReason  *string        `json:"revert_reason,omitempty"`
+	Attempts      int            `json:"attempts"`
+	SubmittedAt   *time.Time     `json:"submitted_at,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+const txIntentColumns = `id, application_id, COALESCE(chain_id, 0), kind, status, prior_status, nonce, tx_hash, tx_hashes, cancel_tx_hash, raw_tx,
+	block_number, block_hash, error, revert_reason, attempts, submitted_at, created_at, updated_at`
+
+func scanTxIntent(row pgx.Row) (*TxIntent, error) {
+	var intent TxIntent
+	err := row.Scan(
+		&intent.ID,
+		&intent.ApplicationID,
+		&intent.ChainID,
+		&intent.Kind,
+		&intent.Status,
+		&intent.PriorStatus,
+		&intent.Nonce,
+		&intent.TxHash,
+		&intent.TxHashes,
+		&intent.CancelTxHash,
+		&intent.RawTx,
+		&intent.BlockNumber,
+		&intent.BlockHash,
+		&intent.Error,
+		&intent.RevertReason,
+		&intent.Attempts,
+		&intent.SubmittedAt,
+		&intent.CreatedAt,
+		&intent.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &intent, nil
+}
+
+// EnqueueTxIntent moves the application into the kind's in-flight payment status and
+// queues the transaction on chainID in the same database transaction, so the status
+// change and the obligation to send can never be separated. If an intent of the same kind
+// is already in flight it is returned instead, with created set to false. The status
+// change is attributed to origin in the payment ledger.
+//
+// The first intent records chainID as the application's escrow chain; intents for an
+// application whose escrow job is on another chain fail with ErrEscrowChainMismatch.
+func (db *DB) EnqueueTxIntent(ctx context.Context, chainID int64, applicationID int32, kind string, origin Origin) (*TxIntent, bool, error) {
+	return db.enqueueTxIntent(ctx, chainID, applicationID, kind, origin, nil)
+}
+
+// SignedTx is a transaction signed outside the gateway, such as by a client's wallet
+type SignedTx struct {
+	From    string // signer, recorded in the payment ledger
+	Nonce   uint64
+	TxHash  string
+	RawTx   []byte
+	Amounts Amounts
+}
+
+// EnqueueSignedTxIntent is EnqueueTxIntent for a transaction that is already signed. The
+// intent starts out submitted with the transaction stored, so it must be called before
+// the transaction is broadcast, and the outbox worker only tracks it from there.
+func (db *DB) EnqueueSignedTxIntent(ctx context.Context, chainID int64, applicationID int32, kind string, signed SignedTx, origin Origin) (*TxIntent, bool, error) {
+	return db.enqueueTxIntent(ctx, chainID, applicationID, kind, origin, &signed)
+}
+
+func (db *DB) enqueueTxIntent(ctx context.Context, chainID int64, applicationID int32, kind string, origin Origin, signed *SignedTx) (*TxIntent, bool, error) {
+	spec, ok := txIntentKinds[kind]
+	if !ok {
+		return nil, false, fmt.Errorf("unknown transaction intent kind %q", kind)
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	status, found, err := currentPaymentStatus(ctx, tx, applicationID)
+	if err != nil {
+		return nil, false, err
+	}
+	if !found {
+		return nil, false, fmt.Errorf("application %d not found", applicationID)
+	}
+
+	var escrowChainID *int64
+	if err := tx.QueryRow(ctx, `
+		SELECT escrow_chain_id FROM applications WHERE id = $1
+	`, applicationID).Scan(&escrowChainID); err != nil {
+		return nil, false, fmt.Errorf("error reading escrow chain: %v", err)
+	}
+	if escrowChainID != nil && *escrowChainID != chainID {
+		return nil, false, fmt.Errorf("%w: chain %d, not %d", ErrEscrowChainMismatch, *escrowChainID, chainID)
+	}
+
+	existing, err := scanTxIntent(tx.QueryRow(ctx, `
+		SELECT `+txIntentColumns+` FROM tx_intents
+		WHERE application_id = $1 AND kind = $2 AND status IN ('queued', 'submitted', 'mined')
+	`, applicationID, kind))
+	if err == nil {
+		return existing, false, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, fmt.Errorf("error checking existing transaction intents: %v", err)
+	}
+
+	if status != spec.from {
+		return nil, false, fmt.Errorf("%w: payment status is '%s', expected '%s'", ErrIntentNotAllowed, status, spec.from)
+	}
+
+	if _, err := setPaymentStatus(ctx, tx, applicationID, spec.initiated, paymentEntry{origin: origin, detail: kind}, spec.from); err != nil {
+		return nil, false, err
+	}
+
+	if escrowChainID == nil {
+		if _, err := tx.Exec(ctx, `
+			UPDATE applications SET escrow_chain_id = $2 WHERE id = $1
+		`, applicationID, chainID); err != nil {
+			return nil, false, fmt.Errorf("error recording escrow chain: %v", err)
+		}
+	}
+
+	var intent *TxIntent
+	if signed == nil {
+		intent, err = scanTxIntent(tx.QueryRow(ctx, `
+			INSERT INTO tx_intents (application_id, chain_id, kind, status, prior_status)
+			VALUES ($1, $2, $3, 'queued', $4)
+			RETURNING `+txIntentColumns,
+			applicationID, chainID, kind, status))
+	} else {
+		intent, err = scanTxIntent(tx.QueryRow(ctx, `
+			INSERT INTO tx_intents (application_id, chain_id, kind, status, prior_status, nonce, tx_hash, tx_hashes, raw_tx, submitted_at)
+			VALUES ($1, $2, $3, 'submitted', $4, $5, $6, ARRAY[$6::TEXT], $7, NOW())
+			RETURNING `+txIntentColumns,
+			applicationID, chainID, kind, status, int64(signed.Nonce), signed.TxHash, signed.RawTx))
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("error creating transaction intent: %v", err)
+	}
+
+	if signed != nil {
+		if err := recordPaymentEvent(ctx, tx, paymentEntry{
+			applicationID: applicationID,
+			eventType:     PaymentEventTxSubmitted,
+			origin:        origin,
+			txHash:        signed.TxHash,
+			amounts:       signed.Amounts,
+			detail:        fmt.Sprintf("%s signed by %s, nonce %d", kind, signed.From, signed.Nonce),
+		}); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("failed to commit transaction intent: %v", err)
+	}
+
+	log.Printf("Outbox: queued %s intent %d for application %d on chain %d", kind, intent.ID, applicationID, chainID)
+	return intent, true, nil
+}
+
+// GetTxIntent returns a transaction intent by id
+func (db *DB) GetTxIntent(ctx context.Context, id int64) (*TxIntent, error) {
+	intent, err := scanTxIntent(db.Pool.QueryRow(ctx, `SELECT `+txIntentColumns+` FROM tx_intents WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTxIntentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting transaction intent %d: %v", id, err)
+	}
+	return intent, nil
+}
+
+// LatestTxIntent returns the most recent intent of a kind for an application, or nil if there is none
+func (db *DB) LatestTxIntent(ctx context.Context, applicationID int32, kind string) (*TxIntent, error) {
+	intent, err := scanTxIntent(db.Pool.QueryRow(ctx, `
+		SELECT `+txIntentColumns+` FROM tx_intents
+		WHERE application_id = $1 AND kind = $2
+		ORDER BY id DESC
+		LIMIT 1
+	`, applicationID, kind))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting latest transaction intent: %v", err)
+	}
+	return intent, nil
+}
+
+// ClaimTxIntents leases up to limit unfinished intents on chainID to the caller. A leased
+// intent is skipped by other workers until it is updated or the lease expires, so several
+// gateway processes can share the outbox.
+func (db *DB) ClaimTxIntents(ctx context.Context, chainID int64, limit int, lease time.Duration) ([]TxIntent, error) {
+	rows, err := db.Pool.Query(ctx, `
+		UPDATE tx_intents
+		SET locked_until = NOW() + make_interval(secs => $3)
+		WHERE id IN (
+			SELECT id FROM tx_intents
+			WHERE chain_id = $1 AND status IN ('queued', 'submitted', 'mined')
+			  AND (locked_until IS NULL OR locked_until < NOW())
+			ORDER BY id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+txIntentColumns,
+		chainID, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("error claiming transaction intents: %v", err)
+	}
+	defer rows.Close()
+
+	var intents []TxIntent
+	for rows.Next() {
+		intent, err := scanTxIntent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning transaction intent: %v", err)
+		}
+		intents = append(intents, *intent)
+	}
+	return intents, rows.Err()
+}
+
+// UnlockTxIntent ends the caller's lease without changing the intent
+func (db *DB) UnlockTxIntent(ctx context.Context, id int64) error {
+	if _, err := db.Pool.Exec(ctx, `UPDATE tx_intents SET locked_until = NULL WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("error unlocking transaction intent %d: %v", id, err)
+	}
+	return nil
+}
+
+// RecordTxIntentError stores the latest failure and returns the number of failed attempts
+func (db *DB) RecordTxIntentError(ctx context.Context, id int64, message string) (int, error) {
+	var attempts int
+	err := db.Pool.QueryRow(ctx, `
+		UPDATE tx_intents
+		SET attempts = attempts + 1, error = $2, locked_until = NULL, updated_at = NOW()
+		WHERE id = $1
+		RETURNING attempts
+	`, id, message).Scan(&attempts)
+	if err != nil {
+		return 0, fmt.Errorf("error recording transaction intent %d error: %v", id, err)
+	}
+	return attempts, nil
+}
+
+// MarkTxIntentSubmitted records a signed transaction for the intent. It must be called
+// before the transaction is broadcast, so a crash never leaves a sent transaction
+// untracked. Replacements are recorded the same way and join TxHashes; only the first
+// transaction is entered in the payment ledger, with amounts, since RecordTxReplacement
+// enters the rest.
+func (db *DB) MarkTxIntentSubmitted(ctx context.Context, id int64, nonce uint64, txHash string, rawTx []byte, amounts Amounts) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var applicationID int32
+	var kind string
+	var versions int
+	err = tx.QueryRow(ctx, `
+		UPDATE tx_intents
+		SET status = 'submitted', nonce = $2, tx_hash = $3, tx_hashes = array_append(tx_hashes, $3),
+		    raw_tx = $4, attempts = 0, error = NULL, submitted_at = NOW(), locked_until = NULL, updated_at = NOW()
+		WHERE id = $1
+		RETURNING application_id, kind, cardinality(tx_hashes)
+	`, id, int64(nonce), txHash, rawTx).Scan(&applicationID, &kind, &versions)
+	if err != nil {
+		return fmt.Errorf("error marking transaction intent %d submitted: %v", id, err)
+	}
+
+	if versions == 1 {
+		if err := recordPaymentEvent(ctx, tx, paymentEntry{
+			applicationID: applicationID,
+			eventType:     PaymentEventTxSubmitted,
+			origin:        outboxOrigin,
+			txHash:        txHash,
+			amounts:       amounts,
+			detail:        fmt.Sprintf("%s, nonce %d", kind, nonce),
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction intent %d: %v", id, err)
+	}
+	return nil
+}
+
+// MarkTxIntentCancelled records a cancel broadcast under a submitted intent's nonce. It
+// joins TxHashes so the outbox notices whichever of the call and the cancel is mined; a
+// later cancel replaces an earlier one.
+func (db *DB) MarkTxIntentCancelled(ctx context.Context, id int64, txHash string) error {
+	result, err := db.Pool.Exec(ctx, `
+		UPDATE tx_intents
+		SET cancel_tx_hash = $2, tx_hashes = array_append(tx_hashes, $2), locked_until = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = 'submitted'
+	`, id, txHash)
+	if err != nil {
+		return fmt.Errorf("error marking transaction intent %d cancelled: %v", id, err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("transaction intent %d is not submitted", id)
+	}
+	return nil
+}
+
+// MarkTxIntentMined records the transaction version that was mined
+func (db *DB) MarkTxIntentMined(ctx context.Context, id int64, txHash string, blockNumber uint64, blockHash string) error {
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE tx_intents
+		SET status = 'mined', tx_hash = $2, block_number = $3, block_hash = $4, locked_until = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, id, txHash, int64(blockNumber), blockHash)
+	if err != nil {
+		return fmt.Errorf("error marking transaction intent %d mined: %v", id, err)
+	}
+	return nil
+}
+
+// MarkTxIntentReorged returns a mined intent to submitted after its block left the canonical chain
+func (db *DB) MarkTxIntentReorged(ctx context.Context, id int64) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var applicationID int32
+	var txHash *string
+	var blockNumber *int64
+	err = tx.QueryRow(ctx, `
+		UPDATE tx_intents t
+		SET status = 'submitted', block_number = NULL, block_hash = NULL, locked_until = NULL, updated_at = NOW()
+		FROM (SELECT id, block_number FROM tx_intents WHERE id = $1 FOR UPDATE) old
+		WHERE t.id = old.id AND t.status = 'mined'
+		RETURNING t.application_id, t.tx_hash, old.block_number
+	`, id).Scan(&applicationID, &txHash, &blockNumber)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error marking transaction intent %d reorged: %v", id, err)
+	}
+
+	entry := paymentEntry{
+		applicationID: applicationID,
+		eventType:     PaymentEventReorg,
+		origin:        outboxOrigin,
+		detail:        fmt.Sprintf("transaction intent %d is no longer mined", id),
+	}
+	if txHash != nil {
+		entry.txHash = *txHash
+	}
+	if blockNumber != nil {
+		entry.blockNumber = uint64(*blockNumber)
+	}
+	if err := recordPaymentEvent(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction intent %d: %v", id, err)
+	}
+	return nil
+}
+
+// ConfirmTxIntent finalizes an intent and, in the same transaction, records its hash on
+// the application and moves the payment status on
+func (db *DB) ConfirmTxIntent(ctx context.Context, id int64) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	intent, err := scanTxIntent(tx.QueryRow(ctx, `
+		UPDATE tx_intents
+		SET status = 'confirmed', error = NULL, locked_until = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = 'mined'
+		RETURNING `+txIntentColumns, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error confirming transaction intent %d: %v", id, err)
+	}
+
+	// A confirmed deposit also records the escrow job, as the indexer does for JobPosted
+	spec := txIntentKinds[intent.Kind]
+	extra := ""
+	if intent.Kind == IntentDeposit || intent.Kind == IntentClientDeposit {
+		extra = ", escrow_job_id = id"
+	}
+
+	// The hash goes first so the webhook event snapshot includes it
+	query := fmt.Sprintf(`
+		UPDATE applications SET %[1]s = COALESCE(NULLIF(%[1]s, ''), $1)%[2]s WHERE id = $2
+	`, spec.column, extra)
+	if _, err := tx.Exec(ctx, query, intent.TxHash, intent.ApplicationID); err != nil {
+		return fmt.Errorf("error finalizing application %d: %v", intent.ApplicationID, err)
+	}
+
+	confirmed := paymentEntry{
+		applicationID: intent.ApplicationID,
+		eventType:     PaymentEventTxConfirmed,
+		origin:        outboxOrigin,
+		txHash:        *intent.TxHash,
+		detail:        intent.Kind,
+	}
+	if intent.BlockNumber != nil {
+		confirmed.blockNumber = uint64(*intent.BlockNumber)
+	}
+	if err := recordPaymentEvent(ctx, tx, confirmed); err != nil {
+		return err
+	}
+
+	// An application the indexer has already moved on is left alone
+	if _, err := setPaymentStatus(ctx, tx, intent.ApplicationID, spec.final, confirmed, spec.initiated); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction intent %d: %v", id, err)
+	}
+
+	log.Printf("Outbox: %s intent %d for application %d confirmed (tx %s)", intent.Kind, id, intent.ApplicationID, *intent.TxHash)
+	return nil
+}
+
+// FailTxIntent gives up on an intent that was never broadcast and, in the same
+// transaction, restores the payment status it started from if the application is still
+// waiting on it. A submitted or mined intent is refused with ErrTxIntentInFlight, since
+// its transaction could still land after the payment status was rolled back.
+func (db *DB) FailTxIntent(ctx context.Context, id int64, reason string) error {
+	return db.failTxIntent(ctx, id, reason, nil, IntentQueued)
+}
+
+// RevertTxIntent fails an intent whose transaction reverted, either when mined or when
+// the contract rejected it during gas estimation
+func (db *DB) RevertTxIntent(ctx context.Context, id int64, revertReason string) error {
+	return db.failTxIntent(ctx, id, "transaction reverted: "+revertReason, &revertReason, IntentQueued, IntentMined)
+}
+
+// FailReplacedTxIntent fails a submitted intent whose nonce was consumed by another
+// transaction, such as a cancel, so none of its versions can be mined any more
+func (db *DB) FailReplacedTxIntent(ctx context.Context, id int64, reason string) error {
+	return db.failTxIntent(ctx, id, reason, nil, IntentSubmitted)
+}
+
+// RejectTxIntent fails a mined intent at confirmation depth that the gateway will not
+// accept, such as a client deposit that does not match the agreed terms. Its
+// transaction has spent the nonce, so nothing further can land.
+func (db *DB) RejectTxIntent(ctx context.Context, id int64, reason string) error {
+	return db.failTxIntent(ctx, id, reason, nil, IntentMined)
+}
+
+// failTxIntent fails an intent in one of the statuses from, which the caller has
+// established are safe to roll back. Intents already final are left alone.
+func (db *DB) failTxIntent(ctx context.Context, id int64, reason string, revertReason *string, from ...string) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var status string
+	err = tx.QueryRow(ctx, `
+		SELECT status FROM tx_intents WHERE id = $1 FOR UPDATE
+	`, id).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error locking transaction intent %d: %v", id, err)
+	}
+	if status == IntentConfirmed || status == IntentFailed {
+		return nil
+	}
+	if !slices.Contains(from, status) {
+		return fmt.Errorf("%w: intent %d is %s", ErrTxIntentInFlight, id, status)
+	}
+
+	intent, err := scanTxIntent(tx.QueryRow(ctx, `
+		UPDATE tx_intents
+		SET status = 'failed', error = $2, revert_reason = $3, locked_until = NULL, updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+txIntentColumns, id, reason, revertReason))
+	if err != nil {
+		return fmt.Errorf("error failing transaction intent %d: %v", id, err)
+	}
+
+	failed := paymentEntry{
+		applicationID: intent.ApplicationID,
+		eventType:     PaymentEventTxFailed,
+		origin:        outboxOrigin,
+		detail:        fmt.Sprintf("%s: %s", intent.Kind, reason),
+	}
+	if intent.TxHash != nil {
+		failed.txHash = *intent.TxHash
+	}
+	if err := recordPaymentEvent(ctx, tx, failed); err != nil {
+		return err
+	}
+
+	spec := txIntentKinds[intent.Kind]
+	restored, err := setPaymentStatus(ctx, tx, intent.ApplicationID, intent.PriorStatus, failed, spec.initiated)
+	if err != nil {
+		return err
+	}
+
+	// An application back to waiting for its deposit may be funded on any chain
+	if restored && intent.PriorStatus == payment.PendingDeposit {
+		if _, err := tx.Exec(ctx, `
+			UPDATE applications SET escrow_chain_id = NULL WHERE id = $1
+		`, intent.ApplicationID); err != nil {
+			return fmt.Errorf("error clearing escrow chain: %v", err)
+		}
+	}
+
+	if err := queueWebhookEvent(ctx, tx, intent.ApplicationID, WebhookEventFailed); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction intent %d: %v", id, err)
+	}
+
+	log.Printf("Outbox: %s intent %d for application %d failed: %s", intent.Kind, id, intent.ApplicationID, reason)
+	return nil
+}
@@ -0,0 +1,187 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/fahedafzaal/go-integration/pkg/payment"
+)
+
+// Payment ledger event types
+const (
+	PaymentEventStatusChanged = "status_changed" // payment_status moved; from_status and to_status are set
+	PaymentEventTxSubmitted   = "tx_submitted"   // a gateway transaction was signed and stored for broadcast
+	PaymentEventTxReplaced    = "tx_replaced"    // a stuck gateway transaction was sped up or cancelled
+	PaymentEventTxConfirmed   = "tx_confirmed"   // a gateway transaction reached confirmation depth
+	PaymentEventTxFailed      = "tx_failed"      // a gateway transaction reverted or could not be sent
+	PaymentEventReorg         = "reorg"          // a block the application's state depended on left the chain
+	PaymentEventAccessDenied  = "access_denied"  // a caller was refused an action on the application
+	PaymentEventOfferSigned   = "offer_signed"   // the client's EIP-712 signature over the escrow terms was accepted
+)
+
+// Payment ledger actors
+const (
+	ActorAPI     = "api"     // an HTTP caller, recorded as api:<key id>[/user:<user id>] when authenticated
+	ActorGateway = "gateway" // the gateway acting on its own, with its wallet
+	ActorChain   = "chain"   // an escrow event observed on chain
+)
+
+// Payment ledger sources other than HTTP endpoints
+const (
+	SourceIndexer     = "indexer"     // the chain event indexer
+	SourceOutbox      = "outbox"      // the transaction outbox worker
+	SourceReplacement = "replacement" // a fee bump or cancel, from the outbox or POST /replace-transaction
+)
+
+// Origin identifies who caused a payment event and through which part of the gateway.
+// Source is an HTTP endpoint such as "POST /post-job", or one of the Source constants.
+type Origin struct {
+	Actor  string
+	Source string
+}
+
+var (
+	indexerOrigin = Origin{Actor: ActorChain, Source: SourceIndexer}
+	outboxOrigin  = Origin{Actor: ActorGateway, Source: SourceOutbox}
+)
+
+// Amounts are the money figures recorded on a payment event, where known
+type Amounts struct {
+	USDE8         *big.Int // 8-decimal USD as the escrow contract takes it; defaults to the agreed amount
+	Wei           *big.Int
+	EthUSDPriceE8 *big.Int // ETH/USD feed answer the amount was converted at
+}
+
+// PaymentEvent is one row of the append-only payment_events ledger. Amounts are decimal
+// strings, since wei do not fit in a JSON number.
+type PaymentEvent struct {
+	ID            int64           `json:"id"`
+	ApplicationID int32           `json:"application_id"`
+	Type          string          `json:"type"`
+	Actor         string          `json:"actor"`
+	Source        string          `json:"source"`
+	FromStatus    *payment.Status `json:"from_status,omitempty"`
+	ToStatus      *payment.Status `json:"to_status,omitempty"`
+	TxHash        *string         `json:"tx_hash,omitempty"`
+	BlockNumber   *int64          `json:"block_number,omitempty"`
+	AmountUSDE8   *string         `json:"amount_usd_e8,omitempty"`
+	AmountWei     *string         `json:"amount_wei,omitempty"`
+	EthUSDPriceE8 *string         `json:"eth_usd_price_e8,omitempty"`
+	Detail        *string         `json:"detail,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// PaymentEventFilter narrows ListPaymentEvents; zero values match everything
+type PaymentEventFilter struct {
+	ApplicationID int32
+	Since         time.Time // inclusive
+	Until         time.Time // exclusive
+}
+
+// paymentEntry is a ledger row to be written. from, to, txHash, blockNumber and detail
+// are stored as NULL when empty.
+type paymentEntry struct {
+	applicationID int32
+	eventType     string
+	origin        Origin
+	from, to      payment.Status
+	txHash        string
+	blockNumber   uint64
+	amounts       Amounts
+	detail        string
+}
+
+// recordPaymentEvent appends to the ledger inside the transaction making the change it
+// describes, so the ledger holds exactly the changes that commit
+func recordPaymentEvent(ctx context.Context, tx pgx.Tx, e paymentEntry) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO payment_events (application_id, event_type, actor, source, from_status, to_status,
+			tx_hash, block_number, amount_usd_e8, amount_wei, eth_usd_price_e8, detail)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), NULLIF($8::BIGINT, 0),
+			COALESCE($9::NUMERIC, (SELECT agreed_usd_amount::NUMERIC * 100000000 FROM applications WHERE id = $1)),
+			$10::NUMERIC, $11::NUMERIC, NULLIF($12, ''))
+	`, e.applicationID, e.eventType, e.origin.Actor, e.origin.Source, string(e.from), string(e.to),
+		e.txHash, int64(e.blockNumber), numericText(e.amounts.USDE8), numericText(e.amounts.Wei),
+		numericText(e.amounts.EthUSDPriceE8), e.detail)
+	if err != nil {
+		return fmt.Errorf("error recording %s payment event for application %d: %v", e.eventType, e.applicationID, err)
+	}
+	return nil
+}
+
+// RecordAccessDenied enters a refused request in the application's payment ledger
+func (db *DB) RecordAccessDenied(ctx context.Context, applicationID int32, origin Origin, reason string) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := recordPaymentEvent(ctx, tx, paymentEntry{
+		applicationID: applicationID,
+		eventType:     PaymentEventAccessDenied,
+		origin:        origin,
+		detail:        reason,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// numericText renders an amount for a NUMERIC parameter, nil staying NULL
+func numericText(n *big.Int) *string {
+	if n == nil {
+		return nil
+	}
+	s := n.String()
+	return &s
+}
+
+// ListPaymentEvents returns the ledger rows matching filter, oldest first
+func (db *DB) ListPaymentEvents(ctx context.Context, filter PaymentEventFilter) ([]PaymentEvent, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.ApplicationID != 0 {
+		args = append(args, filter.ApplicationID)
+		conditions = append(conditions, fmt.Sprintf("application_id = $%d", len(args)))
+	}
+	if !filter.Since.IsZero() {
+		args = append(args, filter.Since)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if !filter.Until.IsZero() {
+		args = append(args, filter.Until)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+
+	query := `
+		SELECT id, application_id, event_type, actor, source, from_status, to_status, tx_hash, block_number,
+		       amount_usd_e8::TEXT, amount_wei::TEXT, eth_usd_price_e8::TEXT, detail, created_at
+		FROM payment_events`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += ` ORDER BY id`
+
+	rows, err := db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing payment events: %v", err)
+	}
+	defer rows.Close()
+
+	var events []PaymentEvent
+	for rows.Next() {
+		var e PaymentEvent
+		if err := rows.Scan(&e.ID, &e.ApplicationID, &e.Type, &e.Actor, &e.Source, &e.FromStatus, &e.ToStatus,
+			&e.TxHash, &e.BlockNumber, &e.AmountUSDE8, &e.AmountWei, &e.EthUSDPriceE8, &e.Detail, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning payment event: %v", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
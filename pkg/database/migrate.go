@@ -0,0 +1,292 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// migrationFiles holds the schema migrations, named NNNN_description.up.sql with a
+// matching NNNN_description.down.sql that reverts it
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is the advisory lock key held while migrating, so two gateways
+// starting at once cannot apply the same migration twice
+const migrationLockID = 0x6761746577617900 // "gateway\0"
+
+// ErrSchemaBehind is returned by CheckSchema when migrations are pending
+var ErrSchemaBehind = errors.New("database schema is behind")
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// SchemaStatus compares the applied migrations with those embedded in the binary
+type SchemaStatus struct {
+	Current int64       // highest applied version, 0 when none are applied
+	Latest  int64       // highest embedded version
+	Pending []Migration // embedded migrations not yet applied, oldest first
+}
+
+// Migrations returns the embedded migrations ordered by version
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations: %v", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %q", entry.Name())
+		}
+		body, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("error reading migration %s: %v", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// ensureMigrationsTable creates the table recording applied migrations
+func ensureMigrationsTable(ctx context.Context, conn *pgx.Conn) error {
+	_, err := conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    BIGINT PRIMARY KEY,
+			name       TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("error creating schema_migrations: %v", err)
+	}
+	return nil
+}
+
+// querier is satisfied by a pool, a connection and a transaction
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// appliedVersions returns the versions recorded in schema_migrations
+func appliedVersions(ctx context.Context, q querier) (map[int64]bool, error) {
+	rows, err := q.Query(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("error reading schema_migrations: %v", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("error scanning schema_migrations: %v", err)
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// withMigrationLock runs fn on a dedicated connection holding the migration advisory lock
+func (db *DB) withMigrationLock(ctx context.Context, fn func(conn *pgx.Conn) error) error {
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %v", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, int64(migrationLockID)); err != nil {
+		return fmt.Errorf("error acquiring migration lock: %v", err)
+	}
+	defer func() {
+		// Unlock with a fresh context so a cancelled migration still releases the lock
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, int64(migrationLockID)); err != nil {
+			log.Printf("Warning: Failed to release migration lock: %v", err)
+		}
+	}()
+
+	if err := ensureMigrationsTable(ctx, conn.Conn()); err != nil {
+		return err
+	}
+	return fn(conn.Conn())
+}
+
+// Migrate applies every pending migration in version order, each in its own
+// transaction, and returns the migrations it applied
+func (db *DB) Migrate(ctx context.Context) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	err = db.withMigrationLock(ctx, func(conn *pgx.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			if done[m.Version] {
+				continue
+			}
+			if err := runMigration(ctx, conn, m.Up, func(tx pgx.Tx) error {
+				_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name)
+				return err
+			}); err != nil {
+				return fmt.Errorf("error applying migration %d_%s: %v", m.Version, m.Name, err)
+			}
+			log.Printf("Migrations: applied %d_%s", m.Version, m.Name)
+			applied = append(applied, m)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// MigrateDown reverts the most recent steps applied migrations and returns them, newest
+// first
+func (db *DB) MigrateDown(ctx context.Context, steps int) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	err = db.withMigrationLock(ctx, func(conn *pgx.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			m := migrations[i]
+			if !done[m.Version] {
+				continue
+			}
+			if err := runMigration(ctx, conn, m.Down, func(tx pgx.Tx) error {
+				_, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.Version)
+				return err
+			}); err != nil {
+				return fmt.Errorf("error reverting migration %d_%s: %v", m.Version, m.Name, err)
+			}
+			log.Printf("Migrations: reverted %d_%s", m.Version, m.Name)
+			reverted = append(reverted, m)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// runMigration executes a migration script and its bookkeeping in one transaction
+func runMigration(ctx context.Context, conn *pgx.Conn, script string, record func(tx pgx.Tx) error) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, script); err != nil {
+		return err
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// SchemaStatus reports which embedded migrations have not been applied
+func (db *DB) SchemaStatus(ctx context.Context) (*SchemaStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	if err := db.Pool.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("error checking schema_migrations: %v", err)
+	}
+	done := make(map[int64]bool)
+	if exists {
+		if done, err = appliedVersions(ctx, db.Pool); err != nil {
+			return nil, err
+		}
+	}
+
+	status := &SchemaStatus{}
+	for version := range done {
+		if version > status.Current {
+			status.Current = version
+		}
+	}
+	for _, m := range migrations {
+		status.Latest = m.Version
+		if !done[m.Version] {
+			status.Pending = append(status.Pending, m)
+		}
+	}
+	return status, nil
+}
+
+// CheckSchema returns an error wrapping ErrSchemaBehind when any embedded migration has
+// not been applied
+func (db *DB) CheckSchema(ctx context.Context) error {
+	status, err := db.SchemaStatus(ctx)
+	if err != nil {
+		return err
+	}
+	if len(status.Pending) > 0 {
+		first := status.Pending[0]
+		return fmt.Errorf("%w: at version %d, %d migration(s) pending from %d_%s to version %d",
+			ErrSchemaBehind, status.Current, len(status.Pending), first.Version, first.Name, status.Latest)
+	}
+	if status.Current > status.Latest {
+		log.Printf("Warning: Database schema version %d is newer than this binary's %d", status.Current, status.Latest)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package database
+
+import "testing"
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Migrations: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+
+	// Versions are contiguous from 1, so a skipped number is caught before it ships
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			t.Errorf("migration %d_%s has version %d, want %d", m.Version, m.Name, m.Version, i+1)
+		}
+	}
+}
@@ -0,0 +1,2 @@
+-- Nothing to revert. These columns belong to the main application, which had them
+-- before the gateway had migrations, and dropping them would destroy its data.
@@ -0,0 +1,11 @@
+-- Payment columns the gateway reads and writes on the main application's tables.
+-- The applications, jobs and users tables themselves belong to the main application.
+ALTER TABLE applications
+    ADD COLUMN IF NOT EXISTS agreed_usd_amount      INTEGER,
+    ADD COLUMN IF NOT EXISTS payment_status         TEXT DEFAULT 'pending_deposit',
+    ADD COLUMN IF NOT EXISTS escrow_job_id          INTEGER,
+    ADD COLUMN IF NOT EXISTS escrow_tx_hash_deposit TEXT,
+    ADD COLUMN IF NOT EXISTS escrow_tx_hash_release TEXT,
+    ADD COLUMN IF NOT EXISTS escrow_tx_hash_refund  TEXT;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS wallet_address TEXT;
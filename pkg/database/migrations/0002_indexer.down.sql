@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS pending_chain_events;
+DROP TABLE IF EXISTS indexer_cursors;
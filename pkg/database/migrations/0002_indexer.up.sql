@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS indexer_cursors (
+    name       TEXT PRIMARY KEY,
+    last_block BIGINT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS pending_chain_events (
+    id             BIGSERIAL PRIMARY KEY,
+    indexer        TEXT NOT NULL,
+    application_id INTEGER NOT NULL,
+    event_name     TEXT NOT NULL,
+    tx_hash        TEXT NOT NULL,
+    block_number   BIGINT NOT NULL,
+    block_hash     TEXT NOT NULL,
+    log_index      INTEGER NOT NULL,
+    prior_status   TEXT NOT NULL,
+    target_status  TEXT NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (indexer, tx_hash, log_index)
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS tx_replacements;
+DROP TABLE IF EXISTS nonce_reservations;
+DROP TABLE IF EXISTS signer_nonces;
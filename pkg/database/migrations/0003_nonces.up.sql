@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS signer_nonces (
+    address    TEXT PRIMARY KEY,
+    next_nonce BIGINT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS nonce_reservations (
+    address    TEXT NOT NULL,
+    nonce      BIGINT NOT NULL,
+    status     TEXT NOT NULL,
+    tx_hash    TEXT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (address, nonce)
+);
+
+CREATE TABLE IF NOT EXISTS tx_replacements (
+    id                  BIGSERIAL PRIMARY KEY,
+    application_id      INTEGER NOT NULL,
+    original_tx_hash    TEXT NOT NULL,
+    replacement_tx_hash TEXT NOT NULL UNIQUE,
+    kind                TEXT NOT NULL,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS tx_replacements_application_id_idx ON tx_replacements (application_id);
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS tx_intents (
+    id             BIGSERIAL PRIMARY KEY,
+    application_id INTEGER NOT NULL,
+    kind           TEXT NOT NULL,
+    status         TEXT NOT NULL,
+    prior_status   TEXT NOT NULL,
+    nonce          BIGINT,
+    tx_hash        TEXT,
+    tx_hashes      TEXT[] NOT NULL DEFAULT '{}',
+    cancel_tx_hash TEXT,
+    raw_tx         BYTEA,
+    block_number   BIGINT,
+    block_hash     TEXT,
+    error          TEXT,
+    revert_reason  TEXT,
+    attempts       INTEGER NOT NULL DEFAULT 0,
+    submitted_at   TIMESTAMPTZ,
+    locked_until   TIMESTAMPTZ,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- At most one intent of each kind may be in flight per application
+CREATE UNIQUE INDEX IF NOT EXISTS tx_intents_in_flight_idx ON tx_intents (application_id, kind)
+    WHERE status IN ('queued', 'submitted', 'mined');
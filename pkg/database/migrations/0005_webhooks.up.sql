@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS webhook_events (
+    id              BIGSERIAL PRIMARY KEY,
+    application_id  INTEGER NOT NULL,
+    event_type      TEXT NOT NULL,
+    payment_status  TEXT NOT NULL,
+    tx_hash_deposit TEXT,
+    tx_hash_release TEXT,
+    tx_hash_refund  TEXT,
+    dispatched      BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS webhook_events_undispatched_idx ON webhook_events (id) WHERE NOT dispatched;
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id               BIGSERIAL PRIMARY KEY,
+    event_id         BIGINT NOT NULL REFERENCES webhook_events (id),
+    endpoint         TEXT NOT NULL,
+    status           TEXT NOT NULL DEFAULT 'pending',
+    attempts         INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_status_code INTEGER,
+    last_error       TEXT,
+    delivered_at     TIMESTAMPTZ,
+    locked_until     TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (event_id, endpoint)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (status, next_attempt_at);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS payment_status_history (
+    id             BIGSERIAL PRIMARY KEY,
+    application_id INTEGER NOT NULL,
+    from_status    TEXT NOT NULL,
+    to_status      TEXT NOT NULL,
+    source         TEXT NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS payment_status_history_application_idx ON payment_status_history (application_id, id);
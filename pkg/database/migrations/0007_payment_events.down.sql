@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS payment_status_history (
+    id             BIGSERIAL PRIMARY KEY,
+    application_id INTEGER NOT NULL,
+    from_status    TEXT NOT NULL,
+    to_status      TEXT NOT NULL,
+    source         TEXT NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS payment_status_history_application_idx ON payment_status_history (application_id, id);
+
+INSERT INTO payment_status_history (application_id, from_status, to_status, source, created_at)
+SELECT application_id, from_status, to_status, source, created_at
+FROM payment_events
+WHERE event_type = 'status_changed'
+ORDER BY id;
+
+DROP TABLE IF EXISTS payment_events;
+DROP FUNCTION IF EXISTS payment_events_append_only();
@@ -0,0 +1,43 @@
+-- Append-only audit ledger of everything that happens to an application's payment.
+-- Amounts are NUMERIC because wei do not fit in a BIGINT.
+CREATE TABLE IF NOT EXISTS payment_events (
+    id               BIGSERIAL PRIMARY KEY,
+    application_id   INTEGER NOT NULL,
+    event_type       TEXT NOT NULL,
+    actor            TEXT NOT NULL,
+    source           TEXT NOT NULL,
+    from_status      TEXT,
+    to_status        TEXT,
+    tx_hash          TEXT,
+    block_number     BIGINT,
+    amount_usd_e8    NUMERIC(38, 0),
+    amount_wei       NUMERIC(78, 0),
+    eth_usd_price_e8 NUMERIC(38, 0),
+    detail           TEXT,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS payment_events_application_idx ON payment_events (application_id, id);
+CREATE INDEX IF NOT EXISTS payment_events_created_at_idx ON payment_events (created_at);
+
+CREATE OR REPLACE FUNCTION payment_events_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'payment_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS payment_events_no_update ON payment_events;
+CREATE TRIGGER payment_events_no_update BEFORE UPDATE OR DELETE ON payment_events
+    FOR EACH ROW EXECUTE FUNCTION payment_events_append_only();
+
+DROP TRIGGER IF EXISTS payment_events_no_truncate ON payment_events;
+CREATE TRIGGER payment_events_no_truncate BEFORE TRUNCATE ON payment_events
+    FOR EACH STATEMENT EXECUTE FUNCTION payment_events_append_only();
+
+-- The ledger supersedes payment_status_history; its rows become status_changed events
+INSERT INTO payment_events (application_id, event_type, actor, source, from_status, to_status, created_at)
+SELECT application_id, 'status_changed', 'unknown', source, from_status, to_status, created_at
+FROM payment_status_history
+ORDER BY id;
+
+DROP TABLE IF EXISTS payment_status_history;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS payment_discrepancies (
+    id             BIGSERIAL PRIMARY KEY,
+    application_id INTEGER NOT NULL,
+    kind           TEXT NOT NULL,
+    payment_status TEXT NOT NULL,
+    chain_state    TEXT NOT NULL,
+    block_number   BIGINT NOT NULL,
+    detail         TEXT NOT NULL,
+    status         TEXT NOT NULL DEFAULT 'open',
+    occurrences    INTEGER NOT NULL DEFAULT 1,
+    resolution     TEXT,
+    first_seen_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolved_at    TIMESTAMPTZ
+);
+
+-- One open discrepancy per application and kind; later sightings update it
+CREATE UNIQUE INDEX IF NOT EXISTS payment_discrepancies_open_idx ON payment_discrepancies (application_id, kind) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS payment_discrepancies_status_idx ON payment_discrepancies (status, id);
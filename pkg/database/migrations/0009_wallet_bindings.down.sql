@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS wallet_bindings;
+DROP TABLE IF EXISTS wallet_nonces;
@@ -0,0 +1,23 @@
+-- Sign-In-With-Ethereum challenges; a nonce is single use and short lived
+CREATE TABLE IF NOT EXISTS wallet_nonces (
+    nonce      TEXT PRIMARY KEY,
+    user_id    INTEGER NOT NULL,
+    address    TEXT NOT NULL,
+    message    TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS wallet_nonces_expires_idx ON wallet_nonces (expires_at) WHERE used_at IS NULL;
+
+-- Wallets a user has proven control of. Addresses are stored lowercase so they compare
+-- with users.wallet_address regardless of checksum casing.
+CREATE TABLE IF NOT EXISTS wallet_bindings (
+    user_id     INTEGER NOT NULL,
+    address     TEXT NOT NULL,
+    nonce       TEXT NOT NULL REFERENCES wallet_nonces (nonce),
+    signature   TEXT NOT NULL,
+    verified_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, address)
+);
@@ -0,0 +1,18 @@
+-- EIP-712 EscrowOffer signatures by which clients agreed to the terms of escrow they
+-- funded from their own wallet
+CREATE TABLE IF NOT EXISTS escrow_offers (
+    id                 BIGSERIAL PRIMARY KEY,
+    application_id     INTEGER NOT NULL,
+    client_address     TEXT NOT NULL,
+    freelancer_address TEXT NOT NULL,
+    usd_amount_e8      NUMERIC(38,0) NOT NULL,
+    expiry             TIMESTAMPTZ NOT NULL,
+    chain_id           BIGINT NOT NULL,
+    contract_address   TEXT NOT NULL,
+    typed_data_hash    TEXT NOT NULL,
+    signature          TEXT NOT NULL UNIQUE,
+    client_tx_hash     TEXT NOT NULL,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS escrow_offers_application_idx ON escrow_offers (application_id, id);
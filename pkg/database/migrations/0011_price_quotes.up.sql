@@ -0,0 +1,15 @@
+-- Time-limited price quotes locking the ETH a deposit must carry. A quote is redeemed by
+-- the deposit transaction it was used for, and cannot be used for another.
+CREATE TABLE IF NOT EXISTS price_quotes (
+    id               TEXT PRIMARY KEY,
+    application_id   INTEGER NOT NULL,
+    usd_amount_e8    NUMERIC(38, 0) NOT NULL,
+    amount_wei       NUMERIC(78, 0) NOT NULL,
+    eth_usd_price_e8 NUMERIC(38, 0) NOT NULL,
+    price_sources    TEXT[] NOT NULL,
+    expires_at       TIMESTAMPTZ NOT NULL,
+    tx_hash          TEXT UNIQUE,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS price_quotes_application_idx ON price_quotes (application_id, created_at);
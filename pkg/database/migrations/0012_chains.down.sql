@@ -0,0 +1,21 @@
+-- Without chain_id one chain's nonces fit; those of several cannot be merged
+DO $$
+BEGIN
+    IF (SELECT COUNT(DISTINCT chain_id) FROM signer_nonces) > 1
+        OR (SELECT COUNT(DISTINCT chain_id) FROM nonce_reservations) > 1 THEN
+        RAISE EXCEPTION 'nonces of several chains are stored; delete those of all but one chain first';
+    END IF;
+END $$;
+
+ALTER TABLE nonce_reservations DROP CONSTRAINT nonce_reservations_pkey;
+ALTER TABLE nonce_reservations DROP COLUMN chain_id;
+ALTER TABLE nonce_reservations ADD PRIMARY KEY (address, nonce);
+
+ALTER TABLE signer_nonces DROP CONSTRAINT signer_nonces_pkey;
+ALTER TABLE signer_nonces DROP COLUMN chain_id;
+ALTER TABLE signer_nonces ADD PRIMARY KEY (address);
+
+DROP INDEX IF EXISTS tx_intents_chain_idx;
+ALTER TABLE price_quotes DROP COLUMN IF EXISTS chain_id;
+ALTER TABLE tx_intents DROP COLUMN IF EXISTS chain_id;
+ALTER TABLE applications DROP COLUMN IF EXISTS escrow_chain_id;
@@ -0,0 +1,21 @@
+-- The chain each escrow job lives on. NULL until a deposit is queued, and for rows written
+-- before the gateway served several chains, which the gateway assigns to its default
+-- chain at startup.
+ALTER TABLE applications ADD COLUMN IF NOT EXISTS escrow_chain_id BIGINT;
+ALTER TABLE tx_intents ADD COLUMN IF NOT EXISTS chain_id BIGINT;
+ALTER TABLE price_quotes ADD COLUMN IF NOT EXISTS chain_id BIGINT;
+
+CREATE INDEX IF NOT EXISTS tx_intents_chain_idx ON tx_intents (chain_id, id)
+    WHERE status IN ('queued', 'submitted', 'mined');
+
+-- Nonces are per chain. Stored sequences and reservations belong to the chain the gateway
+-- served before; they get chain 0 here and the gateway assigns them to its default chain
+-- at startup. Sent reservations must survive, as their transactions may still be
+-- rebroadcast or replaced.
+ALTER TABLE signer_nonces ADD COLUMN chain_id BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE signer_nonces DROP CONSTRAINT signer_nonces_pkey;
+ALTER TABLE signer_nonces ADD PRIMARY KEY (chain_id, address);
+
+ALTER TABLE nonce_reservations ADD COLUMN chain_id BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE nonce_reservations DROP CONSTRAINT nonce_reservations_pkey;
+ALTER TABLE nonce_reservations ADD PRIMARY KEY (chain_id, address, nonce);
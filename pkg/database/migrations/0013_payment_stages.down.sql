@@ -0,0 +1,2 @@
+ALTER TABLE applications DROP COLUMN IF EXISTS payment_staged_to;
+ALTER TABLE applications DROP COLUMN IF EXISTS payment_staged_from;
@@ -0,0 +1,14 @@
+-- The transition an application in pending_confirmations is waiting on: the status it
+-- came from and the one its chain event moves it to. It may leave only to one of them.
+ALTER TABLE applications ADD COLUMN IF NOT EXISTS payment_staged_from TEXT;
+ALTER TABLE applications ADD COLUMN IF NOT EXISTS payment_staged_to TEXT;
+
+-- Applications already waiting take the stage of their earliest pending event
+UPDATE applications a
+SET payment_staged_from = e.prior_status, payment_staged_to = e.target_status
+FROM (
+    SELECT DISTINCT ON (application_id) application_id, prior_status, target_status
+    FROM pending_chain_events
+    ORDER BY application_id, block_number, log_index
+) e
+WHERE a.id = e.application_id AND a.payment_status = 'pending_confirmations';
@@ -0,0 +1,188 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Nonce reservation states, matching blockchain.NonceReserved and friends
+const (
+	nonceReserved = "reserved"
+	nonceSent     = "sent"
+	nonceReleased = "released"
+)
+
+// ChainNonces is the nonce store of one chain. A signer address has an independent
+// nonce sequence on every chain it sends from.
+type ChainNonces struct {
+	db      *DB
+	chainID int64
+}
+
+// Nonces returns the nonce store for chainID
+func (db *DB) Nonces(chainID int64) *ChainNonces {
+	return &ChainNonces{db: db, chainID: chainID}
+}
+
+// ReserveNonce atomically hands out the next nonce for a signer address.
+// The signer_nonces row is locked for the duration, so concurrent gateway
+// processes sharing one key never receive the same nonce.
+func (n *ChainNonces) ReserveNonce(ctx context.Context, address string, chainPending, chainMined uint64, staleAfter time.Duration) (uint64, error) {
+	tx, err := n.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO signer_nonces (chain_id, address, next_nonce) VALUES ($1, $2, $3)
+		ON CONFLICT (chain_id, address) DO NOTHING
+	`, n.chainID, address, int64(chainPending)); err != nil {
+		return 0, fmt.Errorf("error initializing signer nonce: %v", err)
+	}
+
+	var next int64
+	if err := tx.QueryRow(ctx, `
+		SELECT next_nonce FROM signer_nonces WHERE chain_id = $1 AND address = $2 FOR UPDATE
+	`, n.chainID, address).Scan(&next); err != nil {
+		return 0, fmt.Errorf("error locking signer nonce: %v", err)
+	}
+
+	// Mined (or otherwise consumed) nonces no longer need tracking
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM nonce_reservations WHERE chain_id = $1 AND address = $2 AND nonce < $3
+	`, n.chainID, address, int64(chainMined)); err != nil {
+		return 0, fmt.Errorf("error pruning nonce reservations: %v", err)
+	}
+
+	if next < int64(chainPending) {
+		next = int64(chainPending)
+	}
+
+	// A gap at the node's pending nonce means the transaction holding it was dropped
+	// or never sent; everything broadcast after it is stuck until it is filled. A sent
+	// transaction is left to the outbox, which rebroadcasts it or releases the nonce.
+	if int64(chainPending) < next {
+		var status string
+		var updatedAt time.Time
+		var txHash *string
+		err := tx.QueryRow(ctx, `
+			SELECT status, updated_at, tx_hash FROM nonce_reservations WHERE chain_id = $1 AND address = $2 AND nonce = $3
+		`, n.chainID, address, int64(chainPending)).Scan(&status, &updatedAt, &txHash)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return 0, fmt.Errorf("error reading nonce reservation: %v", err)
+		}
+
+		missing := errors.Is(err, pgx.ErrNoRows)
+		stale := !missing && time.Since(updatedAt) > staleAfter
+		if status == nonceSent && stale && txHash != nil {
+			log.Printf("Nonce %d for %s on chain %d was sent as %s but is missing from the node", chainPending, address, n.chainID, *txHash)
+		}
+		if missing || (status == nonceReserved && stale) {
+			log.Printf("Nonce %d for %s on chain %d is missing from the node, releasing for reuse", chainPending, address, n.chainID)
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO nonce_reservations (chain_id, address, nonce, status, updated_at)
+				VALUES ($1, $2, $3, $4, NOW())
+				ON CONFLICT (chain_id, address, nonce) DO UPDATE SET status = EXCLUDED.status, tx_hash = NULL, updated_at = NOW()
+			`, n.chainID, address, int64(chainPending), nonceReleased); err != nil {
+				return 0, fmt.Errorf("error releasing dropped nonce: %v", err)
+			}
+		}
+	}
+
+	// Released nonces are handed out again, lowest first, before new ones
+	var nonce int64
+	err = tx.QueryRow(ctx, `
+		UPDATE nonce_reservations SET status = $3, tx_hash = NULL, updated_at = NOW()
+		WHERE chain_id = $1 AND address = $2 AND nonce = (
+			SELECT MIN(nonce) FROM nonce_reservations WHERE chain_id = $1 AND address = $2 AND status = $4
+		)
+		RETURNING nonce
+	`, n.chainID, address, nonceReserved, nonceReleased).Scan(&nonce)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("error reusing released nonce: %v", err)
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		nonce = next
+		next++
+
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO nonce_reservations (chain_id, address, nonce, status, updated_at)
+			VALUES ($1, $2, $3, $4, NOW())
+			ON CONFLICT (chain_id, address, nonce) DO UPDATE SET status = EXCLUDED.status, tx_hash = NULL, updated_at = NOW()
+		`, n.chainID, address, nonce, nonceReserved); err != nil {
+			return 0, fmt.Errorf("error reserving nonce: %v", err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE signer_nonces SET next_nonce = $3, updated_at = NOW() WHERE chain_id = $1 AND address = $2
+	`, n.chainID, address, next); err != nil {
+		return 0, fmt.Errorf("error advancing signer nonce: %v", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("error committing nonce reservation: %v", err)
+	}
+
+	return uint64(nonce), nil
+}
+
+// MarkNonceSent records the transaction broadcast with a reserved nonce
+func (n *ChainNonces) MarkNonceSent(ctx context.Context, address string, nonce uint64, txHash string) error {
+	result, err := n.db.Pool.Exec(ctx, `
+		UPDATE nonce_reservations SET status = $4, tx_hash = $5, updated_at = NOW()
+		WHERE chain_id = $1 AND address = $2 AND nonce = $3
+	`, n.chainID, address, int64(nonce), nonceSent, txHash)
+	if err != nil {
+		return fmt.Errorf("error marking nonce sent: %v", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("nonce %d is not reserved for %s on chain %d", nonce, address, n.chainID)
+	}
+	return nil
+}
+
+// ReleaseNonce returns a nonce whose transaction never reached the network, or was
+// given up on by the outbox
+func (n *ChainNonces) ReleaseNonce(ctx context.Context, address string, nonce uint64) error {
+	tx, err := n.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var next int64
+	if err := tx.QueryRow(ctx, `
+		SELECT next_nonce FROM signer_nonces WHERE chain_id = $1 AND address = $2 FOR UPDATE
+	`, n.chainID, address).Scan(&next); err != nil {
+		return fmt.Errorf("error locking signer nonce: %v", err)
+	}
+
+	if int64(nonce)+1 == next {
+		// Giving back the newest nonce simply rewinds the counter
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM nonce_reservations WHERE chain_id = $1 AND address = $2 AND nonce = $3
+		`, n.chainID, address, int64(nonce)); err != nil {
+			return fmt.Errorf("error deleting nonce reservation: %v", err)
+		}
+		if _, err := tx.Exec(ctx, `
+			UPDATE signer_nonces SET next_nonce = $3, updated_at = NOW() WHERE chain_id = $1 AND address = $2
+		`, n.chainID, address, int64(nonce)); err != nil {
+			return fmt.Errorf("error rewinding signer nonce: %v", err)
+		}
+	} else if _, err := tx.Exec(ctx, `
+		UPDATE nonce_reservations SET status = $4, tx_hash = NULL, updated_at = NOW()
+		WHERE chain_id = $1 AND address = $2 AND nonce = $3
+	`, n.chainID, address, int64(nonce), nonceReleased); err != nil {
+		return fmt.Errorf("error releasing nonce: %v", err)
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// EscrowOffer is a client's EIP-712 signature over the escrow terms of an application,
+// kept as proof of what the client agreed to fund
+type EscrowOffer struct {
+	ApplicationID     int32
+	ClientAddress     string
+	FreelancerAddress string
+	USDAmountE8       *big.Int
+	Expiry            time.Time
+	ChainID           int64
+	ContractAddress   string
+	TypedDataHash     string
+	Signature         string
+	ClientTxHash      string
+}
+
+// RecordEscrowOffer stores a verified offer signature and enters it in the payment ledger.
+// Recording the same signature again is a no-op.
+func (db *DB) RecordEscrowOffer(ctx context.Context, o EscrowOffer, origin Origin) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := recordEscrowOffer(ctx, tx, o, origin); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// StartClientDeposit records the offer of a deposit the client sent from their own wallet,
+// redeems the price quote quoteID for it unless quoteID is empty, and marks the deposit
+// initiated on chainID, all in one database transaction: a quote is never used up by a
+// deposit the gateway failed to record.
+func (db *DB) StartClientDeposit(ctx context.Context, chainID int64, o EscrowOffer, quoteID string, origin Origin) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if quoteID != "" {
+		if err := redeemPriceQuote(ctx, tx, quoteID, o.ClientTxHash); err != nil {
+			return err
+		}
+	}
+	if err := recordEscrowOffer(ctx, tx, o, origin); err != nil {
+		return err
+	}
+	if err := startEscrowDeposit(ctx, tx, chainID, o.ApplicationID, o.ClientTxHash, origin); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func recordEscrowOffer(ctx context.Context, tx pgx.Tx, o EscrowOffer, origin Origin) error {
+	result, err := tx.Exec(ctx, `
+		INSERT INTO escrow_offers (application_id, client_address, freelancer_address, usd_amount_e8, expiry,
+			chain_id, contract_address, typed_data_hash, signature, client_tx_hash)
+		VALUES ($1, $2, $3, $4::NUMERIC, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (signature) DO NOTHING
+	`, o.ApplicationID, o.ClientAddress, o.FreelancerAddress, o.USDAmountE8.String(), o.Expiry,
+		o.ChainID, o.ContractAddress, o.TypedDataHash, o.Signature, o.ClientTxHash)
+	if err != nil {
+		return fmt.Errorf("error recording escrow offer: %v", err)
+	}
+	if result.RowsAffected() == 0 {
+		return nil
+	}
+
+	return recordPaymentEvent(ctx, tx, paymentEntry{
+		applicationID: o.ApplicationID,
+		eventType:     PaymentEventOfferSigned,
+		origin:        origin,
+		txHash:        o.ClientTxHash,
+		amounts:       Amounts{USDE8: o.USDAmountE8},
+		detail:        fmt.Sprintf("client %s signed offer %s", o.ClientAddress, o.TypedDataHash),
+	})
+}
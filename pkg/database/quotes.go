@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrPriceQuoteNotFound is returned when no price quote has the requested id
+	ErrPriceQuoteNotFound = errors.New("price quote not found")
+	// ErrPriceQuoteRedeemed is returned when a price quote was already used by another transaction
+	ErrPriceQuoteRedeemed = errors.New("price quote was already used for another deposit")
+)
+
+// PriceQuote locks the ETH a deposit for an application on ChainID must carry until ExpiresAt
+type PriceQuote struct {
+	ID            string
+	ApplicationID int32
+	ChainID       int64
+	USDAmountE8   *big.Int
+	Wei           *big.Int
+	EthUSDPriceE8 *big.Int
+	PriceSources  []string
+	ExpiresAt     time.Time
+	TxHash        *string // deposit transaction that redeemed the quote
+	CreatedAt     time.Time
+}
+
+const priceQuoteColumns = `id, application_id, COALESCE(chain_id, 0), usd_amount_e8::TEXT, amount_wei::TEXT, eth_usd_price_e8::TEXT,
+	price_sources, expires_at, tx_hash, created_at`
+
+func scanPriceQuote(row pgx.Row) (*PriceQuote, error) {
+	var q PriceQuote
+	var usdE8, wei, price string
+	if err := row.Scan(&q.ID, &q.ApplicationID, &q.ChainID, &usdE8, &wei, &price,
+		&q.PriceSources, &q.ExpiresAt, &q.TxHash, &q.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	var ok [3]bool
+	q.USDAmountE8, ok[0] = new(big.Int).SetString(usdE8, 10)
+	q.Wei, ok[1] = new(big.Int).SetString(wei, 10)
+	q.EthUSDPriceE8, ok[2] = new(big.Int).SetString(price, 10)
+	if !ok[0] || !ok[1] || !ok[2] {
+		return nil, fmt.Errorf("price quote %s has a malformed amount", q.ID)
+	}
+	return &q, nil
+}
+
+// CreatePriceQuote stores a newly issued quote
+func (db *DB) CreatePriceQuote(ctx context.Context, q PriceQuote) error {
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO price_quotes (id, application_id, chain_id, usd_amount_e8, amount_wei, eth_usd_price_e8, price_sources, expires_at)
+		VALUES ($1, $2, $3, $4::NUMERIC, $5::NUMERIC, $6::NUMERIC, $7, $8)
+	`, q.ID, q.ApplicationID, q.ChainID, q.USDAmountE8.String(), q.Wei.String(), q.EthUSDPriceE8.String(), q.PriceSources, q.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("error creating price quote: %v", err)
+	}
+	return nil
+}
+
+// GetPriceQuote returns a quote whether or not it has expired or been redeemed
+func (db *DB) GetPriceQuote(ctx context.Context, id string) (*PriceQuote, error) {
+	q, err := scanPriceQuote(db.Pool.QueryRow(ctx, `
+		SELECT `+priceQuoteColumns+` FROM price_quotes WHERE id = $1
+	`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPriceQuoteNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying price quote: %v", err)
+	}
+	return q, nil
+}
+
+// GetPriceQuoteByTx returns the quote txHash redeemed, or nil if it was sent without one
+func (db *DB) GetPriceQuoteByTx(ctx context.Context, txHash string) (*PriceQuote, error) {
+	q, err := scanPriceQuote(db.Pool.QueryRow(ctx, `
+		SELECT `+priceQuoteColumns+` FROM price_quotes WHERE tx_hash = $1
+	`, strings.ToLower(txHash)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying price quote: %v", err)
+	}
+	return q, nil
+}
+
+// RedeemPriceQuote marks a quote as used by the deposit transaction txHash. Redeeming it
+// again for the same transaction is a no-op; for any other it returns ErrPriceQuoteRedeemed.
+func (db *DB) RedeemPriceQuote(ctx context.Context, id, txHash string) error {
+	return redeemPriceQuote(ctx, db.Pool, id, txHash)
+}
+
+func redeemPriceQuote(ctx context.Context, q querier, id, txHash string) error {
+	txHash = strings.ToLower(txHash)
+
+	var redeemedBy string
+	err := q.QueryRow(ctx, `
+		UPDATE price_quotes SET tx_hash = COALESCE(tx_hash, $2)
+		WHERE id = $1
+		RETURNING tx_hash
+	`, id, txHash).Scan(&redeemedBy)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrPriceQuoteNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error redeeming price quote: %v", err)
+	}
+	if redeemedBy != txHash {
+		return fmt.Errorf("%w: %s", ErrPriceQuoteRedeemed, redeemedBy)
+	}
+	return nil
+}
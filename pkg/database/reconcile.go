@@ -0,0 +1,264 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/fahedafzaal/go-integration/pkg/money"
+	"github.com/fahedafzaal/go-integration/pkg/payment"
+)
+
+// Payment discrepancy statuses
+const (
+	DiscrepancyOpen     = "open"     // drift seen on the latest reconciler pass, or not yet looked at
+	DiscrepancyResolved = "resolved" // back in sync, healed, or closed by an operator
+)
+
+// SourceReconciler is the payment ledger source of changes made by the reconciler
+const SourceReconciler = "reconciler"
+
+var reconcilerOrigin = Origin{Actor: ActorGateway, Source: SourceReconciler}
+
+// ErrDiscrepancyNotFound is returned when no payment discrepancy has the requested id
+var ErrDiscrepancyNotFound = errors.New("payment discrepancy not found")
+
+// ReconcileCandidate is an application whose payment status the reconciler compares
+// with the escrow contract
+type ReconcileCandidate struct {
+	ApplicationID          int32
+	PaymentStatus          payment.Status
+	AgreedUSDAmount        *money.USD
+	ApplicantWalletAddress *string
+	PosterWalletAddress    *string
+}
+
+// PaymentDiscrepancy is a drift between payment_status and the chain that the reconciler
+// would not fix on its own
+type PaymentDiscrepancy struct {
+	ID            int64          `json:"id"`
+	ApplicationID int32          `json:"application_id"`
+	Kind          string         `json:"kind"`
+	PaymentStatus payment.Status `json:"payment_status"`
+	ChainState    string         `json:"chain_state"`
+	BlockNumber   int64          `json:"block_number"`
+	Detail        string         `json:"detail"`
+	Status        string         `json:"status"`
+	Occurrences   int            `json:"occurrences"`
+	Resolution    *string        `json:"resolution,omitempty"`
+	FirstSeenAt   time.Time      `json:"first_seen_at"`
+	LastSeenAt    time.Time      `json:"last_seen_at"`
+	ResolvedAt    *time.Time     `json:"resolved_at,omitempty"`
+}
+
+// PaymentDiscrepancyFilter narrows ListPaymentDiscrepancies; zero values match everything
+type PaymentDiscrepancyFilter struct {
+	ApplicationID int32
+	Status        string
+	Limit         int
+}
+
+const paymentDiscrepancyColumns = `id, application_id, kind, payment_status, chain_state, block_number, detail,
+	status, occurrences, resolution, first_seen_at, last_seen_at, resolved_at`
+
+func scanPaymentDiscrepancy(row pgx.Row) (*PaymentDiscrepancy, error) {
+	var d PaymentDiscrepancy
+	err := row.Scan(&d.ID, &d.ApplicationID, &d.Kind, &d.PaymentStatus, &d.ChainState, &d.BlockNumber, &d.Detail,
+		&d.Status, &d.Occurrences, &d.Resolution, &d.FirstSeenAt, &d.LastSeenAt, &d.ResolvedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ListReconcileCandidates returns up to limit applications with an escrow job on chainID
+// after afterID, in id order, whose payment status is one of statuses. Applications with a transaction in the outbox,
+// or whose ledger moved within settle, are skipped: the outbox and indexer are still
+// working on them.
+func (db *DB) ListReconcileCandidates(ctx context.Context, chainID int64, statuses []payment.Status, afterID int32, limit int, settle time.Duration) ([]ReconcileCandidate, error) {
+	names := make([]string, len(statuses))
+	for i, status := range statuses {
+		names[i] = string(status)
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT a.id, a.payment_status, a.agreed_usd_amount, applicant.wallet_address, poster.wallet_address
+		FROM applications a
+		JOIN jobs j ON a.job_id = j.id
+		JOIN users applicant ON a.user_id = applicant.id
+		JOIN users poster ON j.user_id = poster.id
+		WHERE a.escrow_chain_id = $1 AND a.id > $2 AND a.payment_status = ANY($3)
+		  AND NOT EXISTS (
+			SELECT 1 FROM tx_intents t
+			WHERE t.application_id = a.id AND t.status IN ('queued', 'submitted', 'mined')
+		  )
+		  AND NOT EXISTS (
+			SELECT 1 FROM payment_events e
+			WHERE e.application_id = a.id AND e.created_at > NOW() - make_interval(secs => $4)
+		  )
+		ORDER BY a.id
+		LIMIT $5
+	`, chainID, afterID, names, settle.Seconds(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("error listing reconcile candidates: %v", err)
+	}
+	defer rows.Close()
+
+	var candidates []ReconcileCandidate
+	for rows.Next() {
+		var c ReconcileCandidate
+		if err := rows.Scan(&c.ApplicationID, &c.PaymentStatus, &c.AgreedUSDAmount,
+			&c.ApplicantWalletAddress, &c.PosterWalletAddress); err != nil {
+			return nil, fmt.Errorf("error scanning reconcile candidate: %v", err)
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// HealPaymentStatus walks an application from status along path, one legal transition
+// at a time, to match the chain as read at blockNumber, and resolves its open
+// discrepancies. Nothing changes, and false is returned, if the application has left
+// status since it was read.
+func (db *DB) HealPaymentStatus(ctx context.Context, applicationID int32, status payment.Status, path []payment.Status, blockNumber uint64, detail string) (bool, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	cause := paymentEntry{origin: reconcilerOrigin, blockNumber: blockNumber, detail: detail}
+	from := status
+	for _, next := range path {
+		changed, err := setPaymentStatus(ctx, tx, applicationID, next, cause, from)
+		if err != nil {
+			return false, err
+		}
+		if !changed {
+			return false, nil
+		}
+		from = next
+	}
+
+	if err := resolvePaymentDiscrepancies(ctx, tx, applicationID, "healed by reconciler: "+detail); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit payment status heal: %v", err)
+	}
+	return true, nil
+}
+
+// FlagPaymentDiscrepancy opens a discrepancy, or refreshes the open one of the same kind
+// for the application. It reports whether a new discrepancy was opened.
+func (db *DB) FlagPaymentDiscrepancy(ctx context.Context, d PaymentDiscrepancy) (bool, error) {
+	var created bool
+	err := db.Pool.QueryRow(ctx, `
+		INSERT INTO payment_discrepancies (application_id, kind, payment_status, chain_state, block_number, detail)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (application_id, kind) WHERE status = 'open' DO UPDATE
+		SET payment_status = EXCLUDED.payment_status, chain_state = EXCLUDED.chain_state,
+		    block_number = EXCLUDED.block_number, detail = EXCLUDED.detail,
+		    occurrences = payment_discrepancies.occurrences + 1, last_seen_at = NOW()
+		RETURNING xmax = 0
+	`, d.ApplicationID, d.Kind, d.PaymentStatus, d.ChainState, d.BlockNumber, d.Detail).Scan(&created)
+	if err != nil {
+		return false, fmt.Errorf("error flagging payment discrepancy for application %d: %v", d.ApplicationID, err)
+	}
+	return created, nil
+}
+
+// ResolvePaymentDiscrepancies closes every open discrepancy of an application found back
+// in sync with the chain
+func (db *DB) ResolvePaymentDiscrepancies(ctx context.Context, applicationID int32, resolution string) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := resolvePaymentDiscrepancies(ctx, tx, applicationID, resolution); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func resolvePaymentDiscrepancies(ctx context.Context, tx pgx.Tx, applicationID int32, resolution string) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE payment_discrepancies
+		SET status = 'resolved', resolution = $2, resolved_at = NOW()
+		WHERE application_id = $1 AND status = 'open'
+	`, applicationID, resolution)
+	if err != nil {
+		return fmt.Errorf("error resolving payment discrepancies for application %d: %v", applicationID, err)
+	}
+	return nil
+}
+
+// ResolvePaymentDiscrepancy closes a discrepancy an operator has dealt with. Resolving an
+// already resolved discrepancy returns it unchanged.
+func (db *DB) ResolvePaymentDiscrepancy(ctx context.Context, id int64, resolution string) (*PaymentDiscrepancy, error) {
+	d, err := scanPaymentDiscrepancy(db.Pool.QueryRow(ctx, `
+		UPDATE payment_discrepancies
+		SET status = 'resolved', resolution = $2, resolved_at = NOW()
+		WHERE id = $1 AND status = 'open'
+		RETURNING `+paymentDiscrepancyColumns, id, resolution))
+	if errors.Is(err, pgx.ErrNoRows) {
+		d, err = scanPaymentDiscrepancy(db.Pool.QueryRow(ctx, `
+			SELECT `+paymentDiscrepancyColumns+` FROM payment_discrepancies WHERE id = $1
+		`, id))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDiscrepancyNotFound
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error resolving payment discrepancy %d: %v", id, err)
+	}
+	return d, nil
+}
+
+// ListPaymentDiscrepancies returns discrepancies matching filter, newest first
+func (db *DB) ListPaymentDiscrepancies(ctx context.Context, filter PaymentDiscrepancyFilter) ([]PaymentDiscrepancy, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.ApplicationID != 0 {
+		args = append(args, filter.ApplicationID)
+		conditions = append(conditions, fmt.Sprintf("application_id = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	args = append(args, limit)
+
+	query := `SELECT ` + paymentDiscrepancyColumns + ` FROM payment_discrepancies`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(` ORDER BY id DESC LIMIT $%d`, len(args))
+
+	rows, err := db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing payment discrepancies: %v", err)
+	}
+	defer rows.Close()
+
+	var discrepancies []PaymentDiscrepancy
+	for rows.Next() {
+		d, err := scanPaymentDiscrepancy(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning payment discrepancy: %v", err)
+		}
+		discrepancies = append(discrepancies, *d)
+	}
+	return discrepancies, rows.Err()
+}
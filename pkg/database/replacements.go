@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrApplicationTxNotFound is returned for a transaction hash that was never sent for the
+// application
+var ErrApplicationTxNotFound = errors.New("transaction was not sent for this application")
+
+// TxReplacement is a fee-bumped or cancelling transaction sent in place of a stuck one
+type TxReplacement struct {
+	OriginalTxHash    string    `json:"original_tx_hash"`
+	ReplacementTxHash string    `json:"replacement_tx_hash"`
+	Kind              string    `json:"kind"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// RecordTxReplacement stores a replacement transaction hash against its application and
+// enters it in the payment ledger
+func (db *DB) RecordTxReplacement(ctx context.Context, applicationID int32, originalTxHash, replacementTxHash, kind string) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		INSERT INTO tx_replacements (application_id, original_tx_hash, replacement_tx_hash, kind)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (replacement_tx_hash) DO NOTHING
+	`, applicationID, originalTxHash, replacementTxHash, kind)
+	if err != nil {
+		return fmt.Errorf("error recording transaction replacement: %v", err)
+	}
+	if result.RowsAffected() == 0 {
+		return nil
+	}
+
+	if err := recordPaymentEvent(ctx, tx, paymentEntry{
+		applicationID: applicationID,
+		eventType:     PaymentEventTxReplaced,
+		origin:        Origin{Actor: ActorGateway, Source: SourceReplacement},
+		txHash:        replacementTxHash,
+		detail:        fmt.Sprintf("%s of %s", kind, originalTxHash),
+	}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ListTxReplacements returns the replacements sent for an application, oldest first
+func (db *DB) ListTxReplacements(ctx context.Context, applicationID int32) ([]TxReplacement, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT original_tx_hash, replacement_tx_hash, kind, created_at
+		FROM tx_replacements
+		WHERE application_id = $1
+		ORDER BY id
+	`, applicationID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing transaction replacements: %v", err)
+	}
+	defer rows.Close()
+
+	var replacements []TxReplacement
+	for rows.Next() {
+		var r TxReplacement
+		if err := rows.Scan(&r.OriginalTxHash, &r.ReplacementTxHash, &r.Kind, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning transaction replacement: %v", err)
+		}
+		replacements = append(replacements, r)
+	}
+	return replacements, rows.Err()
+}
+
+// FindApplicationTx looks txHash up among the transactions sent for an application: the
+// versions of its transaction intents, its escrow transactions and their replacements.
+// The intent is returned when txHash is one of its versions, nil otherwise; a hash the
+// application never sent fails with ErrApplicationTxNotFound.
+func (db *DB) FindApplicationTx(ctx context.Context, applicationID int32, txHash string) (*TxIntent, error) {
+	intent, err := scanTxIntent(db.Pool.QueryRow(ctx, `
+		SELECT `+txIntentColumns+` FROM tx_intents
+		WHERE application_id = $1 AND EXISTS (SELECT 1 FROM unnest(tx_hashes) h WHERE LOWER(h) = LOWER($2))
+		ORDER BY id DESC
+		LIMIT 1
+	`, applicationID, txHash))
+	if err == nil {
+		return intent, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("error finding transaction intent for %s: %v", txHash, err)
+	}
+
+	var sent bool
+	err = db.Pool.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM applications
+			WHERE id = $1 AND LOWER($2) IN (LOWER(escrow_tx_hash_deposit), LOWER(escrow_tx_hash_release), LOWER(escrow_tx_hash_refund))
+		) OR EXISTS (
+			SELECT 1 FROM tx_replacements
+			WHERE application_id = $1 AND LOWER(replacement_tx_hash) = LOWER($2)
+		)
+	`, applicationID, txHash).Scan(&sent)
+	if err != nil {
+		return nil, fmt.Errorf("error finding transaction %s: %v", txHash, err)
+	}
+	if !sent {
+		return nil, ErrApplicationTxNotFound
+	}
+	return nil, nil
+}
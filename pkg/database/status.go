@@ -0,0 +1,173 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/fahedafzaal/go-integration/pkg/payment"
+)
+
+// Webhook event types that do not correspond to a payment status
+const (
+	WebhookEventFailed = "escrow.failed" // a queued transaction failed; payment status was restored
+)
+
+// PaymentStatusEventType returns the webhook event type announcing a payment status
+func PaymentStatusEventType(status payment.Status) string {
+	return "escrow." + string(status)
+}
+
+// setPaymentStatus is the single place gateway code changes applications.payment_status.
+// It locks the application, and when from is given leaves applications in any other
+// status untouched. Anything else must be a transition the payment state machine
+// allows, or a *payment.TransitionError is returned; an application in
+// pending_confirmations may only leave to the outcome of its stage, or back to where it
+// came from. Parking in pending_confirmations goes through stagePaymentStatus. The write
+// is a compare-and-swap on the status that was read, and each change is recorded in the
+// payment_events ledger, attributed to cause, and queued as a webhook event in the same
+// transaction, so both reflect exactly the transitions that commit. It reports whether
+// the status changed.
+func setPaymentStatus(ctx context.Context, tx pgx.Tx, applicationID int32, status payment.Status, cause paymentEntry, from ...payment.Status) (bool, error) {
+	current, found, err := currentPaymentStatus(ctx, tx, applicationID)
+	if err != nil {
+		return false, err
+	}
+	if !found || current == status {
+		return false, nil
+	}
+	if len(from) > 0 && !slices.Contains(from, current) {
+		return false, nil
+	}
+
+	if current == payment.PendingConfirmations {
+		stage, err := currentPaymentStage(ctx, tx, applicationID)
+		if err != nil {
+			return false, err
+		}
+		if err := stage.Exit(status); err != nil {
+			return false, fmt.Errorf("application %d: %w", applicationID, err)
+		}
+	} else if err := payment.Transition(current, status); err != nil {
+		return false, fmt.Errorf("application %d: %w", applicationID, err)
+	}
+
+	if err := writePaymentStatus(ctx, tx, applicationID, current, status, payment.Stage{}, cause); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// stagePaymentStatus parks an application in pending_confirmations while a chain event
+// moving it to target gains confirmations. The status it leaves and target are stored as
+// its stage, the only statuses setPaymentStatus lets it move to next. An application
+// already in pending_confirmations keeps its stage and false is returned.
+func stagePaymentStatus(ctx context.Context, tx pgx.Tx, applicationID int32, target payment.Status, cause paymentEntry) (bool, error) {
+	current, found, err := currentPaymentStatus(ctx, tx, applicationID)
+	if err != nil {
+		return false, err
+	}
+	if !found || current == payment.PendingConfirmations {
+		return false, nil
+	}
+
+	stage, err := payment.NewStage(current, target)
+	if err != nil {
+		return false, fmt.Errorf("application %d: %w", applicationID, err)
+	}
+	if err := writePaymentStatus(ctx, tx, applicationID, current, payment.PendingConfirmations, stage, cause); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// setPaymentStage replaces the stage of an application in pending_confirmations, when an
+// earlier of several pending chain events is confirmed and the next one is waited on
+func setPaymentStage(ctx context.Context, tx pgx.Tx, applicationID int32, from, to payment.Status) error {
+	stage, err := payment.NewStage(from, to)
+	if err != nil {
+		return fmt.Errorf("application %d: %w", applicationID, err)
+	}
+
+	result, err := tx.Exec(ctx, `
+		UPDATE applications SET payment_staged_from = $2, payment_staged_to = $3
+		WHERE id = $1 AND payment_status = 'pending_confirmations'
+	`, applicationID, stage.From, stage.To)
+	if err != nil {
+		return fmt.Errorf("error updating payment stage for application %d: %v", applicationID, err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("%w: application %d is no longer '%s'", payment.ErrStatusConflict, applicationID, payment.PendingConfirmations)
+	}
+	return nil
+}
+
+// writePaymentStatus swaps the status from current to status, storing stage, and records
+// the change in the ledger and as a webhook event
+func writePaymentStatus(ctx context.Context, tx pgx.Tx, applicationID int32, current, status payment.Status, stage payment.Stage, cause paymentEntry) error {
+	result, err := tx.Exec(ctx, `
+		UPDATE applications
+		SET payment_status = $1, payment_staged_from = NULLIF($4, ''), payment_staged_to = NULLIF($5, '')
+		WHERE id = $2 AND COALESCE(NULLIF(payment_status, ''), 'pending_deposit') = $3
+	`, status, applicationID, current, stage.From, stage.To)
+	if err != nil {
+		return fmt.Errorf("error updating payment status for application %d: %v", applicationID, err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("%w: application %d is no longer '%s'", payment.ErrStatusConflict, applicationID, current)
+	}
+
+	cause.applicationID, cause.eventType, cause.from, cause.to = applicationID, PaymentEventStatusChanged, current, status
+	if err := recordPaymentEvent(ctx, tx, cause); err != nil {
+		return err
+	}
+
+	return queueWebhookEvent(ctx, tx, applicationID, PaymentStatusEventType(status))
+}
+
+// currentPaymentStage returns the stage of an application in pending_confirmations
+func currentPaymentStage(ctx context.Context, tx pgx.Tx, applicationID int32) (payment.Stage, error) {
+	var from, to *payment.Status
+	err := tx.QueryRow(ctx, `
+		SELECT payment_staged_from, payment_staged_to FROM applications WHERE id = $1
+	`, applicationID).Scan(&from, &to)
+	if err != nil {
+		return payment.Stage{}, fmt.Errorf("error reading payment stage: %v", err)
+	}
+	if from == nil || to == nil {
+		return payment.Stage{}, fmt.Errorf("application %d is in '%s' without a stage", applicationID, payment.PendingConfirmations)
+	}
+	return payment.Stage{From: *from, To: *to}, nil
+}
+
+// currentPaymentStatus locks the application row and returns its payment status
+func currentPaymentStatus(ctx context.Context, tx pgx.Tx, applicationID int32) (payment.Status, bool, error) {
+	var status payment.Status
+	err := tx.QueryRow(ctx, `
+		SELECT COALESCE(NULLIF(payment_status, ''), 'pending_deposit') FROM applications WHERE id = $1 FOR UPDATE
+	`, applicationID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("error reading payment status: %v", err)
+	}
+	return status, true, nil
+}
+
+// queueWebhookEvent snapshots the application's payment state into webhook_events
+func queueWebhookEvent(ctx context.Context, tx pgx.Tx, applicationID int32, eventType string) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO webhook_events (application_id, event_type, payment_status, tx_hash_deposit, tx_hash_release, tx_hash_refund)
+		SELECT id, $2, COALESCE(NULLIF(payment_status, ''), 'pending_deposit'),
+		       escrow_tx_hash_deposit, escrow_tx_hash_release, escrow_tx_hash_refund
+		FROM applications WHERE id = $1
+	`, applicationID, eventType)
+	if err != nil {
+		return fmt.Errorf("error queueing %s webhook event for application %d: %v", eventType, applicationID, err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/fahedafzaal/go-integration/pkg/payment"
+)
+
+// The indexer and outbox tables must only describe moves the state machine allows,
+// or setPaymentStatus would reject them at runtime
+func TestStatusFlowsAreLegal(t *testing.T) {
+	for name, transition := range chainEventTransitions {
+		for _, from := range transition.from {
+			if err := payment.Transition(from, transition.status); err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+			// Staged, then confirmed or rolled back by a reorg
+			stage, err := payment.NewStage(from, transition.status)
+			if err != nil {
+				t.Errorf("%s staging: %v", name, err)
+				continue
+			}
+			for _, to := range []payment.Status{transition.status, from} {
+				if err := stage.Exit(to); err != nil {
+					t.Errorf("%s staging: %v", name, err)
+				}
+			}
+		}
+	}
+
+	for kind, spec := range txIntentKinds {
+		for _, step := range [][2]payment.Status{
+			{spec.from, spec.initiated},  // enqueue
+			{spec.initiated, spec.final}, // confirm
+			{spec.initiated, spec.from},  // fail
+		} {
+			if err := payment.Transition(step[0], step[1]); err != nil {
+				t.Errorf("%s intent: %v", kind, err)
+			}
+		}
+	}
+}
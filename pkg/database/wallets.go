@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrWalletNonceInvalid is returned for a wallet nonce that is unknown, used or expired
+var ErrWalletNonceInvalid = errors.New("wallet nonce is unknown, used or expired")
+
+// WalletNonce is a Sign-In-With-Ethereum challenge issued to a user for an address
+type WalletNonce struct {
+	Nonce     string
+	UserID    int32
+	Address   string
+	Message   string
+	ExpiresAt time.Time
+}
+
+// WalletBinding records that a user proved control of a wallet address
+type WalletBinding struct {
+	UserID     int32     `json:"user_id"`
+	Address    string    `json:"address"`
+	VerifiedAt time.Time `json:"verified_at"`
+}
+
+// CreateWalletNonce stores a challenge message to be signed by address
+func (db *DB) CreateWalletNonce(ctx context.Context, n WalletNonce) error {
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO wallet_nonces (nonce, user_id, address, message, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, n.Nonce, n.UserID, strings.ToLower(n.Address), n.Message, n.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("error creating wallet nonce: %v", err)
+	}
+	return nil
+}
+
+// GetWalletNonce returns an unused, unexpired challenge
+func (db *DB) GetWalletNonce(ctx context.Context, nonce string) (*WalletNonce, error) {
+	var n WalletNonce
+	err := db.Pool.QueryRow(ctx, `
+		SELECT nonce, user_id, address, message, expires_at
+		FROM wallet_nonces
+		WHERE nonce = $1 AND used_at IS NULL AND expires_at > NOW()
+	`, nonce).Scan(&n.Nonce, &n.UserID, &n.Address, &n.Message, &n.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWalletNonceInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying wallet nonce: %v", err)
+	}
+	return &n, nil
+}
+
+// BindWallet spends a challenge whose signature has been checked and records the wallet
+// as verified for its user. A nonce spent concurrently, or expired since it was read,
+// returns ErrWalletNonceInvalid.
+func (db *DB) BindWallet(ctx context.Context, nonce, signature string) (*WalletBinding, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var binding WalletBinding
+	err = tx.QueryRow(ctx, `
+		UPDATE wallet_nonces SET used_at = NOW()
+		WHERE nonce = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id, address
+	`, nonce).Scan(&binding.UserID, &binding.Address)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWalletNonceInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error spending wallet nonce: %v", err)
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO wallet_bindings (user_id, address, nonce, signature)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, address) DO UPDATE
+		SET nonce = EXCLUDED.nonce, signature = EXCLUDED.signature, verified_at = NOW()
+		RETURNING verified_at
+	`, binding.UserID, binding.Address, nonce, signature).Scan(&binding.VerifiedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error binding wallet: %v", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit wallet binding: %v", err)
+	}
+	return &binding, nil
+}
+
+// ListWalletBindings returns the wallets a user has verified, most recent first
+func (db *DB) ListWalletBindings(ctx context.Context, userID int32) ([]WalletBinding, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT user_id, address, verified_at
+		FROM wallet_bindings
+		WHERE user_id = $1
+		ORDER BY verified_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing wallet bindings: %v", err)
+	}
+	defer rows.Close()
+
+	var bindings []WalletBinding
+	for rows.Next() {
+		var b WalletBinding
+		if err := rows.Scan(&b.UserID, &b.Address, &b.VerifiedAt); err != nil {
+			return nil, fmt.Errorf("error scanning wallet binding: %v", err)
+		}
+		bindings = append(bindings, b)
+	}
+	return bindings, rows.Err()
+}
@@ -0,0 +1,259 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Webhook delivery statuses
+const (
+	WebhookPending   = "pending"   // waiting for its next attempt
+	WebhookDelivered = "delivered" // the endpoint answered with a 2xx
+	WebhookFailed    = "failed"    // out of attempts; can be replayed
+)
+
+// ErrWebhookDeliveryNotFound is returned when no webhook delivery has the requested id
+var ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+
+// WebhookEvent is a payment state change snapshotted in the transaction that made it
+type WebhookEvent struct {
+	ID            int64     `json:"id"`
+	ApplicationID int32     `json:"application_id"`
+	Type          string    `json:"type"`
+	PaymentStatus string    `json:"payment_status"`
+	TxHashDeposit *string   `json:"tx_hash_deposit,omitempty"`
+	TxHashRelease *string   `json:"tx_hash_release,omitempty"`
+	TxHashRefund  *string   `json:"tx_hash_refund,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// WebhookDelivery is one event sent to one endpoint, with its retry state
+type WebhookDelivery struct {
+	ID             int64        `json:"id"`
+	Event          WebhookEvent `json:"event"`
+	Endpoint       string       `json:"endpoint"`
+	Status         string       `json:"status"`
+	Attempts       int          `json:"attempts"`
+	NextAttemptAt  time.Time    `json:"next_attempt_at"`
+	LastStatusCode *int         `json:"last_status_code,omitempty"`
+	LastError      *string      `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time   `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+// WebhookDeliveryFilter narrows ListWebhookDeliveries; zero values match everything
+type WebhookDeliveryFilter struct {
+	ApplicationID int32
+	Status        string
+	Limit         int
+}
+
+const webhookDeliveryColumns = `
+	d.id, e.id, e.application_id, e.event_type, e.payment_status,
+	e.tx_hash_deposit, e.tx_hash_release, e.tx_hash_refund, e.created_at,
+	d.endpoint, d.status, d.attempts, d.next_attempt_at, d.last_status_code, d.last_error,
+	d.delivered_at, d.created_at, d.updated_at`
+
+func scanWebhookDelivery(row pgx.Row) (*WebhookDelivery, error) {
+	var d WebhookDelivery
+	err := row.Scan(
+		&d.ID, &d.Event.ID, &d.Event.ApplicationID, &d.Event.Type, &d.Event.PaymentStatus,
+		&d.Event.TxHashDeposit, &d.Event.TxHashRelease, &d.Event.TxHashRefund, &d.Event.CreatedAt,
+		&d.Endpoint, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.LastStatusCode, &d.LastError,
+		&d.DeliveredAt, &d.CreatedAt, &d.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// FanOutWebhookEvents creates a delivery per endpoint for every event not yet dispatched.
+// Events are marked dispatched even when no endpoints are configured, so turning
+// webhooks on later does not flood subscribers with history.
+func (db *DB) FanOutWebhookEvents(ctx context.Context, endpoints []string) (int, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		UPDATE webhook_events SET dispatched = TRUE
+		WHERE id IN (
+			SELECT id FROM webhook_events WHERE NOT dispatched
+			ORDER BY id
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("error dispatching webhook events: %v", err)
+	}
+	var eventIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("error scanning webhook event: %v", err)
+		}
+		eventIDs = append(eventIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error dispatching webhook events: %v", err)
+	}
+
+	if len(eventIDs) > 0 && len(endpoints) > 0 {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO webhook_deliveries (event_id, endpoint)
+			SELECT e, u FROM unnest($1::BIGINT[]) AS e, unnest($2::TEXT[]) AS u
+			ON CONFLICT (event_id, endpoint) DO NOTHING
+		`, eventIDs, endpoints); err != nil {
+			return 0, fmt.Errorf("error creating webhook deliveries: %v", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit webhook events: %v", err)
+	}
+	return len(eventIDs), nil
+}
+
+// ClaimWebhookDeliveries leases up to limit pending deliveries that are due, oldest first
+func (db *DB) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	rows, err := db.Pool.Query(ctx, `
+		WITH claimed AS (
+			UPDATE webhook_deliveries
+			SET locked_until = NOW() + make_interval(secs => $2)
+			WHERE id IN (
+				SELECT id FROM webhook_deliveries
+				WHERE status = 'pending' AND next_attempt_at <= NOW()
+				  AND (locked_until IS NULL OR locked_until < NOW())
+				ORDER BY next_attempt_at, id
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING *
+		)
+		SELECT `+webhookDeliveryColumns+`
+		FROM claimed d JOIN webhook_events e ON e.id = d.event_id
+		ORDER BY d.id
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("error claiming webhook deliveries: %v", err)
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning webhook delivery: %v", err)
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
+
+// MarkWebhookDelivered records a successful delivery
+func (db *DB) MarkWebhookDelivered(ctx context.Context, id int64, statusCode int) error {
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'delivered', attempts = attempts + 1, last_status_code = $2, last_error = NULL,
+		    delivered_at = NOW(), locked_until = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, id, statusCode)
+	if err != nil {
+		return fmt.Errorf("error marking webhook delivery %d delivered: %v", id, err)
+	}
+	return nil
+}
+
+// RecordWebhookFailure records a failed attempt. The delivery is retried at nextAttemptAt,
+// or marked failed when nextAttemptAt is nil.
+func (db *DB) RecordWebhookFailure(ctx context.Context, id int64, statusCode *int, errMsg string, nextAttemptAt *time.Time) error {
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = CASE WHEN $4::TIMESTAMPTZ IS NULL THEN 'failed' ELSE 'pending' END,
+		    attempts = attempts + 1, last_status_code = $2, last_error = $3,
+		    next_attempt_at = COALESCE($4, next_attempt_at), locked_until = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, id, statusCode, errMsg, nextAttemptAt)
+	if err != nil {
+		return fmt.Errorf("error recording webhook delivery %d failure: %v", id, err)
+	}
+	return nil
+}
+
+// ListWebhookDeliveries returns deliveries matching filter, newest first
+func (db *DB) ListWebhookDeliveries(ctx context.Context, filter WebhookDeliveryFilter) ([]WebhookDelivery, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.ApplicationID != 0 {
+		args = append(args, filter.ApplicationID)
+		conditions = append(conditions, fmt.Sprintf("e.application_id = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("d.status = $%d", len(args)))
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	args = append(args, limit)
+
+	query := `SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries d JOIN webhook_events e ON e.id = d.event_id`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(` ORDER BY d.id DESC LIMIT $%d`, len(args))
+
+	rows, err := db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing webhook deliveries: %v", err)
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning webhook delivery: %v", err)
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
+
+// ReplayWebhookDelivery queues a delivery to be sent again straight away with a fresh
+// attempt budget, whatever its current status
+func (db *DB) ReplayWebhookDelivery(ctx context.Context, id int64) (*WebhookDelivery, error) {
+	d, err := scanWebhookDelivery(db.Pool.QueryRow(ctx, `
+		WITH replayed AS (
+			UPDATE webhook_deliveries
+			SET status = 'pending', attempts = 0, next_attempt_at = NOW(), delivered_at = NULL,
+			    locked_until = NULL, updated_at = NOW()
+			WHERE id = $1
+			RETURNING *
+		)
+		SELECT `+webhookDeliveryColumns+`
+		FROM replayed d JOIN webhook_events e ON e.id = d.event_id
+	`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error replaying webhook delivery %d: %v", id, err)
+	}
+	return d, nil
+}
@@ -0,0 +1,192 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/fahedafzaal/go-integration/pkg/blockchain"
+	"github.com/fahedafzaal/go-integration/pkg/database"
+)
+
+// Indexer follows EthJobEscrow events and drives applications.payment_status from them.
+// Progress is stored as a block cursor in Postgres so a restarted indexer resumes from
+// the last processed block and never misses an event. Events younger than the client's
+// confirmation depth only move an application to pending_confirmations; they are
+// finalized once buried deep enough, or rolled back if their block is reorged away.
+type Indexer struct {
+	client       *blockchain.Client
+	db           Store
+	name         string
+	startBlock   uint64
+	batchSize    uint64
+	pollInterval time.Duration
+}
+
+// Store is the database the indexer keeps its cursor and applies events through,
+// implemented by *database.DB
+type Store interface {
+	GetIndexerCursor(ctx context.Context, name string) (uint64, bool, error)
+	RenameIndexer(ctx context.Context, from, to string) error
+	ApplyChainEvents(ctx context.Context, name string, events []database.ChainEvent, cursorBlock, finalizedBlock uint64) error
+	ListPendingBlocks(ctx context.Context, name string) ([]database.PendingBlock, error)
+	FinalizeChainEvents(ctx context.Context, name string, finalizedBlock uint64) error
+	RollbackChainEvents(ctx context.Context, name string, fromBlock uint64) error
+}
+
+// Config holds indexer settings
+type Config struct {
+	StartBlock   uint64        // First block to scan when no cursor is stored
+	BatchSize    uint64        // Optional, defaults to 2000 blocks per log query
+	PollInterval time.Duration // Optional, defaults to 15 seconds
+}
+
+// New creates an indexer for the client's escrow contract
+func New(client *blockchain.Client, db Store, cfg Config) *Indexer {
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 2000
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 15 * time.Second
+	}
+
+	return &Indexer{
+		client: client,
+		db:     db,
+		// The cursor is keyed by chain and contract so pointing the gateway at a new
+		// deployment starts a fresh scan instead of reusing a stale cursor
+		name:         fmt.Sprintf("escrow:%d:%s", client.ChainID(), strings.ToLower(client.ContractAddress().Hex())),
+		startBlock:   cfg.StartBlock,
+		batchSize:    cfg.BatchSize,
+		pollInterval: cfg.PollInterval,
+	}
+}
+
+// AdoptLegacyCursor takes over the cursor and unconfirmed events of an indexer for the
+// same contract from before cursors were keyed by chain. Only the indexer of the chain
+// the gateway served then may adopt them.
+func (i *Indexer) AdoptLegacyCursor(ctx context.Context) error {
+	legacy := "escrow:" + strings.ToLower(i.client.ContractAddress().Hex())
+	return i.db.RenameIndexer(ctx, legacy, i.name)
+}
+
+// Run polls for new events until the context is cancelled
+func (i *Indexer) Run(ctx context.Context) {
+	log.Printf("Indexer: starting %s (poll interval %v, batch size %d)", i.name, i.pollInterval, i.batchSize)
+
+	ticker := time.NewTicker(i.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := i.Poll(ctx); err != nil {
+			log.Printf("Indexer: poll failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Printf("Indexer: stopping %s", i.name)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll processes every block between the stored cursor and the current head
+func (i *Indexer) Poll(ctx context.Context) error {
+	head, err := i.client.LatestBlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get latest block: %w", err)
+	}
+
+	// Blocks at or below finalized have reached the confirmation depth
+	var finalized uint64
+	if depth := i.client.ConfirmationDepth(); head+1 >= depth {
+		finalized = head + 1 - depth
+	}
+
+	if err := i.detectReorg(ctx); err != nil {
+		return err
+	}
+
+	cursor, found, err := i.db.GetIndexerCursor(ctx, i.name)
+	if err != nil {
+		return err
+	}
+
+	from := i.startBlock
+	if found {
+		from = cursor + 1
+	}
+
+	for from <= head {
+		to := from + i.batchSize - 1
+		if to > head {
+			to = head
+		}
+
+		events, err := i.client.FilterEscrowEvents(ctx, from, to)
+		if err != nil {
+			return err
+		}
+
+		records := make([]database.ChainEvent, 0, len(events))
+		for _, event := range events {
+			records = append(records, database.ChainEvent{
+				Name:        event.Name,
+				ChainID:     i.client.ChainID(),
+				EscrowJobID: int32(event.JobID),
+				TxHash:      event.TxHash.Hex(),
+				BlockNumber: event.BlockNumber,
+				BlockHash:   event.BlockHash.Hex(),
+				LogIndex:    event.LogIndex,
+			})
+		}
+
+		// The cursor never passes the finalized block, so blocks that could still be
+		// reorged are scanned again on the next poll
+		next := to
+		if next > finalized {
+			next = finalized
+		}
+		if next+1 < from {
+			next = from - 1
+		}
+
+		if err := i.db.ApplyChainEvents(ctx, i.name, records, next, finalized); err != nil {
+			return fmt.Errorf("failed to apply events for blocks %d-%d: %w", from, to, err)
+		}
+
+		if len(records) > 0 {
+			log.Printf("Indexer: processed %d events in blocks %d-%d", len(records), from, to)
+		}
+
+		from = to + 1
+	}
+
+	return i.db.FinalizeChainEvents(ctx, i.name, finalized)
+}
+
+// detectReorg compares blocks holding pending events with the canonical chain and rolls
+// back everything from the first block whose hash no longer matches
+func (i *Indexer) detectReorg(ctx context.Context) error {
+	blocks, err := i.db.ListPendingBlocks(ctx, i.name)
+	if err != nil {
+		return err
+	}
+
+	for _, block := range blocks {
+		canonical, err := i.client.BlockHashAt(ctx, block.Number)
+		if err != nil {
+			return err
+		}
+
+		if !strings.EqualFold(canonical.Hex(), block.Hash) {
+			log.Printf("Indexer: reorg detected at block %d (had %s, canonical %s)", block.Number, block.Hash, canonical.Hex())
+			return i.db.RollbackChainEvents(ctx, i.name, block.Number)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,350 @@
+package indexer
+
+import (
+	"context"
+	"math/big"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+
+	"github.com/fahedafzaal/go-integration/contracts"
+	"github.com/fahedafzaal/go-integration/internal/config"
+	"github.com/fahedafzaal/go-integration/internal/escrowsim"
+	"github.com/fahedafzaal/go-integration/pkg/blockchain"
+	"github.com/fahedafzaal/go-integration/pkg/database"
+)
+
+// fakeStore keeps the cursor and events in memory. Like *database.DB it stages events
+// above the finalized block, skips staged ones, and rewinds the cursor on rollback.
+type fakeStore struct {
+	mu        sync.Mutex
+	cursors   map[string]uint64
+	pending   []database.ChainEvent
+	applied   []database.ChainEvent
+	rollbacks []uint64
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{cursors: make(map[string]uint64)}
+}
+
+func (s *fakeStore) GetIndexerCursor(ctx context.Context, name string) (uint64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cursor, ok := s.cursors[name]
+	return cursor, ok, nil
+}
+
+func (s *fakeStore) RenameIndexer(ctx context.Context, from, to string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cursor, ok := s.cursors[from]; ok {
+		if _, taken := s.cursors[to]; !taken {
+			s.cursors[to] = cursor
+			delete(s.cursors, from)
+		}
+	}
+	return nil
+}
+
+func (s *fakeStore) ApplyChainEvents(ctx context.Context, name string, events []database.ChainEvent, cursorBlock, finalizedBlock uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, event := range events {
+		staged := slices.ContainsFunc(s.pending, func(p database.ChainEvent) bool {
+			return p.TxHash == event.TxHash && p.LogIndex == event.LogIndex
+		})
+		switch {
+		case staged:
+		case event.BlockNumber <= finalizedBlock:
+			s.applied = append(s.applied, event)
+		default:
+			s.pending = append(s.pending, event)
+		}
+	}
+	if cursor, ok := s.cursors[name]; !ok || cursorBlock > cursor {
+		s.cursors[name] = cursorBlock
+	}
+	return nil
+}
+
+func (s *fakeStore) ListPendingBlocks(ctx context.Context, name string) ([]database.PendingBlock, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var blocks []database.PendingBlock
+	for _, event := range s.pending {
+		block := database.PendingBlock{Number: event.BlockNumber, Hash: event.BlockHash}
+		if !slices.Contains(blocks, block) {
+			blocks = append(blocks, block)
+		}
+	}
+	slices.SortFunc(blocks, func(a, b database.PendingBlock) int { return int(a.Number) - int(b.Number) })
+	return blocks, nil
+}
+
+func (s *fakeStore) FinalizeChainEvents(ctx context.Context, name string, finalizedBlock uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var remaining []database.ChainEvent
+	for _, event := range s.pending {
+		if event.BlockNumber <= finalizedBlock {
+			s.applied = append(s.applied, event)
+		} else {
+			remaining = append(remaining, event)
+		}
+	}
+	s.pending = remaining
+	return nil
+}
+
+func (s *fakeStore) RollbackChainEvents(ctx context.Context, name string, fromBlock uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rollbacks = append(s.rollbacks, fromBlock)
+	s.pending = slices.DeleteFunc(s.pending, func(event database.ChainEvent) bool { return event.BlockNumber >= fromBlock })
+	if cursor, ok := s.cursors[name]; ok && fromBlock > 0 && cursor > fromBlock-1 {
+		s.cursors[name] = fromBlock - 1
+	}
+	return nil
+}
+
+func (s *fakeStore) snapshot() (pending, applied []database.ChainEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.pending), slices.Clone(s.applied)
+}
+
+// indexerTest emits escrow events from a stand-in contract on a simulated chain whose
+// blocks are only mined on Commit
+type indexerTest struct {
+	t         *testing.T
+	ctx       context.Context
+	backend   *simulated.Backend
+	auth      *bind.TransactOpts
+	emitter   *escrowsim.Emitter
+	escrowABI *abi.ABI
+	client    *blockchain.Client
+	store     *fakeStore
+}
+
+func newIndexerTest(t *testing.T, depth uint64) *indexerTest {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	backend := escrowsim.NewBackend(crypto.PubkeyToAddress(key.PublicKey))
+	t.Cleanup(func() { backend.Close() })
+
+	eth, ok := backend.Client().(blockchain.EthBackend)
+	if !ok {
+		t.Fatal("simulated client does not implement EthBackend")
+	}
+	auth, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+	if err != nil {
+		t.Fatalf("NewKeyedTransactorWithChainID: %v", err)
+	}
+	emitter, _, err := escrowsim.DeployEmitter(auth, eth)
+	if err != nil {
+		t.Fatalf("DeployEmitter: %v", err)
+	}
+	escrowsim.Commit(backend)
+
+	escrowABI, err := contracts.EthJobEscrowMetaData.GetAbi()
+	if err != nil {
+		t.Fatalf("GetAbi: %v", err)
+	}
+	client, err := blockchain.NewClientWithBackend(&config.Config{
+		NetworkID:         1337,
+		ContractAddress:   emitter.Address.Hex(),
+		GasLimit:          300000,
+		ConfirmationDepth: depth,
+	}, eth, blockchain.NewFakeSigner())
+	if err != nil {
+		t.Fatalf("NewClientWithBackend: %v", err)
+	}
+
+	return &indexerTest{
+		t:         t,
+		ctx:       context.Background(),
+		backend:   backend,
+		auth:      auth,
+		emitter:   emitter,
+		escrowABI: escrowABI,
+		client:    client,
+		store:     newFakeStore(),
+	}
+}
+
+// emit sends an escrow event for jobID with opts and returns the transaction unmined
+func (x *indexerTest) emit(opts *bind.TransactOpts, event string, jobID int64) *types.Transaction {
+	x.t.Helper()
+	tx, err := x.emitter.EmitEvent(opts, x.escrowABI, event, eventArgs(event, jobID)...)
+	if err != nil {
+		x.t.Fatalf("EmitEvent %s: %v", event, err)
+	}
+	return tx
+}
+
+// eventArgs returns arguments for an escrow event of jobID in the event's input order
+func eventArgs(event string, jobID int64) []interface{} {
+	client, freelancer := common.HexToAddress("0x01"), common.HexToAddress("0x02")
+	switch event {
+	case "JobPosted":
+		return []interface{}{big.NewInt(jobID), client, freelancer, big.NewInt(100_00000000), big.NewInt(5e16)}
+	case "PaymentReleased":
+		return []interface{}{big.NewInt(jobID), freelancer, big.NewInt(5e16)}
+	case "JobCancelled":
+		return []interface{}{big.NewInt(jobID), client, big.NewInt(5e16)}
+	}
+	return []interface{}{big.NewInt(jobID)}
+}
+
+// mine emits an escrow event for jobID and mines it, returning the block it landed in
+func (x *indexerTest) mine(event string, jobID int64) uint64 {
+	x.t.Helper()
+	tx := x.emit(x.auth, event, jobID)
+	escrowsim.Commit(x.backend)
+	receipt, err := x.backend.Client().TransactionReceipt(x.ctx, tx.Hash())
+	if err != nil {
+		x.t.Fatalf("TransactionReceipt: %v", err)
+	}
+	return receipt.BlockNumber.Uint64()
+}
+
+func (x *indexerTest) poll(i *Indexer) {
+	x.t.Helper()
+	if err := i.Poll(x.ctx); err != nil {
+		x.t.Fatalf("Poll: %v", err)
+	}
+}
+
+func eventNames(events []database.ChainEvent) []string {
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i] = event.Name
+	}
+	return names
+}
+
+func TestIndexerResumesFromCursor(t *testing.T) {
+	x := newIndexerTest(t, 1)
+	x.mine("JobPosted", 7)
+
+	x.poll(New(x.client, x.store, Config{}))
+	if _, applied := x.store.snapshot(); !slices.Equal(eventNames(applied), []string{"JobPosted"}) {
+		t.Fatalf("first run applied %v, want JobPosted", eventNames(applied))
+	}
+
+	// A restarted indexer picks up after the stored cursor: JobPosted is not applied
+	// again, even with a start block before it
+	x.mine("JobCompleted", 7)
+	x.mine("PaymentReleased", 7)
+	restarted := New(x.client, x.store, Config{StartBlock: 0, BatchSize: 1})
+	x.poll(restarted)
+
+	_, applied := x.store.snapshot()
+	if want := []string{"JobPosted", "JobCompleted", "PaymentReleased"}; !slices.Equal(eventNames(applied), want) {
+		t.Errorf("after restart applied %v, want %v", eventNames(applied), want)
+	}
+	for _, event := range applied {
+		if event.EscrowJobID != 7 || event.ChainID != 1337 {
+			t.Errorf("%s recorded for job %d on chain %d, want job 7 on chain 1337", event.Name, event.EscrowJobID, event.ChainID)
+		}
+	}
+
+	head, err := x.client.LatestBlockNumber(x.ctx)
+	if err != nil {
+		t.Fatalf("LatestBlockNumber: %v", err)
+	}
+	if cursor, found, _ := x.store.GetIndexerCursor(x.ctx, restarted.name); !found || cursor != head {
+		t.Errorf("cursor = %d (found %v), want the head %d", cursor, found, head)
+	}
+}
+
+func TestIndexerWaitsForConfirmationDepth(t *testing.T) {
+	x := newIndexerTest(t, 3)
+	i := New(x.client, x.store, Config{})
+	block := x.mine("JobPosted", 7)
+
+	// One and two confirmations: staged, and staged only once however often it is scanned
+	for confirmations := 1; confirmations <= 2; confirmations++ {
+		x.poll(i)
+		pending, applied := x.store.snapshot()
+		if len(pending) != 1 || len(applied) != 0 {
+			t.Fatalf("at %d confirmations: %d pending, %d applied; want 1 pending", confirmations, len(pending), len(applied))
+		}
+		if cursor, _, _ := x.store.GetIndexerCursor(x.ctx, i.name); cursor >= block {
+			t.Errorf("at %d confirmations the cursor passed block %d: %d", confirmations, block, cursor)
+		}
+		escrowsim.Commit(x.backend)
+	}
+
+	// Three confirmations: finalized
+	x.poll(i)
+	pending, applied := x.store.snapshot()
+	if len(pending) != 0 || len(applied) != 1 || applied[0].BlockNumber != block {
+		t.Errorf("at the confirmation depth: pending %v, applied %v; want JobPosted of block %d applied", pending, applied, block)
+	}
+}
+
+func TestIndexerRollsBackReorgedEvents(t *testing.T) {
+	x := newIndexerTest(t, 3)
+	i := New(x.client, x.store, Config{})
+
+	parent, err := x.backend.Client().HeaderByNumber(x.ctx, nil)
+	if err != nil {
+		t.Fatalf("HeaderByNumber: %v", err)
+	}
+	x.auth.GasTipCap, x.auth.GasFeeCap = big.NewInt(1e9), big.NewInt(100e9)
+	reorged := x.emit(x.auth, "JobPosted", 7)
+	escrowsim.Commit(x.backend)
+
+	x.poll(i)
+	pending, _ := x.store.snapshot()
+	if len(pending) != 1 || pending[0].EscrowJobID != 7 {
+		t.Fatalf("before the reorg: pending %v, want JobPosted for job 7", pending)
+	}
+	reorgedBlock := pending[0]
+
+	// A side chain from the parent replaces the block; the same nonce now posts job 8.
+	// The higher fees win over the reorged transaction if the pool takes it back.
+	if err := x.backend.Fork(parent.Hash()); err != nil {
+		t.Fatalf("Fork: %v", err)
+	}
+	replacement := *x.auth
+	replacement.Nonce = new(big.Int).SetUint64(reorged.Nonce())
+	replacement.GasTipCap, replacement.GasFeeCap = big.NewInt(2e9), big.NewInt(200e9)
+	// The pool refuses the nonce until it has moved to the side chain
+	for deadline := time.Now().Add(30 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		_, err := x.emitter.EmitEvent(&replacement, x.escrowABI, "JobPosted", eventArgs("JobPosted", 8)...)
+		if err == nil {
+			break
+		}
+		if !strings.Contains(err.Error(), "nonce too low") || time.Now().After(deadline) {
+			t.Fatalf("EmitEvent on the side chain: %v", err)
+		}
+	}
+	escrowsim.Commit(x.backend)
+
+	x.poll(i)
+	if len(x.store.rollbacks) != 1 || x.store.rollbacks[0] != reorgedBlock.BlockNumber {
+		t.Fatalf("rollbacks = %v, want one from block %d", x.store.rollbacks, reorgedBlock.BlockNumber)
+	}
+	pending, applied := x.store.snapshot()
+	if len(applied) != 0 {
+		t.Errorf("reorged events were applied: %v", applied)
+	}
+	if len(pending) != 1 || pending[0].EscrowJobID != 8 || pending[0].BlockHash == reorgedBlock.BlockHash {
+		t.Errorf("after the reorg: pending %v, want only job 8 in the replacement block", pending)
+	}
+}
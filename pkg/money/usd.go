@@ -0,0 +1,216 @@
+// Package money holds exact decimal amounts. USD amounts are fixed point with 8 decimals,
+// the precision the escrow contract and Chainlink USD price feeds use, so no amount is
+// ever rounded through a float on its way to the chain.
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Decimals is the number of decimals a USD amount carries
+const Decimals = 8
+
+// CentDecimals is the number of decimals agreed amounts are limited to
+const CentDecimals = 2
+
+var (
+	// ErrInvalidUSD is returned for text that is not a plain decimal number
+	ErrInvalidUSD = errors.New("invalid USD amount")
+	// ErrNegativeUSD is returned for a negative USD amount
+	ErrNegativeUSD = errors.New("USD amount is negative")
+	// ErrUSDPrecision is returned for an amount with more decimals than allowed
+	ErrUSDPrecision = errors.New("USD amount has too many decimals")
+	// ErrUSDRange is returned for an amount too large to represent
+	ErrUSDRange = errors.New("USD amount is out of range")
+)
+
+// scale is 10^Decimals, one dollar in E8 units
+const scale = 100_000_000
+
+// USD is an exact, non-negative amount of US dollars in units of 10^-8 dollars. The zero
+// value is $0. USD values are comparable with ==.
+type USD struct {
+	e8 int64
+}
+
+// Dollars returns a whole-dollar amount
+func Dollars(dollars int64) (USD, error) {
+	if dollars < 0 {
+		return USD{}, ErrNegativeUSD
+	}
+	if dollars > math.MaxInt64/scale {
+		return USD{}, ErrUSDRange
+	}
+	return USD{e8: dollars * scale}, nil
+}
+
+// ParseUSD parses a plain decimal such as "250", "0.29" or "1234.56789" with at most
+// Decimals significant decimals. Signs, exponents, NaN and infinities are rejected.
+func ParseUSD(s string) (USD, error) {
+	return parse(s, Decimals)
+}
+
+// ParseUSDCents is ParseUSD limited to whole cents, as agreed amounts are
+func ParseUSDCents(s string) (USD, error) {
+	return parse(s, CentDecimals)
+}
+
+// MustParseUSD is ParseUSD for amounts known to be valid, such as constants. It panics
+// on an invalid amount.
+func MustParseUSD(s string) USD {
+	u, err := ParseUSD(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+func parse(s string, maxDecimals int) (USD, error) {
+	if strings.HasPrefix(s, "-") {
+		return USD{}, fmt.Errorf("%w: %q", ErrNegativeUSD, s)
+	}
+
+	whole, frac, hasPoint := strings.Cut(s, ".")
+	if whole == "" || (hasPoint && frac == "") || !digits(whole) || !digits(frac) {
+		return USD{}, fmt.Errorf("%w: %q", ErrInvalidUSD, s)
+	}
+
+	// Trailing zeros do not add precision
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > maxDecimals {
+		return USD{}, fmt.Errorf("%w: %q has more than %d", ErrUSDPrecision, s, maxDecimals)
+	}
+
+	var e8 int64
+	for _, c := range whole {
+		if e8 > (math.MaxInt64/scale-int64(c-'0'))/10 {
+			return USD{}, fmt.Errorf("%w: %q", ErrUSDRange, s)
+		}
+		e8 = e8*10 + int64(c-'0')
+	}
+	e8 *= scale
+
+	var fracE8 int64
+	unit := int64(scale / 10)
+	for _, c := range frac {
+		fracE8 += int64(c-'0') * unit
+		unit /= 10
+	}
+	if e8 > math.MaxInt64-fracE8 {
+		return USD{}, fmt.Errorf("%w: %q", ErrUSDRange, s)
+	}
+	return USD{e8: e8 + fracE8}, nil
+}
+
+// digits reports whether s consists of ASCII digits only
+func digits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// USDFromE8 converts an 8-decimal amount, as the escrow contract stores it
+func USDFromE8(e8 *big.Int) (USD, error) {
+	if e8 == nil {
+		return USD{}, fmt.Errorf("%w: nil", ErrInvalidUSD)
+	}
+	if e8.Sign() < 0 {
+		return USD{}, fmt.Errorf("%w: %s E8", ErrNegativeUSD, e8)
+	}
+	if !e8.IsInt64() {
+		return USD{}, fmt.Errorf("%w: %s E8", ErrUSDRange, e8)
+	}
+	return USD{e8: e8.Int64()}, nil
+}
+
+// E8 returns the amount in 8-decimal units, as the escrow contract takes it
+func (u USD) E8() *big.Int {
+	return big.NewInt(u.e8)
+}
+
+// IsZero reports whether the amount is $0
+func (u USD) IsZero() bool {
+	return u.e8 == 0
+}
+
+// Cmp compares u and v and returns -1, 0 or +1
+func (u USD) Cmp(v USD) int {
+	switch {
+	case u.e8 < v.e8:
+		return -1
+	case u.e8 > v.e8:
+		return 1
+	}
+	return 0
+}
+
+// String formats the amount with at least two decimals and no trailing zeros beyond
+// them, e.g. "250.00", "0.29" or "0.00000001"
+func (u USD) String() string {
+	frac := fmt.Sprintf("%08d", u.e8%scale)
+	frac = strings.TrimRight(frac, "0")
+	for len(frac) < CentDecimals {
+		frac += "0"
+	}
+	return fmt.Sprintf("%d.%s", u.e8/scale, frac)
+}
+
+// MarshalJSON encodes the amount as a JSON string, since not every JSON decoder keeps
+// numbers exact
+func (u USD) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + u.String() + `"`), nil
+}
+
+// UnmarshalJSON accepts a JSON string or a plain JSON number; null leaves u unchanged
+func (u *USD) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1]
+	}
+	parsed, err := ParseUSD(s)
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
+
+// Scan reads a NUMERIC or INTEGER column of dollars. Scan into a *USD pointer for a
+// nullable column.
+func (u *USD) Scan(src interface{}) error {
+	var parsed USD
+	var err error
+	switch v := src.(type) {
+	case string:
+		parsed, err = ParseUSD(v)
+	case []byte:
+		parsed, err = ParseUSD(string(v))
+	case int64:
+		parsed, err = Dollars(v)
+	case nil:
+		return fmt.Errorf("cannot scan NULL into USD")
+	default:
+		return fmt.Errorf("cannot scan %T into USD", src)
+	}
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
+
+// Value writes the amount as decimal text for a NUMERIC column
+func (u USD) Value() (driver.Value, error) {
+	return u.String(), nil
+}
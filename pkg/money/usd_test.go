@@ -0,0 +1,168 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestParseUSD(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantE8  int64
+		wantErr error
+	}{
+		{"250", 250_00000000, nil},
+		{"0.29", 29000000, nil},
+		{"0.1", 10000000, nil},
+		{"1234.56789012", 1234_56789012, nil},
+		{"0.00000001", 1, nil},
+		{"1.500000000000", 1_50000000, nil}, // trailing zeros add no precision
+		{"007", 7_00000000, nil},
+		{"92233720368.54775807", 9223372036854775807, nil},
+		{"92233720368.54775808", 0, ErrUSDRange},
+		{"100000000000", 0, ErrUSDRange},
+		{"0.000000001", 0, ErrUSDPrecision},
+		{"-1", 0, ErrNegativeUSD},
+		{"-0", 0, ErrNegativeUSD},
+		{"", 0, ErrInvalidUSD},
+		{".5", 0, ErrInvalidUSD},
+		{"5.", 0, ErrInvalidUSD},
+		{"+5", 0, ErrInvalidUSD},
+		{"1e3", 0, ErrInvalidUSD},
+		{"NaN", 0, ErrInvalidUSD},
+		{"Inf", 0, ErrInvalidUSD},
+		{"1,000", 0, ErrInvalidUSD},
+		{" 1", 0, ErrInvalidUSD},
+		{"1.2.3", 0, ErrInvalidUSD},
+	}
+	for _, tt := range tests {
+		got, err := ParseUSD(tt.in)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("ParseUSD(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got.E8().Int64() != tt.wantE8 {
+			t.Errorf("ParseUSD(%q) = %s E8, want %d", tt.in, got.E8(), tt.wantE8)
+		}
+	}
+}
+
+func TestParseUSDCents(t *testing.T) {
+	if _, err := ParseUSDCents("19.99"); err != nil {
+		t.Errorf("ParseUSDCents(19.99): %v", err)
+	}
+	if _, err := ParseUSDCents("19.990"); err != nil {
+		t.Errorf("ParseUSDCents(19.990): %v", err)
+	}
+	if _, err := ParseUSDCents("19.999"); !errors.Is(err, ErrUSDPrecision) {
+		t.Errorf("ParseUSDCents(19.999) error = %v, want ErrUSDPrecision", err)
+	}
+}
+
+func TestUSDString(t *testing.T) {
+	tests := map[string]string{
+		"250":        "250.00",
+		"0.29":       "0.29",
+		"1.5":        "1.50",
+		"0.00000001": "0.00000001",
+		"0":          "0.00",
+	}
+	for in, want := range tests {
+		if got := MustParseUSD(in).String(); got != want {
+			t.Errorf("ParseUSD(%q).String() = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestUSDJSON(t *testing.T) {
+	var v struct {
+		Amount USD `json:"amount"`
+	}
+	for _, in := range []string{`{"amount":"0.29"}`, `{"amount":0.29}`} {
+		if err := json.Unmarshal([]byte(in), &v); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", in, err)
+		}
+		if v.Amount != MustParseUSD("0.29") {
+			t.Errorf("Unmarshal(%s) = %s, want 0.29", in, v.Amount)
+		}
+	}
+	for _, in := range []string{`{"amount":-1}`, `{"amount":"1e2"}`, `{"amount":true}`} {
+		if err := json.Unmarshal([]byte(in), &v); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded, want an error", in)
+		}
+	}
+
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"amount":"0.29"}` {
+		t.Errorf("Marshal = %s", out)
+	}
+}
+
+func TestUSDScan(t *testing.T) {
+	var u USD
+	for src, want := range map[interface{}]string{"19.99": "19.99", int64(250): "250"} {
+		if err := u.Scan(src); err != nil || u != MustParseUSD(want) {
+			t.Errorf("Scan(%v) = %s, %v; want %s", src, u, err, want)
+		}
+	}
+	if err := u.Scan(nil); err == nil {
+		t.Error("Scan(nil) succeeded, want an error")
+	}
+	if err := u.Scan(1.5); err == nil {
+		t.Error("Scan(float64) succeeded, want an error")
+	}
+}
+
+// FuzzUSDRoundTrip checks that every accepted amount is exactly the decimal it was parsed
+// from and survives formatting, JSON and E8 conversion unchanged
+func FuzzUSDRoundTrip(f *testing.F) {
+	for _, seed := range []string{"0", "0.29", "250", "1234.56789012", "92233720368.54775807", "1.10", "-1", "1e2", ".5"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		u, err := ParseUSD(s)
+		if err != nil {
+			return
+		}
+
+		// Exact: E8 / 10^8 is the decimal s
+		want, ok := new(big.Rat).SetString(s)
+		if !ok {
+			t.Fatalf("ParseUSD accepted %q, which is not a decimal", s)
+		}
+		got := new(big.Rat).SetFrac(u.E8(), big.NewInt(scale))
+		if got.Cmp(want) != 0 {
+			t.Fatalf("ParseUSD(%q) = %s, want %s", s, got.FloatString(Decimals), want.FloatString(Decimals))
+		}
+
+		again, err := ParseUSD(u.String())
+		if err != nil || again != u {
+			t.Fatalf("ParseUSD(%q) = %v, %v; want %s", u.String(), again, err, u)
+		}
+
+		data, err := json.Marshal(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded USD
+		if err := json.Unmarshal(data, &decoded); err != nil || decoded != u {
+			t.Fatalf("JSON round trip of %s via %s = %s, %v", u, data, decoded, err)
+		}
+
+		fromE8, err := USDFromE8(u.E8())
+		if err != nil || fromE8 != u {
+			t.Fatalf("USDFromE8(%s) = %s, %v; want %s", u.E8(), fromE8, err, u)
+		}
+
+		var scanned USD
+		value, _ := u.Value()
+		if err := scanned.Scan(value); err != nil || scanned != u {
+			t.Fatalf("Scan(Value()) of %s = %s, %v", u, scanned, err)
+		}
+	})
+}
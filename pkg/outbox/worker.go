@@ -0,0 +1,454 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/fahedafzaal/go-integration/pkg/blockchain"
+	"github.com/fahedafzaal/go-integration/pkg/database"
+)
+
+// Worker drains the tx_intents outbox. Each poll advances every claimed intent by one
+// step - sign and broadcast, check for a receipt, or check confirmation depth - so no
+// intent blocks another and every step can be repeated safely after a crash:
+//
+//	queued    -> the transaction is signed and its hash stored before it is broadcast
+//	submitted -> rebroadcast if the node lost it, speed up if it is stuck, mined once a version lands
+//	mined     -> confirmed at the confirmation depth, back to submitted if reorged
+//
+// A submitted transaction that cannot be rebroadcast within the attempt limit has its
+// nonce cancelled with a zero-value self-send; the intent fails once the cancel, or any
+// other transaction, spends the nonce. Client deposits arrive already signed and
+// submitted; they are rebroadcast but never replaced or cancelled, and are verified
+// against the application's terms before they are confirmed.
+//
+// A reverted or never-sendable intent fails, which restores the application's payment status.
+type Worker struct {
+	client       *blockchain.Client
+	service      *blockchain.PaymentGatewayService // verifies client deposits
+	db           Store
+	batchSize    int
+	pollInterval time.Duration
+	lease        time.Duration
+	maxAttempts  int
+}
+
+// Store is the database the worker drives intents through, implemented by *database.DB
+type Store interface {
+	ClaimTxIntents(ctx context.Context, chainID int64, limit int, lease time.Duration) ([]database.TxIntent, error)
+	UnlockTxIntent(ctx context.Context, id int64) error
+	RecordTxIntentError(ctx context.Context, id int64, message string) (int, error)
+	MarkTxIntentSubmitted(ctx context.Context, id int64, nonce uint64, txHash string, rawTx []byte, amounts database.Amounts) error
+	MarkTxIntentCancelled(ctx context.Context, id int64, txHash string) error
+	MarkTxIntentMined(ctx context.Context, id int64, txHash string, blockNumber uint64, blockHash string) error
+	MarkTxIntentReorged(ctx context.Context, id int64) error
+	ConfirmTxIntent(ctx context.Context, id int64) error
+	FailTxIntent(ctx context.Context, id int64, reason string) error
+	RevertTxIntent(ctx context.Context, id int64, revertReason string) error
+	FailReplacedTxIntent(ctx context.Context, id int64, reason string) error
+	RejectTxIntent(ctx context.Context, id int64, reason string) error
+	GetApplicationPaymentDetails(ctx context.Context, applicationID int32) (*database.ApplicationPaymentDetails, error)
+	GetPriceQuoteByTx(ctx context.Context, txHash string) (*database.PriceQuote, error)
+}
+
+// Config holds outbox worker settings
+type Config struct {
+	BatchSize    int           // Optional, defaults to 20 intents per poll
+	PollInterval time.Duration // Optional, defaults to 5 seconds
+	MaxAttempts  int           // Optional, defaults to 5 failed sends before an intent fails, or a broadcast one is cancelled
+}
+
+// New creates an outbox worker for the intents on the client's chain
+func New(client *blockchain.Client, db Store, cfg Config) *Worker {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 20
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 5 * time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+
+	return &Worker{
+		client:       client,
+		service:      blockchain.NewPaymentGatewayServiceWithClient(client),
+		db:           db,
+		batchSize:    cfg.BatchSize,
+		pollInterval: cfg.PollInterval,
+		lease:        2 * time.Minute,
+		maxAttempts:  cfg.MaxAttempts,
+	}
+}
+
+// Run processes the outbox until the context is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	log.Printf("Outbox: starting worker for chain %d (poll interval %v, batch size %d)", w.client.ChainID(), w.pollInterval, w.batchSize)
+
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Poll(ctx); err != nil {
+			log.Printf("Outbox: poll failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Printf("Outbox: stopping worker for chain %d", w.client.ChainID())
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll advances every unfinished intent by one step
+func (w *Worker) Poll(ctx context.Context) error {
+	intents, err := w.db.ClaimTxIntents(ctx, w.client.ChainID(), w.batchSize, w.lease)
+	if err != nil {
+		return err
+	}
+
+	for i := range intents {
+		intent := &intents[i]
+
+		if err := w.process(ctx, intent); err != nil {
+			log.Printf("Outbox: %s intent %d for application %d: %v", intent.Kind, intent.ID, intent.ApplicationID, err)
+			if _, err := w.db.RecordTxIntentError(ctx, intent.ID, err.Error()); err != nil {
+				log.Printf("Outbox: %v", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (w *Worker) process(ctx context.Context, intent *database.TxIntent) error {
+	switch intent.Status {
+	case database.IntentQueued:
+		return w.submit(ctx, intent)
+	case database.IntentSubmitted:
+		return w.track(ctx, intent)
+	case database.IntentMined:
+		return w.confirm(ctx, intent)
+	default:
+		return w.db.UnlockTxIntent(ctx, intent.ID)
+	}
+}
+
+// submit signs the intent's transaction, stores it, then broadcasts it
+func (w *Worker) submit(ctx context.Context, intent *database.TxIntent) error {
+	tx, err := w.prepare(ctx, intent)
+	var contractErr *blockchain.ContractError
+	if errors.As(err, &contractErr) {
+		// The contract rejected the call during gas estimation; retrying cannot help
+		return w.db.RevertTxIntent(ctx, intent.ID, contractErr.Name)
+	}
+	if err != nil {
+		return w.retryOrFail(ctx, intent, err)
+	}
+
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		w.client.DiscardTransaction(ctx, tx)
+		return fmt.Errorf("failed to encode transaction: %w", err)
+	}
+
+	// Persist before broadcasting: a crash after this point leaves a submitted intent
+	// whose transaction the next poll finds on chain or rebroadcasts
+	if err := w.db.MarkTxIntentSubmitted(ctx, intent.ID, tx.Nonce(), tx.Hash().Hex(), raw, w.amounts(ctx, intent, tx)); err != nil {
+		w.client.DiscardTransaction(ctx, tx)
+		return err
+	}
+
+	if err := w.client.BroadcastTransaction(ctx, tx); err != nil {
+		// Left submitted; track rebroadcasts it on the next poll
+		return err
+	}
+
+	log.Printf("Outbox: %s intent %d for application %d broadcast as %s", intent.Kind, intent.ID, intent.ApplicationID, tx.Hash().Hex())
+	return nil
+}
+
+func (w *Worker) prepare(ctx context.Context, intent *database.TxIntent) (*types.Transaction, error) {
+	jobID := uint64(intent.ApplicationID) // application.id is used as escrow job_id
+
+	switch intent.Kind {
+	case database.IntentDeposit:
+		return w.prepareDeposit(ctx, intent)
+	case database.IntentRelease:
+		return w.client.PrepareMarkJobCompleted(ctx, jobID)
+	case database.IntentRefund:
+		return w.client.PrepareCancelJob(ctx, jobID)
+	default:
+		return nil, fmt.Errorf("unknown intent kind %q", intent.Kind)
+	}
+}
+
+// prepareDeposit funds the escrow from the gateway wallet using the application's agreed terms
+func (w *Worker) prepareDeposit(ctx context.Context, intent *database.TxIntent) (*types.Transaction, error) {
+	details, err := w.db.GetApplicationPaymentDetails(ctx, intent.ApplicationID)
+	if err != nil {
+		return nil, err
+	}
+	if details.ApplicantWalletAddress == nil || details.PosterWalletAddress == nil || details.AgreedUSDAmount == nil {
+		return nil, fmt.Errorf("application %d is missing wallet addresses or agreed amount", intent.ApplicationID)
+	}
+
+	return w.client.PreparePostJob(ctx,
+		uint64(intent.ApplicationID),
+		common.HexToAddress(*details.ApplicantWalletAddress),
+		*details.AgreedUSDAmount,
+		common.HexToAddress(*details.PosterWalletAddress),
+	)
+}
+
+// amounts returns the figures the payment ledger records for a newly signed transaction.
+// A deposit also records the feed price, read just after PreparePostJob converted the
+// agreed amount at it.
+func (w *Worker) amounts(ctx context.Context, intent *database.TxIntent, tx *types.Transaction) database.Amounts {
+	amounts := database.Amounts{Wei: tx.Value()}
+	if intent.Kind == database.IntentDeposit {
+		price, err := w.client.GetETHUSDPrice(ctx)
+		if err != nil {
+			log.Printf("Outbox: could not read ETH/USD price for the ledger: %v", err)
+		} else {
+			amounts.EthUSDPriceE8 = price
+		}
+	}
+	return amounts
+}
+
+// track checks whether any version of a submitted transaction has been mined
+func (w *Worker) track(ctx context.Context, intent *database.TxIntent) error {
+	if intent.Nonce == nil || len(intent.RawTx) == 0 {
+		return fmt.Errorf("submitted intent has no signed transaction")
+	}
+
+	var latest types.Transaction
+	if err := latest.UnmarshalBinary(intent.RawTx); err != nil {
+		return fmt.Errorf("failed to decode stored transaction: %w", err)
+	}
+
+	hashes := make([]common.Hash, len(intent.TxHashes))
+	for i, hash := range intent.TxHashes {
+		hashes[i] = common.HexToHash(hash)
+	}
+
+	// A client deposit spends the client's nonce, not the gateway's
+	var receipt *types.Receipt
+	var err error
+	if intent.Kind == database.IntentClientDeposit {
+		var from common.Address
+		from, err = types.Sender(types.LatestSignerForChainID(latest.ChainId()), &latest)
+		if err != nil {
+			return fmt.Errorf("failed to recover client transaction sender: %w", err)
+		}
+		receipt, err = w.client.FindMinedTransactionFrom(ctx, from, uint64(*intent.Nonce), hashes)
+	} else {
+		receipt, err = w.client.FindMinedTransaction(ctx, uint64(*intent.Nonce), hashes)
+	}
+	if errors.Is(err, blockchain.ErrTransactionReplaced) {
+		return w.db.FailReplacedTxIntent(ctx, intent.ID, err.Error())
+	}
+	if err != nil {
+		return err
+	}
+
+	if receipt != nil {
+		// None of the call's versions can land once its cancel is mined
+		if intent.CancelTxHash != nil && strings.EqualFold(receipt.TxHash.Hex(), *intent.CancelTxHash) {
+			return w.db.FailReplacedTxIntent(ctx, intent.ID, "cancelled by "+receipt.TxHash.Hex())
+		}
+		if err := w.db.MarkTxIntentMined(ctx, intent.ID, receipt.TxHash.Hex(), receipt.BlockNumber.Uint64(), receipt.BlockHash.Hex()); err != nil {
+			return err
+		}
+		if receipt.Status == types.ReceiptStatusFailed {
+			return w.db.RevertTxIntent(ctx, intent.ID, w.client.RevertReason(ctx, receipt.TxHash))
+		}
+		log.Printf("Outbox: %s intent %d for application %d mined in block %d", intent.Kind, intent.ID, intent.ApplicationID, receipt.BlockNumber.Uint64())
+		return nil
+	}
+
+	// A cancel is in flight under the nonce; wait for it or the call to be mined
+	if intent.CancelTxHash != nil {
+		return w.db.UnlockTxIntent(ctx, intent.ID)
+	}
+
+	// The node may have dropped the transaction, or never received it before a crash
+	known, err := w.client.TransactionKnown(ctx, latest.Hash())
+	if err != nil {
+		return err
+	}
+	if !known {
+		err := w.broadcast(ctx, intent, &latest)
+		if err == nil {
+			log.Printf("Outbox: rebroadcast %s for intent %d", latest.Hash().Hex(), intent.ID)
+			return w.db.UnlockTxIntent(ctx, intent.ID)
+		}
+
+		// The transaction may still reach the network, so the intent cannot simply be
+		// failed; out of attempts, its nonce is cancelled instead. Poll records err as
+		// this attempt.
+		if intent.Attempts+1 >= w.maxAttempts {
+			if cancelErr := w.cancel(ctx, intent, hashes[0], &latest); cancelErr != nil {
+				return fmt.Errorf("%w; cancel failed: %v", err, cancelErr)
+			}
+		}
+		return err
+	}
+
+	// Stuck in the mempool - speed it up under the same nonce. Only the client can
+	// replace a transaction they signed.
+	if intent.Kind != database.IntentClientDeposit && intent.SubmittedAt != nil && time.Since(*intent.SubmittedAt) > w.client.ReplaceAfter() {
+		replacement, err := w.client.SpeedUpTransaction(ctx, uint64(intent.ApplicationID), hashes[0], &latest)
+		if errors.Is(err, blockchain.ErrFeeCeilingReached) {
+			log.Printf("Outbox: intent %d is at the fee ceiling, waiting without further replacement", intent.ID)
+			return w.db.UnlockTxIntent(ctx, intent.ID)
+		}
+		if err != nil {
+			return err
+		}
+
+		raw, err := replacement.MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("failed to encode replacement: %w", err)
+		}
+		return w.db.MarkTxIntentSubmitted(ctx, intent.ID, replacement.Nonce(), replacement.Hash().Hex(), raw, database.Amounts{Wei: replacement.Value()})
+	}
+
+	return w.db.UnlockTxIntent(ctx, intent.ID)
+}
+
+// confirm finalizes a mined intent once it reaches the confirmation depth
+func (w *Worker) confirm(ctx context.Context, intent *database.TxIntent) error {
+	if intent.TxHash == nil {
+		return fmt.Errorf("mined intent has no transaction hash")
+	}
+
+	receipt, err := w.client.TransactionReceipt(ctx, common.HexToHash(*intent.TxHash))
+	if errors.Is(err, ethereum.NotFound) {
+		log.Printf("Outbox: %s for intent %d is no longer mined, waiting for it again", *intent.TxHash, intent.ID)
+		return w.db.MarkTxIntentReorged(ctx, intent.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get receipt: %w", err)
+	}
+
+	confirmations, err := w.client.Confirmations(ctx, receipt)
+	if errors.Is(err, blockchain.ErrTransactionReorged) {
+		log.Printf("Outbox: %s for intent %d was reorged out of block %d", *intent.TxHash, intent.ID, receipt.BlockNumber.Uint64())
+		return w.db.MarkTxIntentReorged(ctx, intent.ID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if confirmations < w.client.ConfirmationDepth() {
+		return w.db.UnlockTxIntent(ctx, intent.ID)
+	}
+
+	// A deposit the client signed is accepted only once it verifies as one they sent
+	// themselves would. One that never does is failed and left to the reconciler, which
+	// flags the job on chain.
+	if intent.Kind == database.IntentClientDeposit {
+		if err := w.verifyClientDeposit(ctx, intent); err != nil {
+			attempts, recordErr := w.db.RecordTxIntentError(ctx, intent.ID, err.Error())
+			if recordErr != nil {
+				return recordErr
+			}
+			if attempts >= w.maxAttempts {
+				return w.db.RejectTxIntent(ctx, intent.ID, fmt.Sprintf("deposit verification failed: %v", err))
+			}
+			return nil
+		}
+	}
+
+	return w.db.ConfirmTxIntent(ctx, intent.ID)
+}
+
+// cancel spends the nonce of a submitted intent that could not be rebroadcast on a
+// zero-value self-send and records it on the intent, so track fails the intent once the
+// cancel is mined. A client's transaction can only be cancelled by the client.
+func (w *Worker) cancel(ctx context.Context, intent *database.TxIntent, original common.Hash, latest *types.Transaction) error {
+	if intent.Kind == database.IntentClientDeposit {
+		log.Printf("Outbox: intent %d cannot be rebroadcast; waiting for the client's nonce %d to be spent", intent.ID, latest.Nonce())
+		return nil
+	}
+
+	cancel, err := w.client.CancelTransaction(ctx, uint64(intent.ApplicationID), original, latest)
+	if err != nil {
+		return err
+	}
+	log.Printf("Outbox: intent %d cannot be rebroadcast; cancelling nonce %d with %s", intent.ID, cancel.Nonce(), cancel.Hash().Hex())
+	return w.db.MarkTxIntentCancelled(ctx, intent.ID, cancel.Hash().Hex())
+}
+
+// broadcast sends a stored transaction again
+func (w *Worker) broadcast(ctx context.Context, intent *database.TxIntent, tx *types.Transaction) error {
+	if intent.Kind == database.IntentClientDeposit {
+		return w.client.SendSignedTransaction(ctx, tx)
+	}
+	return w.client.BroadcastTransaction(ctx, tx)
+}
+
+// verifyClientDeposit checks a mined client deposit against the application's agreed terms,
+// and against the ETH amount of the price quote it was submitted with, if any
+func (w *Worker) verifyClientDeposit(ctx context.Context, intent *database.TxIntent) error {
+	details, err := w.db.GetApplicationPaymentDetails(ctx, intent.ApplicationID)
+	if err != nil {
+		return err
+	}
+	if details.ApplicantWalletAddress == nil || details.PosterWalletAddress == nil || details.AgreedUSDAmount == nil {
+		return fmt.Errorf("application %d is missing wallet addresses or agreed amount", intent.ApplicationID)
+	}
+
+	req := blockchain.PostJobRequest{
+		JobID:             uint64(intent.ApplicationID),
+		FreelancerAddress: *details.ApplicantWalletAddress,
+		USDAmount:         *details.AgreedUSDAmount,
+		ClientAddress:     *details.PosterWalletAddress,
+		ClientTxHash:      *intent.TxHash,
+	}
+
+	quote, err := w.db.GetPriceQuoteByTx(ctx, *intent.TxHash)
+	if err != nil {
+		return err
+	}
+	if quote != nil {
+		req.QuoteID = quote.ID
+		req.Quote = &blockchain.Quote{
+			ID:            quote.ID,
+			JobID:         uint64(quote.ApplicationID),
+			USDAmountE8:   quote.USDAmountE8,
+			RequiredWei:   quote.Wei,
+			EthUSDPriceE8: quote.EthUSDPriceE8,
+			PriceSources:  quote.PriceSources,
+			ExpiresAt:     quote.ExpiresAt,
+			RedeemedBy:    *quote.TxHash,
+		}
+	}
+
+	return w.service.VerifyClientDeposit(ctx, req)
+}
+
+// retryOrFail records a failure to sign and fails the intent once it runs out of attempts.
+// Nothing was sent, so failing is always safe.
+func (w *Worker) retryOrFail(ctx context.Context, intent *database.TxIntent, cause error) error {
+	attempts, err := w.db.RecordTxIntentError(ctx, intent.ID, cause.Error())
+	if err != nil {
+		return err
+	}
+	if attempts >= w.maxAttempts {
+		return w.db.FailTxIntent(ctx, intent.ID, fmt.Sprintf("failed %d times: %v", attempts, cause))
+	}
+	return nil
+}
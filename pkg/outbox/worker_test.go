@@ -0,0 +1,348 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+
+	"github.com/fahedafzaal/go-integration/internal/config"
+	"github.com/fahedafzaal/go-integration/internal/escrowsim"
+	"github.com/fahedafzaal/go-integration/pkg/blockchain"
+	"github.com/fahedafzaal/go-integration/pkg/database"
+)
+
+// fakeStore keeps intents in memory and, like *database.DB, only fails an intent from
+// the statuses the failing method allows
+type fakeStore struct {
+	mu      sync.Mutex
+	intents map[int64]*database.TxIntent
+	errors  []string
+}
+
+func newFakeStore(intents ...*database.TxIntent) *fakeStore {
+	s := &fakeStore{intents: make(map[int64]*database.TxIntent)}
+	for _, intent := range intents {
+		s.intents[intent.ID] = intent
+	}
+	return s
+}
+
+func (s *fakeStore) get(id int64) database.TxIntent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.intents[id]
+}
+
+func (s *fakeStore) ClaimTxIntents(ctx context.Context, chainID int64, limit int, lease time.Duration) ([]database.TxIntent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed []database.TxIntent
+	for _, intent := range s.intents {
+		switch intent.Status {
+		case database.IntentQueued, database.IntentSubmitted, database.IntentMined:
+			claimed = append(claimed, *intent)
+		}
+	}
+	return claimed, nil
+}
+
+func (s *fakeStore) UnlockTxIntent(ctx context.Context, id int64) error { return nil }
+
+func (s *fakeStore) RecordTxIntentError(ctx context.Context, id int64, message string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	intent := s.intents[id]
+	intent.Attempts++
+	intent.Error = &message
+	s.errors = append(s.errors, message)
+	return intent.Attempts, nil
+}
+
+func (s *fakeStore) MarkTxIntentSubmitted(ctx context.Context, id int64, nonce uint64, txHash string, rawTx []byte, amounts database.Amounts) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	intent := s.intents[id]
+	now := time.Now()
+	n := int64(nonce)
+	intent.Status, intent.Nonce, intent.TxHash, intent.RawTx = database.IntentSubmitted, &n, &txHash, rawTx
+	intent.TxHashes = append(intent.TxHashes, txHash)
+	intent.Attempts, intent.SubmittedAt = 0, &now
+	return nil
+}
+
+func (s *fakeStore) MarkTxIntentCancelled(ctx context.Context, id int64, txHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	intent := s.intents[id]
+	if intent.Status != database.IntentSubmitted {
+		return fmt.Errorf("transaction intent %d is not submitted", id)
+	}
+	intent.CancelTxHash = &txHash
+	intent.TxHashes = append(intent.TxHashes, txHash)
+	return nil
+}
+
+func (s *fakeStore) MarkTxIntentMined(ctx context.Context, id int64, txHash string, blockNumber uint64, blockHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	intent := s.intents[id]
+	number := int64(blockNumber)
+	intent.Status, intent.TxHash, intent.BlockNumber, intent.BlockHash = database.IntentMined, &txHash, &number, &blockHash
+	return nil
+}
+
+func (s *fakeStore) MarkTxIntentReorged(ctx context.Context, id int64) error {
+	return s.setStatus(id, database.IntentSubmitted, "", database.IntentMined)
+}
+
+func (s *fakeStore) ConfirmTxIntent(ctx context.Context, id int64) error {
+	return s.setStatus(id, database.IntentConfirmed, "", database.IntentMined)
+}
+
+func (s *fakeStore) FailTxIntent(ctx context.Context, id int64, reason string) error {
+	return s.setStatus(id, database.IntentFailed, reason, database.IntentQueued)
+}
+
+func (s *fakeStore) RevertTxIntent(ctx context.Context, id int64, revertReason string) error {
+	return s.setStatus(id, database.IntentFailed, "transaction reverted: "+revertReason, database.IntentQueued, database.IntentMined)
+}
+
+func (s *fakeStore) FailReplacedTxIntent(ctx context.Context, id int64, reason string) error {
+	return s.setStatus(id, database.IntentFailed, reason, database.IntentSubmitted)
+}
+
+func (s *fakeStore) RejectTxIntent(ctx context.Context, id int64, reason string) error {
+	return s.setStatus(id, database.IntentFailed, reason, database.IntentMined)
+}
+
+func (s *fakeStore) setStatus(id int64, status, reason string, from ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	intent := s.intents[id]
+	for _, allowed := range from {
+		if intent.Status == allowed {
+			intent.Status = status
+			if reason != "" {
+				intent.Error = &reason
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: intent %d is %s", database.ErrTxIntentInFlight, id, intent.Status)
+}
+
+func (s *fakeStore) GetApplicationPaymentDetails(ctx context.Context, applicationID int32) (*database.ApplicationPaymentDetails, error) {
+	return nil, database.ErrApplicationNotFound
+}
+
+func (s *fakeStore) GetPriceQuoteByTx(ctx context.Context, txHash string) (*database.PriceQuote, error) {
+	return nil, nil
+}
+
+// outboxTest is a worker on a simulated chain whose blocks are only mined on Commit
+type outboxTest struct {
+	t       *testing.T
+	ctx     context.Context
+	backend *simulated.Backend
+	signer  *blockchain.FakeSigner
+	client  *blockchain.Client
+}
+
+func newOutboxTest(t *testing.T) *outboxTest {
+	t.Helper()
+	signer := blockchain.NewFakeSigner()
+	backend := escrowsim.NewBackend(signer.Address())
+	t.Cleanup(func() { backend.Close() })
+
+	eth, ok := backend.Client().(blockchain.EthBackend)
+	if !ok {
+		t.Fatal("simulated client does not implement EthBackend")
+	}
+	client, err := blockchain.NewClientWithBackend(&config.Config{
+		NetworkID:         1337,
+		ContractAddress:   "0x1111111111111111111111111111111111111111",
+		GasLimit:          300000,
+		ConfirmationDepth: 1,
+		FeeBumpPercent:    15,
+		MaxFeeCapGwei:     500,
+		TxReplaceAfter:    60,
+	}, eth, signer)
+	if err != nil {
+		t.Fatalf("NewClientWithBackend: %v", err)
+	}
+	return &outboxTest{t: t, ctx: context.Background(), backend: backend, signer: signer, client: client}
+}
+
+// transfer signs a gateway transaction under a freshly reserved nonce, standing in for
+// a contract call; gas above the block gas limit makes the node refuse it
+func (o *outboxTest) transfer(gas uint64) *types.Transaction {
+	o.t.Helper()
+	auth, err := o.client.GetAuth(o.ctx)
+	if err != nil {
+		o.t.Fatalf("GetAuth: %v", err)
+	}
+	to := common.HexToAddress("0x000000000000000000000000000000000000dEaD")
+	tx, err := auth.Signer(auth.From, types.NewTx(&types.DynamicFeeTx{
+		Nonce:     auth.Nonce.Uint64(),
+		GasTipCap: auth.GasTipCap,
+		GasFeeCap: auth.GasFeeCap,
+		Gas:       gas,
+		To:        &to,
+		Value:     big.NewInt(1),
+	}))
+	if err != nil {
+		o.t.Fatalf("sign: %v", err)
+	}
+	return tx
+}
+
+// submitted is a release intent whose transaction tx was stored, and perhaps sent,
+// submittedAgo
+func (o *outboxTest) submitted(tx *types.Transaction, submittedAgo time.Duration, attempts int) *database.TxIntent {
+	o.t.Helper()
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		o.t.Fatalf("MarshalBinary: %v", err)
+	}
+	nonce, hash, at := int64(tx.Nonce()), tx.Hash().Hex(), time.Now().Add(-submittedAgo)
+	return &database.TxIntent{
+		ID:            1,
+		ApplicationID: 7,
+		ChainID:       1337,
+		Kind:          database.IntentRelease,
+		Status:        database.IntentSubmitted,
+		Nonce:         &nonce,
+		TxHash:        &hash,
+		TxHashes:      []string{hash},
+		RawTx:         raw,
+		Attempts:      attempts,
+		SubmittedAt:   &at,
+	}
+}
+
+func (o *outboxTest) poll(w *Worker) {
+	o.t.Helper()
+	if err := w.Poll(o.ctx); err != nil {
+		o.t.Fatalf("Poll: %v", err)
+	}
+}
+
+func TestWorkerRebroadcastsLostTransaction(t *testing.T) {
+	o := newOutboxTest(t)
+	tx := o.transfer(21000)
+	store := newFakeStore(o.submitted(tx, 0, 0))
+	w := New(o.client, store, Config{MaxAttempts: 3})
+
+	// Stored but never sent, as after a crash: the node has not seen it
+	o.poll(w)
+	if known, _ := o.client.TransactionKnown(o.ctx, tx.Hash()); !known {
+		t.Fatal("lost transaction was not rebroadcast")
+	}
+
+	escrowsim.Commit(o.backend)
+	o.poll(w)
+	if intent := store.get(1); intent.Status != database.IntentMined || *intent.TxHash != tx.Hash().Hex() {
+		t.Fatalf("after mining: status %s, tx %s", intent.Status, *intent.TxHash)
+	}
+	o.poll(w)
+	if intent := store.get(1); intent.Status != database.IntentConfirmed {
+		t.Errorf("at confirmation depth: status %s, want confirmed", intent.Status)
+	}
+}
+
+func TestWorkerSpeedsUpStuckTransaction(t *testing.T) {
+	o := newOutboxTest(t)
+	tx := o.transfer(21000)
+	if err := o.client.BroadcastTransaction(o.ctx, tx); err != nil {
+		t.Fatalf("BroadcastTransaction: %v", err)
+	}
+	store := newFakeStore(o.submitted(tx, 2*o.client.ReplaceAfter(), 0))
+	w := New(o.client, store, Config{MaxAttempts: 3})
+
+	o.poll(w)
+	intent := store.get(1)
+	if len(intent.TxHashes) != 2 || *intent.TxHash == tx.Hash().Hex() {
+		t.Fatalf("stuck transaction was not replaced: %v", intent.TxHashes)
+	}
+	replacement := *intent.TxHash
+
+	escrowsim.Commit(o.backend)
+	o.poll(w)
+	if intent := store.get(1); intent.Status != database.IntentMined || *intent.TxHash != replacement {
+		t.Errorf("after mining: status %s, tx %s, want mined %s", intent.Status, *intent.TxHash, replacement)
+	}
+}
+
+func TestWorkerCancelsUnsendableTransaction(t *testing.T) {
+	o := newOutboxTest(t)
+	tx := o.transfer(1 << 40)
+	store := newFakeStore(o.submitted(tx, 0, 1))
+	w := New(o.client, store, Config{MaxAttempts: 3})
+
+	// Below the attempt limit the failure is only recorded, once
+	o.poll(w)
+	if intent := store.get(1); intent.Attempts != 2 || intent.CancelTxHash != nil || len(store.errors) != 1 {
+		t.Fatalf("first failure: attempts %d, cancel %v, %d errors recorded", intent.Attempts, intent.CancelTxHash, len(store.errors))
+	}
+
+	// Out of attempts the intent stays submitted and its nonce is cancelled
+	o.poll(w)
+	intent := store.get(1)
+	if intent.Status != database.IntentSubmitted || intent.CancelTxHash == nil {
+		t.Fatalf("out of attempts: status %s, cancel %v, errors %q", intent.Status, intent.CancelTxHash, store.errors)
+	}
+	if len(intent.TxHashes) != 2 || intent.TxHashes[1] != *intent.CancelTxHash || *intent.TxHash != tx.Hash().Hex() {
+		t.Errorf("cancel not tracked as a version: hashes %v, tx %s", intent.TxHashes, *intent.TxHash)
+	}
+	cancel := *intent.CancelTxHash
+
+	// With the cancel in flight nothing is sent or recorded again
+	errors := len(store.errors)
+	o.poll(w)
+	if intent := store.get(1); *intent.CancelTxHash != cancel || len(intent.TxHashes) != 2 || len(store.errors) != errors {
+		t.Errorf("cancel in flight: cancel %s, hashes %v, %d new errors", *intent.CancelTxHash, intent.TxHashes, len(store.errors)-errors)
+	}
+
+	escrowsim.Commit(o.backend)
+	o.poll(w)
+	if intent := store.get(1); intent.Status != database.IntentFailed || !strings.Contains(*intent.Error, cancel) {
+		t.Errorf("after the cancel is mined: status %s, error %v", intent.Status, *intent.Error)
+	}
+}
+
+func TestWorkerFailsReplacedTransaction(t *testing.T) {
+	o := newOutboxTest(t)
+	tx := o.transfer(21000)
+	store := newFakeStore(o.submitted(tx, 0, 0))
+	w := New(o.client, store, Config{MaxAttempts: 3})
+
+	// Another transaction spends the intent's nonce before it is ever sent
+	other, err := o.signer.SignTx(o.ctx, types.NewTx(&types.DynamicFeeTx{
+		Nonce:     tx.Nonce(),
+		GasTipCap: tx.GasTipCap(),
+		GasFeeCap: tx.GasFeeCap(),
+		Gas:       21000,
+		To:        &common.Address{},
+		Value:     big.NewInt(2),
+	}), big.NewInt(1337))
+	if err != nil {
+		t.Fatalf("SignTx: %v", err)
+	}
+	if err := o.client.SendSignedTransaction(o.ctx, other); err != nil {
+		t.Fatalf("SendSignedTransaction: %v", err)
+	}
+	escrowsim.Commit(o.backend)
+
+	o.poll(w)
+	if intent := store.get(1); intent.Status != database.IntentFailed {
+		t.Errorf("nonce spent by another transaction: status %s, want failed", intent.Status)
+	}
+}
@@ -0,0 +1,142 @@
+// Package payment defines the escrow payment status state machine. Every change to
+// applications.payment_status must be one of the transitions allowed here.
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Status is an application's escrow payment status
+type Status string
+
+const (
+	PendingDeposit       Status = "pending_deposit"       // agreed, escrow not funded yet
+	DepositInitiated     Status = "deposit_initiated"     // PostJob sent by the gateway
+	Deposited            Status = "deposited"             // escrow funded
+	ReleaseInitiated     Status = "release_initiated"     // MarkJobCompleted sent or JobCompleted seen
+	Released             Status = "released"              // freelancer paid
+	RefundInitiated      Status = "refund_initiated"      // CancelJob sent
+	Refunded             Status = "refunded"              // client refunded
+	PendingConfirmations Status = "pending_confirmations" // chain event seen, not yet deep enough
+)
+
+var (
+	// ErrUnknownStatus is returned when parsing a string that is not a payment status
+	ErrUnknownStatus = errors.New("unknown payment status")
+	// ErrIllegalTransition is matched by every *TransitionError
+	ErrIllegalTransition = errors.New("illegal payment status transition")
+	// ErrStatusConflict is returned when the status changed between being read and written
+	ErrStatusConflict = errors.New("payment status changed concurrently")
+)
+
+// TransitionError reports a status change the state machine does not allow
+type TransitionError struct {
+	From Status
+	To   Status
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("illegal payment status transition from '%s' to '%s'", e.From, e.To)
+}
+
+// Is makes errors.Is(err, ErrIllegalTransition) match
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrIllegalTransition
+}
+
+// transitions lists the statuses each status may move to. In-flight statuses can fall
+// back to where they started when their transaction fails. A status listing
+// pending_confirmations may park there while a chain event leaving it gains
+// confirmations; entering and leaving pending_confirmations goes through a Stage.
+//
+// Three moves skip an *_initiated status, because the chain can get there without the
+// gateway sending anything:
+//   - pending_deposit to deposited: a client may post the job from their own wallet
+//     without asking the gateway, and JobPosted is the first the gateway hears of it.
+//   - deposited to released: markJobCompleted pays the freelancer in the same
+//     transaction, so a release the contract owner sends outside the gateway is first
+//     seen already paid, and the reconciler finds jobs paid while the indexer was behind.
+//   - deposited to refunded: likewise for a cancelJob sent outside the gateway.
+var transitions = map[Status][]Status{
+	PendingDeposit:       {DepositInitiated, Deposited, PendingConfirmations},
+	DepositInitiated:     {Deposited, PendingDeposit, PendingConfirmations},
+	Deposited:            {ReleaseInitiated, Released, RefundInitiated, Refunded, PendingConfirmations},
+	ReleaseInitiated:     {Released, Deposited, PendingConfirmations},
+	RefundInitiated:      {Refunded, Deposited, PendingConfirmations},
+	PendingConfirmations: nil, // left only as its Stage allows
+	Released:             nil,
+	Refunded:             nil,
+}
+
+// Stage is the transition an application parked in pending_confirmations is waiting on
+type Stage struct {
+	From Status // status before the chain event, restored if the event is reorged out
+	To   Status // status the event moves the application to once it is confirmed
+}
+
+// NewStage returns a *TransitionError unless an application in from may wait in
+// pending_confirmations for a chain event moving it to to
+func NewStage(from, to Status) (Stage, error) {
+	if !slices.Contains(transitions[from], PendingConfirmations) {
+		return Stage{}, &TransitionError{From: from, To: PendingConfirmations}
+	}
+	if err := Transition(from, to); err != nil {
+		return Stage{}, err
+	}
+	return Stage{From: from, To: to}, nil
+}
+
+// Exit returns a *TransitionError unless to confirms the stage or rolls it back
+func (s Stage) Exit(to Status) error {
+	if to != s.To && to != s.From {
+		return &TransitionError{From: PendingConfirmations, To: to}
+	}
+	return nil
+}
+
+// Parse converts a stored payment status. An empty string is pending_deposit, which is
+// how applications created before escrow existed are stored.
+func Parse(s string) (Status, error) {
+	if s == "" {
+		return PendingDeposit, nil
+	}
+	status := Status(s)
+	if !status.Valid() {
+		return "", fmt.Errorf("%w: '%s'", ErrUnknownStatus, s)
+	}
+	return status, nil
+}
+
+// Valid reports whether s is a known payment status
+func (s Status) Valid() bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+// Terminal reports whether no transition leaves s
+func (s Status) Terminal() bool {
+	return s.Valid() && s != PendingConfirmations && len(transitions[s]) == 0
+}
+
+func (s Status) String() string {
+	return string(s)
+}
+
+// CanTransition reports whether a status may change from one value to another. Moves
+// into and out of pending_confirmations are checked by NewStage and Stage.Exit instead.
+func CanTransition(from, to Status) bool {
+	if from == PendingConfirmations || to == PendingConfirmations {
+		return false
+	}
+	return slices.Contains(transitions[from], to)
+}
+
+// Transition returns a *TransitionError unless from may change to to
+func Transition(from, to Status) error {
+	if !CanTransition(from, to) {
+		return &TransitionError{From: from, To: to}
+	}
+	return nil
+}
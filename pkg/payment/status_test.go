@@ -0,0 +1,101 @@
+package payment
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHappyPaths(t *testing.T) {
+	paths := [][]Status{
+		{PendingDeposit, DepositInitiated, Deposited, ReleaseInitiated, Released},
+		{PendingDeposit, DepositInitiated, Deposited, RefundInitiated, Refunded},
+		{PendingDeposit, DepositInitiated, PendingDeposit},
+	}
+	for _, path := range paths {
+		for i := 1; i < len(path); i++ {
+			if err := Transition(path[i-1], path[i]); err != nil {
+				t.Errorf("Transition(%s, %s) = %v, want nil", path[i-1], path[i], err)
+			}
+		}
+	}
+}
+
+func TestIllegalTransitions(t *testing.T) {
+	cases := []struct{ from, to Status }{
+		{PendingDeposit, Released},         // skips the deposit
+		{PendingDeposit, ReleaseInitiated}, // nothing to release
+		{DepositInitiated, Refunded},
+		{ReleaseInitiated, Refunded},
+		{Released, Deposited}, // terminal
+		{Refunded, PendingConfirmations},
+		{Deposited, PendingConfirmations}, // only through a Stage
+		{PendingConfirmations, Released},
+		{Deposited, Deposited}, // a no-op is not a transition
+	}
+	for _, c := range cases {
+		err := Transition(c.from, c.to)
+		if !errors.Is(err, ErrIllegalTransition) {
+			t.Errorf("Transition(%s, %s) = %v, want ErrIllegalTransition", c.from, c.to, err)
+		}
+		var transitionErr *TransitionError
+		if !errors.As(err, &transitionErr) || transitionErr.From != c.from || transitionErr.To != c.to {
+			t.Errorf("Transition(%s, %s) = %#v, want a *TransitionError naming both", c.from, c.to, err)
+		}
+	}
+}
+
+func TestStages(t *testing.T) {
+	// Deposit seen, then the release, each waiting for confirmations
+	for _, step := range []struct{ from, to Status }{
+		{PendingDeposit, Deposited},
+		{Deposited, Released},
+		{Deposited, Refunded},
+		{ReleaseInitiated, Released},
+	} {
+		stage, err := NewStage(step.from, step.to)
+		if err != nil {
+			t.Errorf("NewStage(%s, %s) = %v, want nil", step.from, step.to, err)
+			continue
+		}
+		if err := stage.Exit(step.to); err != nil {
+			t.Errorf("confirming %s -> %s: %v", step.from, step.to, err)
+		}
+		if err := stage.Exit(step.from); err != nil {
+			t.Errorf("rolling back %s -> %s: %v", step.from, step.to, err)
+		}
+	}
+
+	// pending_deposit -> pending_confirmations -> released would skip the deposit
+	if _, err := NewStage(PendingDeposit, Released); !errors.Is(err, ErrIllegalTransition) {
+		t.Errorf("NewStage(pending_deposit, released) = %v, want ErrIllegalTransition", err)
+	}
+	if _, err := NewStage(DepositInitiated, Refunded); !errors.Is(err, ErrIllegalTransition) {
+		t.Errorf("NewStage(deposit_initiated, refunded) = %v, want ErrIllegalTransition", err)
+	}
+	if _, err := NewStage(Released, Refunded); !errors.Is(err, ErrIllegalTransition) {
+		t.Errorf("NewStage(released, refunded) = %v, want ErrIllegalTransition", err)
+	}
+
+	stage := Stage{From: PendingDeposit, To: Deposited}
+	for _, to := range []Status{Released, Refunded, ReleaseInitiated, DepositInitiated} {
+		if err := stage.Exit(to); !errors.Is(err, ErrIllegalTransition) {
+			t.Errorf("leaving %v for %s = %v, want ErrIllegalTransition", stage, to, err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	if got, err := Parse(""); err != nil || got != PendingDeposit {
+		t.Errorf("Parse(\"\") = %q, %v; want pending_deposit", got, err)
+	}
+	if got, err := Parse("release_initiated"); err != nil || got != ReleaseInitiated {
+		t.Errorf("Parse(release_initiated) = %q, %v", got, err)
+	}
+	if _, err := Parse("completed"); !errors.Is(err, ErrUnknownStatus) {
+		t.Errorf("Parse(completed) error = %v, want ErrUnknownStatus", err)
+	}
+
+	if !Released.Terminal() || !Refunded.Terminal() || Deposited.Terminal() || PendingConfirmations.Terminal() {
+		t.Error("only released and refunded should be terminal")
+	}
+}
@@ -0,0 +1,297 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/fahedafzaal/go-integration/pkg/blockchain"
+	"github.com/fahedafzaal/go-integration/pkg/database"
+	"github.com/fahedafzaal/go-integration/pkg/payment"
+)
+
+// Escrow job states as read from the contract
+const (
+	ChainAbsent    = "absent"    // no job with the application's id
+	ChainFunded    = "funded"    // job posted and holding funds
+	ChainCompleted = "completed" // marked complete, payment not yet sent
+	ChainPaid      = "paid"      // funds sent to the freelancer
+)
+
+// Discrepancy kinds the reconciler flags instead of healing
+const (
+	KindMissingOnChain       = "missing_on_chain"       // the database says funds are escrowed, the contract has no job
+	KindTransactionNotLanded = "transaction_not_landed" // an initiated transaction has no effect on chain
+	KindUnexpectedChainState = "unexpected_chain_state" // the chain moved somewhere the database status cannot follow
+	KindTermsMismatch        = "terms_mismatch"         // the job on chain has a different amount or parties
+)
+
+// reconciledStatuses are the non-terminal statuses the contract can confirm or refute.
+// pending_deposit has nothing on chain to compare and pending_confirmations belongs to
+// the indexer.
+var reconciledStatuses = []payment.Status{
+	payment.DepositInitiated,
+	payment.Deposited,
+	payment.ReleaseInitiated,
+	payment.RefundInitiated,
+}
+
+// Reconciler periodically compares applications.payment_status with the escrow contract.
+// Drifts where the chain is simply ahead of the database are healed through the payment
+// state machine; anything else is recorded as a payment discrepancy for an operator.
+type Reconciler struct {
+	client    *blockchain.Client
+	db        *database.DB
+	interval  time.Duration
+	batchSize int
+	settle    time.Duration
+}
+
+// Config holds reconciler settings
+type Config struct {
+	Interval  time.Duration // Optional, defaults to 5 minutes
+	BatchSize int           // Optional, defaults to 100 applications per query
+	Settle    time.Duration // Optional, defaults to 10 minutes; applications whose ledger moved more recently are skipped
+}
+
+// New creates a reconciler for the client's escrow contract, covering the applications
+// whose escrow job is on the client's chain
+func New(client *blockchain.Client, db *database.DB, cfg Config) *Reconciler {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Minute
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.Settle <= 0 {
+		cfg.Settle = 10 * time.Minute
+	}
+
+	return &Reconciler{
+		client:    client,
+		db:        db,
+		interval:  cfg.Interval,
+		batchSize: cfg.BatchSize,
+		settle:    cfg.Settle,
+	}
+}
+
+// Run reconciles every interval until the context is cancelled
+func (r *Reconciler) Run(ctx context.Context) {
+	log.Printf("Reconciler: starting on chain %d (interval %v, settle %v)", r.client.ChainID(), r.interval, r.settle)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Poll(ctx); err != nil {
+			log.Printf("Reconciler: pass failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Printf("Reconciler: stopping")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll makes one pass over every non-terminal application, reading the contract at the
+// deepest block that has reached confirmation depth. An application that cannot be read
+// or recorded is logged and counted, and the pass moves on to the next.
+func (r *Reconciler) Poll(ctx context.Context) error {
+	head, err := r.client.LatestBlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get latest block: %w", err)
+	}
+	block := head
+	if depth := r.client.ConfirmationDepth(); head+1 >= depth {
+		block = head + 1 - depth
+	}
+
+	var after int32
+	var healed, flagged, failed int
+	for {
+		candidates, err := r.db.ListReconcileCandidates(ctx, r.client.ChainID(), reconciledStatuses, after, r.batchSize, r.settle)
+		if err != nil {
+			return err
+		}
+
+		for _, candidate := range candidates {
+			after = candidate.ApplicationID
+
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			job, err := r.client.JobDetailsAt(ctx, uint64(candidate.ApplicationID), block)
+			if err != nil {
+				failed++
+				log.Printf("Reconciler: failed to read job %d at block %d: %v", candidate.ApplicationID, block, err)
+				continue
+			}
+
+			outcome := assess(candidate, job)
+			switch {
+			case len(outcome.heal) > 0:
+				ok, err := r.db.HealPaymentStatus(ctx, candidate.ApplicationID, candidate.PaymentStatus, outcome.heal, block, outcome.detail)
+				if err != nil {
+					failed++
+					log.Printf("Reconciler: failed to heal application %d: %v", candidate.ApplicationID, err)
+					continue
+				}
+				if ok {
+					healed++
+					log.Printf("Reconciler: healed application %d: %s", candidate.ApplicationID, outcome.detail)
+				}
+			case outcome.kind != "":
+				created, err := r.db.FlagPaymentDiscrepancy(ctx, database.PaymentDiscrepancy{
+					ApplicationID: candidate.ApplicationID,
+					Kind:          outcome.kind,
+					PaymentStatus: candidate.PaymentStatus,
+					ChainState:    outcome.chainState,
+					BlockNumber:   int64(block),
+					Detail:        outcome.detail,
+				})
+				if err != nil {
+					failed++
+					log.Printf("Reconciler: failed to flag application %d: %v", candidate.ApplicationID, err)
+					continue
+				}
+				if created {
+					flagged++
+					log.Printf("Reconciler: flagged application %d as %s: %s", candidate.ApplicationID, outcome.kind, outcome.detail)
+				}
+			default:
+				if err := r.db.ResolvePaymentDiscrepancies(ctx, candidate.ApplicationID, "in sync with chain"); err != nil {
+					failed++
+					log.Printf("Reconciler: failed to resolve discrepancies of application %d: %v", candidate.ApplicationID, err)
+				}
+			}
+		}
+
+		if len(candidates) < r.batchSize {
+			break
+		}
+	}
+
+	if healed > 0 || flagged > 0 || failed > 0 {
+		log.Printf("Reconciler: pass at block %d healed %d, flagged %d and failed %d applications", block, healed, flagged, failed)
+	}
+	return nil
+}
+
+// outcome is the reconciler's verdict on one application: heal along a status path, flag
+// a discrepancy of kind, or neither when the two sides agree
+type outcome struct {
+	chainState string
+	heal       []payment.Status
+	kind       string
+	detail     string
+}
+
+// chainState classifies a job read from the contract
+func chainState(job *blockchain.JobDetails) string {
+	switch {
+	case job == nil:
+		return ChainAbsent
+	case job.IsPaid:
+		return ChainPaid
+	case job.IsCompleted:
+		return ChainCompleted
+	default:
+		return ChainFunded
+	}
+}
+
+// assess decides what to do about an application given its job on chain. Only drifts
+// where the chain is ahead of the database along the escrow lifecycle are healed; a chain
+// that is behind, or disagrees with the agreed terms, needs a person to look at it.
+func assess(c database.ReconcileCandidate, job *blockchain.JobDetails) outcome {
+	state := chainState(job)
+	o := outcome{chainState: state}
+
+	if job != nil {
+		if detail := termsMismatch(c, job); detail != "" {
+			o.kind, o.detail = KindTermsMismatch, detail
+			return o
+		}
+	}
+
+	heal := func(path ...payment.Status) outcome {
+		o.heal = path
+		o.detail = fmt.Sprintf("chain shows job %s while status is %s", state, c.PaymentStatus)
+		return o
+	}
+	flag := func(kind string) outcome {
+		o.kind = kind
+		o.detail = fmt.Sprintf("chain shows job %s while status is %s", state, c.PaymentStatus)
+		return o
+	}
+
+	switch c.PaymentStatus {
+	case payment.DepositInitiated:
+		switch state {
+		case ChainAbsent:
+			return flag(KindTransactionNotLanded)
+		case ChainFunded:
+			return heal(payment.Deposited)
+		case ChainCompleted:
+			return heal(payment.Deposited, payment.ReleaseInitiated)
+		case ChainPaid:
+			return heal(payment.Deposited, payment.Released)
+		}
+	case payment.Deposited:
+		switch state {
+		case ChainAbsent:
+			return flag(KindMissingOnChain)
+		case ChainCompleted:
+			return heal(payment.ReleaseInitiated)
+		case ChainPaid:
+			return heal(payment.Released)
+		}
+	case payment.ReleaseInitiated:
+		switch state {
+		case ChainAbsent:
+			return flag(KindMissingOnChain)
+		case ChainFunded:
+			return flag(KindTransactionNotLanded)
+		case ChainPaid:
+			return heal(payment.Released)
+		}
+	case payment.RefundInitiated:
+		// A landed cancel deletes the job, which is indistinguishable from a job that was
+		// never posted, so an absent job is flagged rather than taken as a refund
+		switch state {
+		case ChainAbsent:
+			return flag(KindMissingOnChain)
+		case ChainFunded:
+			return flag(KindTransactionNotLanded)
+		default:
+			return flag(KindUnexpectedChainState)
+		}
+	}
+	return o
+}
+
+// termsMismatch describes how a job on chain differs from the application's agreed
+// terms, or returns "" when they match or the application lacks the data to compare
+func termsMismatch(c database.ReconcileCandidate, job *blockchain.JobDetails) string {
+	var diffs []string
+	if c.AgreedUSDAmount != nil && job.USDAmount != nil {
+		want := c.AgreedUSDAmount.E8()
+		if job.USDAmount.Cmp(want) != 0 {
+			diffs = append(diffs, fmt.Sprintf("usd amount %s on chain, %s agreed", job.USDAmount, want))
+		}
+	}
+	if c.PosterWalletAddress != nil && !strings.EqualFold(*c.PosterWalletAddress, job.Client.Hex()) {
+		diffs = append(diffs, fmt.Sprintf("client %s on chain, poster wallet %s", job.Client.Hex(), *c.PosterWalletAddress))
+	}
+	if c.ApplicantWalletAddress != nil && !strings.EqualFold(*c.ApplicantWalletAddress, job.Freelancer.Hex()) {
+		diffs = append(diffs, fmt.Sprintf("freelancer %s on chain, applicant wallet %s", job.Freelancer.Hex(), *c.ApplicantWalletAddress))
+	}
+	return strings.Join(diffs, "; ")
+}
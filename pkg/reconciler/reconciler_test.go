@@ -0,0 +1,106 @@
+package reconciler
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/fahedafzaal/go-integration/pkg/blockchain"
+	"github.com/fahedafzaal/go-integration/pkg/database"
+	"github.com/fahedafzaal/go-integration/pkg/money"
+	"github.com/fahedafzaal/go-integration/pkg/payment"
+)
+
+var (
+	poster    = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	applicant = common.HexToAddress("0x2222222222222222222222222222222222222222")
+)
+
+func candidate(status payment.Status) database.ReconcileCandidate {
+	amount := money.MustParseUSD("150")
+	posterHex := poster.Hex()
+	applicantHex := applicant.Hex()
+	return database.ReconcileCandidate{
+		ApplicationID:          7,
+		PaymentStatus:          status,
+		AgreedUSDAmount:        &amount,
+		PosterWalletAddress:    &posterHex,
+		ApplicantWalletAddress: &applicantHex,
+	}
+}
+
+func job(state string) *blockchain.JobDetails {
+	if state == ChainAbsent {
+		return nil
+	}
+	return &blockchain.JobDetails{
+		Client:      poster,
+		Freelancer:  applicant,
+		USDAmount:   big.NewInt(150e8),
+		ETHAmount:   big.NewInt(1e17),
+		IsCompleted: state == ChainCompleted || state == ChainPaid,
+		IsPaid:      state == ChainPaid,
+	}
+}
+
+func TestAssess(t *testing.T) {
+	tests := []struct {
+		status payment.Status
+		state  string
+		heal   []payment.Status
+		kind   string
+	}{
+		{payment.DepositInitiated, ChainAbsent, nil, KindTransactionNotLanded},
+		{payment.DepositInitiated, ChainFunded, []payment.Status{payment.Deposited}, ""},
+		{payment.DepositInitiated, ChainCompleted, []payment.Status{payment.Deposited, payment.ReleaseInitiated}, ""},
+		{payment.DepositInitiated, ChainPaid, []payment.Status{payment.Deposited, payment.Released}, ""},
+		{payment.Deposited, ChainAbsent, nil, KindMissingOnChain},
+		{payment.Deposited, ChainFunded, nil, ""},
+		{payment.Deposited, ChainCompleted, []payment.Status{payment.ReleaseInitiated}, ""},
+		{payment.Deposited, ChainPaid, []payment.Status{payment.Released}, ""},
+		{payment.ReleaseInitiated, ChainAbsent, nil, KindMissingOnChain},
+		{payment.ReleaseInitiated, ChainFunded, nil, KindTransactionNotLanded},
+		{payment.ReleaseInitiated, ChainCompleted, nil, ""},
+		{payment.ReleaseInitiated, ChainPaid, []payment.Status{payment.Released}, ""},
+		{payment.RefundInitiated, ChainAbsent, nil, KindMissingOnChain},
+		{payment.RefundInitiated, ChainFunded, nil, KindTransactionNotLanded},
+		{payment.RefundInitiated, ChainPaid, nil, KindUnexpectedChainState},
+	}
+
+	for _, tt := range tests {
+		got := assess(candidate(tt.status), job(tt.state))
+		if got.chainState != tt.state {
+			t.Errorf("%s/%s: chain state = %s", tt.status, tt.state, got.chainState)
+		}
+		if !reflect.DeepEqual(got.heal, tt.heal) || got.kind != tt.kind {
+			t.Errorf("%s/%s: heal %v kind %q, want heal %v kind %q", tt.status, tt.state, got.heal, got.kind, tt.heal, tt.kind)
+		}
+
+		// Every heal must be a walk the state machine accepts
+		from := tt.status
+		for _, next := range got.heal {
+			if err := payment.Transition(from, next); err != nil {
+				t.Errorf("%s/%s: heal path: %v", tt.status, tt.state, err)
+			}
+			from = next
+		}
+	}
+}
+
+func TestAssessTermsMismatch(t *testing.T) {
+	onChain := job(ChainPaid)
+	onChain.USDAmount = big.NewInt(140e8)
+
+	got := assess(candidate(payment.ReleaseInitiated), onChain)
+	if got.kind != KindTermsMismatch || got.heal != nil {
+		t.Fatalf("got heal %v kind %q, want a terms_mismatch and no heal", got.heal, got.kind)
+	}
+
+	onChain = job(ChainFunded)
+	onChain.Freelancer = common.HexToAddress("0x3333333333333333333333333333333333333333")
+	if got := assess(candidate(payment.Deposited), onChain); got.kind != KindTermsMismatch {
+		t.Errorf("got kind %q for a different freelancer, want terms_mismatch", got.kind)
+	}
+}
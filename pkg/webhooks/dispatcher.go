@@ -0,0 +1,227 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fahedafzaal/go-integration/pkg/blockchain"
+	"github.com/fahedafzaal/go-integration/pkg/database"
+)
+
+// maxBackoff caps the delay between delivery attempts
+const maxBackoff = time.Hour
+
+// Event is the JSON body POSTed to webhook endpoints. Data has the same shape as the
+// GET /job-status response, captured when the payment status changed.
+type Event struct {
+	ID        int64                        `json:"id"`
+	Type      string                       `json:"type"`
+	CreatedAt time.Time                    `json:"created_at"`
+	Data      blockchain.JobStatusResponse `json:"data"`
+}
+
+// Dispatcher delivers payment status webhooks. Status changes are recorded as events in
+// the same database transaction that makes them; the dispatcher fans each event out to
+// one delivery per endpoint and retries failed deliveries with exponential backoff.
+type Dispatcher struct {
+	db           *database.DB
+	httpClient   *http.Client
+	endpoints    []string
+	secret       []byte
+	maxAttempts  int
+	baseDelay    time.Duration
+	pollInterval time.Duration
+	lease        time.Duration
+}
+
+// Config holds webhook dispatcher settings
+type Config struct {
+	Endpoints    []string      // URLs every event is POSTed to
+	Secret       string        // HMAC-SHA256 signing key
+	MaxAttempts  int           // Optional, defaults to 8 attempts before a delivery fails
+	BaseDelay    time.Duration // Optional, defaults to 30 seconds before the first retry
+	PollInterval time.Duration // Optional, defaults to 5 seconds
+	Timeout      time.Duration // Optional, defaults to 10 seconds per request
+}
+
+// New creates a webhook dispatcher
+func New(db *database.DB, cfg Config) *Dispatcher {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 8
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = 30 * time.Second
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 5 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	return &Dispatcher{
+		db:           db,
+		httpClient:   &http.Client{Timeout: cfg.Timeout},
+		endpoints:    cfg.Endpoints,
+		secret:       []byte(cfg.Secret),
+		maxAttempts:  cfg.MaxAttempts,
+		baseDelay:    cfg.BaseDelay,
+		pollInterval: cfg.PollInterval,
+		lease:        cfg.Timeout + time.Minute,
+	}
+}
+
+// Run delivers webhooks until the context is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	log.Printf("Webhooks: starting dispatcher for %d endpoint(s)", len(d.endpoints))
+
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.Poll(ctx); err != nil {
+			log.Printf("Webhooks: poll failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Printf("Webhooks: stopping dispatcher")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll fans out new events and attempts every delivery that is due
+func (d *Dispatcher) Poll(ctx context.Context) error {
+	if _, err := d.db.FanOutWebhookEvents(ctx, d.endpoints); err != nil {
+		return err
+	}
+
+	deliveries, err := d.db.ClaimWebhookDeliveries(ctx, 50, d.lease)
+	if err != nil {
+		return err
+	}
+
+	for i := range deliveries {
+		if err := d.deliver(ctx, &deliveries[i]); err != nil {
+			log.Printf("Webhooks: delivery %d: %v", deliveries[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// deliver makes one attempt and records its outcome
+func (d *Dispatcher) deliver(ctx context.Context, delivery *database.WebhookDelivery) error {
+	statusCode, sendErr := d.send(ctx, delivery)
+	if sendErr == nil {
+		log.Printf("Webhooks: delivered %s for application %d to %s", delivery.Event.Type, delivery.Event.ApplicationID, delivery.Endpoint)
+		return d.db.MarkWebhookDelivered(ctx, delivery.ID, statusCode)
+	}
+
+	var code *int
+	if statusCode != 0 {
+		code = &statusCode
+	}
+
+	attempts := delivery.Attempts + 1
+	if attempts >= d.maxAttempts {
+		log.Printf("Webhooks: giving up on delivery %d to %s after %d attempts: %v", delivery.ID, delivery.Endpoint, attempts, sendErr)
+		return d.db.RecordWebhookFailure(ctx, delivery.ID, code, sendErr.Error(), nil)
+	}
+
+	next := time.Now().Add(d.backoff(attempts))
+	return d.db.RecordWebhookFailure(ctx, delivery.ID, code, sendErr.Error(), &next)
+}
+
+// send POSTs the signed event and returns the endpoint's status code, if it answered
+func (d *Dispatcher) send(ctx context.Context, delivery *database.WebhookDelivery) (int, error) {
+	body, err := json.Marshal(d.buildEvent(ctx, &delivery.Event))
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.Event.Type)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(d.secret, now, body))
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// buildEvent combines the event's payment snapshot with the application's current terms
+func (d *Dispatcher) buildEvent(ctx context.Context, event *database.WebhookEvent) Event {
+	data := blockchain.JobStatusResponse{
+		JobID:         uint64(event.ApplicationID), // application.id is used as escrow job_id
+		ApplicationID: event.ApplicationID,
+		PaymentStatus: event.PaymentStatus,
+	}
+	if event.TxHashDeposit != nil {
+		data.TxHashDeposit = *event.TxHashDeposit
+	}
+	if event.TxHashRelease != nil {
+		data.TxHashRelease = *event.TxHashRelease
+	}
+	if event.TxHashRefund != nil {
+		data.TxHashRefund = *event.TxHashRefund
+	}
+
+	details, err := d.db.GetApplicationPaymentDetails(ctx, event.ApplicationID)
+	if err != nil {
+		log.Printf("Warning: Failed to get application details for webhook event %d: %v", event.ID, err)
+	} else {
+		data.ApplicationStatus = details.ApplicationStatus
+		if details.ApplicantWalletAddress != nil {
+			data.FreelancerAddress = *details.ApplicantWalletAddress
+		}
+		if details.PosterWalletAddress != nil {
+			data.ClientAddress = *details.PosterWalletAddress
+		}
+		if details.AgreedUSDAmount != nil {
+			data.USDAmount = *details.AgreedUSDAmount
+		}
+	}
+
+	return Event{
+		ID:        event.ID,
+		Type:      event.Type,
+		CreatedAt: event.CreatedAt,
+		Data:      data,
+	}
+}
+
+// backoff returns the delay before the next attempt after attempts failures
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.baseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
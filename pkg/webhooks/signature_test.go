@@ -0,0 +1,50 @@
+package webhooks
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	secret := []byte("test-secret")
+	body := []byte(`{"type":"escrow.deposited"}`)
+	now := time.Now()
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	signature := Sign(secret, now, body)
+
+	if !Verify(secret, timestamp, signature, body, 5*time.Minute) {
+		t.Fatal("expected a freshly signed payload to verify")
+	}
+	if Verify([]byte("other-secret"), timestamp, signature, body, 5*time.Minute) {
+		t.Error("expected verification with the wrong secret to fail")
+	}
+	if Verify(secret, timestamp, signature, []byte(`{"type":"escrow.refunded"}`), 5*time.Minute) {
+		t.Error("expected verification of a modified body to fail")
+	}
+
+	old := now.Add(-10 * time.Minute)
+	if Verify(secret, strconv.FormatInt(old.Unix(), 10), Sign(secret, old, body), body, 5*time.Minute) {
+		t.Error("expected verification of a stale timestamp to fail")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{baseDelay: 30 * time.Second}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{4, 4 * time.Minute},
+		{20, maxBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := d.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
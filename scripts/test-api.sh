@@ -5,6 +5,8 @@
 set -e
 
 BASE_URL="http://localhost:8081"
+# A key from the gateway's API_KEYS holding the read and write scopes
+API_KEY="${API_KEY:?set API_KEY to a gateway API key secret}"
 
 echo "🧪 Testing Freelance Payment Gateway API..."
 echo "Make sure the server is running with: go run cmd/main.go"
@@ -23,7 +25,7 @@ echo ""
 
 # Test ETH price endpoint
 echo -e "${YELLOW}2. Getting Current ETH Price...${NC}"
-curl -s "$BASE_URL/eth-price" | jq '.' 2>/dev/null || curl -s "$BASE_URL/eth-price"
+curl -s -H "X-API-Key: $API_KEY" "$BASE_URL/eth-price" | jq '.' 2>/dev/null || curl -s -H "X-API-Key: $API_KEY" "$BASE_URL/eth-price"
 echo ""
 
 # Test job posting (requires actual addresses and contract)
@@ -43,6 +45,7 @@ echo "Payload: $JOB_PAYLOAD"
 
 curl -X POST "$BASE_URL/post-job" \
   -H "Content-Type: application/json" \
+  -H "X-API-Key: $API_KEY" \
   -d "$JOB_PAYLOAD" | jq '.' 2>/dev/null || echo "Job posting test skipped"
 
 echo ""
@@ -51,7 +54,7 @@ echo ""
 echo -e "${YELLOW}4. Testing Job Status Query...${NC}"
 echo "Note: This will fail without an existing job"
 
-curl -s "$BASE_URL/job-status?job_id=123" | jq '.' 2>/dev/null || echo "Job status test skipped"
+curl -s -H "X-API-Key: $API_KEY" "$BASE_URL/job-status?job_id=123" | jq '.' 2>/dev/null || echo "Job status test skipped"
 
 echo ""
 